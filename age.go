@@ -0,0 +1,30 @@
+package main
+
+import (
+	"github.com/gen2brain/raylib-go/raylib"
+	"io"
+)
+
+// AgeRampTicks is the age at which the age overlay reaches its coolest color.
+const AgeRampTicks = int32(100)
+
+// ageColor maps an age to a warm (recently changed) to cool (static) ramp.
+func ageColor(age int32) rl.Color {
+	warm := rl.Red
+	cool := rl.Blue
+
+	if age > AgeRampTicks {
+		age = AgeRampTicks
+	}
+	lerp := func(a uint8, b uint8) uint8 {
+		return uint8(int32(a) + (int32(b)-int32(a))*age/AgeRampTicks)
+	}
+	return rl.NewColor(lerp(warm.R, cool.R), lerp(warm.G, cool.G), lerp(warm.B, cool.B), 255)
+}
+
+// exportAge writes age.csv to the working directory and returns its name.
+func (layout Layout) exportAge() (string, error) {
+	return writeExport("age.csv", nil, func(w io.Writer) error {
+		return writeGridCSV(w, layout.AgeField())
+	})
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"github.com/gen2brain/raylib-go/raylib"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DefaultAutosaveSeconds is how often the grid is autosaved unless -autosave-seconds says otherwise.
+const DefaultAutosaveSeconds = 30
+
+// autosavePath is where the autosave goes: a fixed name in the temp directory, so that the next run finds it.
+func autosavePath() string {
+	return filepath.Join(os.TempDir(), "mclighting-autosave.csv")
+}
+
+// writeAutosave writes the emissions of a snapshot to path, in the format -layout reads, and its light caps next to
+// it. It writes a temporary file first and renames it over path, so that a crash mid-write never leaves a truncated
+// autosave.
+func writeAutosave(path string, snapshot *Snapshot) error {
+	if err := saveCapsSidecar(path, snapshot); err != nil {
+		return err
+	}
+	width, height := snapshot.Size()
+	grid := make([][]int32, height)
+	for y := range grid {
+		grid[y] = make([]int32, width)
+		for x := range grid[y] {
+			cell, _ := snapshot.At(Point{X: int32(x), Y: int32(y)})
+			grid[y][x] = cell.Source
+		}
+	}
+
+	partial := path + ".partial"
+	file, err := os.Create(partial)
+	if err != nil {
+		return err
+	}
+	if err := writeGridCSV(file, grid); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(partial, path)
+}
+
+// autosave writes the latest published snapshot, without the point lights (see Saveable), to path every interval
+// until something is sent on stop. Published snapshots are immutable, so this never sees a half-evolved grid and
+// needs no locking.
+func (simulation *Simulation) autosave(path string, interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if err := writeAutosave(path, simulation.Saveable()); err != nil {
+				log.Printf("Autosave failed: %v\n", err)
+			}
+		}
+	}
+}
+
+// startAutosave starts autosaving the simulation to path every interval, and returns the function to defer until the
+// main loop is over. That function stops autosaving and deletes the autosave, unless the loop is panicking: only
+// crashes leave an autosave behind, for the next run to offer.
+func (simulation *Simulation) startAutosave(path string, interval time.Duration) func() {
+	stop := make(chan struct{})
+	go simulation.autosave(path, interval, stop)
+	return func() {
+		// The send waits for a write in progress to finish.
+		stop <- struct{}{}
+		if crash := recover(); crash != nil {
+			panic(crash)
+		}
+		os.Remove(path)
+		os.Remove(capsSidecarPath(path))
+	}
+}
+
+// recoverableAutosave returns the layout autosaved at path if it is newer than every slot saved explicitly, and
+// fits a width x height grid. A clean exit deletes the autosave, so there is only one to find after a crash.
+func recoverableAutosave(path string, slots *SaveSlots, width int32, height int32) (Layout, time.Time, bool) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return Layout{}, time.Time{}, false
+	}
+	for n := 0; n < SaveSlotCount; n++ {
+		if slot, err := os.Stat(slots.Path(n)); err == nil && !slot.ModTime().Before(info.ModTime()) {
+			return Layout{}, time.Time{}, false
+		}
+	}
+
+	layout, err := loadLayoutFile(path)
+	if err != nil {
+		log.Printf("Ignoring autosave: %v\n", err)
+		return Layout{}, time.Time{}, false
+	}
+	if autosavedWidth, autosavedHeight := layout.Size(); autosavedWidth != width || autosavedHeight != height {
+		log.Printf("Ignoring autosave %s: it is %dx%d, the grid is %dx%d\n",
+			path, autosavedWidth, autosavedHeight, width, height)
+		return Layout{}, time.Time{}, false
+	}
+	return layout, info.ModTime(), true
+}
+
+// AutosavePromptFontPx is the font size of the restore prompt.
+const AutosavePromptFontPx = int32(20)
+
+// raylibPromptRestore shows the autosaved layout with a prompt over it until <Y> (true) or <N> (false) is pressed.
+// Closing the window counts as no.
+func raylibPromptRestore(autosaved Layout, savedAt time.Time) bool {
+	preview := autosaved.Snapshot()
+	text := "Restore the autosave from " + savedAt.Format("15:04:05") + "? <Y>/<N>"
+	for !rl.WindowShouldClose() {
+		if rl.IsKeyPressed(rl.KeyY) {
+			return true
+		}
+		if rl.IsKeyPressed(rl.KeyN) {
+			return false
+		}
+
+		rl.BeginDrawing()
+		rl.ClearBackground(theme.Background)
+		raylibDrawSnapshot(preview, nil)
+		textWidth := rl.MeasureText(text, AutosavePromptFontPx)
+		x := (int32(rl.GetScreenWidth()) - textWidth) / 2
+		y := (int32(rl.GetScreenHeight()) - AutosavePromptFontPx) / 2
+		rl.DrawRectangle(x-8, y-8, textWidth+16, AutosavePromptFontPx+16, rl.ColorAlpha(rl.Black, 0.8))
+		rl.DrawText(text, x, y, AutosavePromptFontPx, rl.White)
+		rl.EndDrawing()
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Point lights are not part of the grid: the autosave holds each cell's own emission, so that restoring it after a
+// crash does not turn the lights into fixed sources.
+func TestAutosaveWithoutPointLights(t *testing.T) {
+	layout := NewLayout(6, 5)
+	layout.SetSource(Point{X: 4, Y: 3}, 2)
+	layout.SetSource(Point{X: 0, Y: 0}, -1)
+	layout.SetCap(Point{X: 5, Y: 4}, 9)
+	simulation := NewSimulation(layout)
+	simulation.PointLights = &PointLights{
+		Lights: []PointLight{{X: 1.5, Y: 2.5, Emission: 13}, {X: 4.2, Y: 3.9, Emission: 8}},
+	}
+	simulation.PointLights.Rasterize(simulation.Layout)
+	simulation.Evolve()
+
+	if source := simulation.Snapshot().Cells()[2*6+1].Source; source != 13 {
+		t.Fatalf("published source under the light is %d, want 13", source)
+	}
+
+	path := filepath.Join(t.TempDir(), "autosave.csv")
+	if err := writeAutosave(path, simulation.Saveable()); err != nil {
+		t.Fatal(err)
+	}
+	restored, err := loadLayoutFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := NewLayout(6, 5)
+	want.SetSource(Point{X: 4, Y: 3}, 2)
+	want.SetSource(Point{X: 0, Y: 0}, -1)
+	assertSources(t, restored, want)
+	if levelCap := restored.Get(Point{X: 5, Y: 4}).Cap; levelCap != 9 {
+		t.Errorf("restored cap is %d, want 9", levelCap)
+	}
+}
+
+// runAutosaved runs loop the way main runs its own, autosaving the simulation to path, and returns what loop panicked
+// with, if anything.
+func runAutosaved(simulation *Simulation, path string, loop func()) (crash interface{}) {
+	defer func() { crash = recover() }()
+	defer simulation.startAutosave(path, time.Hour)()
+	loop()
+	return nil
+}
+
+// A clean exit deletes the autosave; a crash keeps it, since recovering from crashes is what it is for.
+func TestAutosaveKeptOnCrash(t *testing.T) {
+	layout := NewLayout(3, 2)
+	layout.SetSource(Point{X: 1, Y: 1}, 7)
+	layout.SetCap(Point{X: 2, Y: 0}, 4)
+	simulation := NewSimulation(layout)
+	path := filepath.Join(t.TempDir(), "autosave.csv")
+
+	if err := writeAutosave(path, simulation.Saveable()); err != nil {
+		t.Fatal(err)
+	}
+	if crash := runAutosaved(simulation, path, func() { panic("crash") }); crash != "crash" {
+		t.Fatalf("the loop's panic came out as %v", crash)
+	}
+	restored, err := loadLayoutFile(path)
+	if err != nil {
+		t.Fatalf("the autosave is gone after a crash: %v", err)
+	}
+	assertSources(t, restored, layout)
+
+	if crash := runAutosaved(simulation, path, func() {}); crash != nil {
+		t.Fatalf("a clean exit panicked with %v", crash)
+	}
+	for _, leftover := range []string{path, capsSidecarPath(path)} {
+		if _, err := os.Stat(leftover); !os.IsNotExist(err) {
+			t.Errorf("%s is still there after a clean exit (%v)", leftover, err)
+		}
+	}
+}
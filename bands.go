@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"github.com/gen2brain/raylib-go/raylib"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Band is a named range of light levels, e.g. "too dark" for 0-3.
+type Band struct {
+	Name  string
+	Min   int32
+	Max   int32
+	Color rl.Color
+}
+
+// DefaultBands splits the light levels the way map balancing usually thinks about them.
+func DefaultBands() []Band {
+	return []Band{
+		{Name: "too dark", Min: 0, Max: 3, Color: rl.NewColor(40, 40, 120, 255)},
+		{Name: "dim", Min: 4, Max: 7, Color: rl.NewColor(120, 90, 160, 255)},
+		{Name: "safe", Min: 8, Max: 11, Color: rl.NewColor(110, 190, 110, 255)},
+		{Name: "bright", Min: 12, Max: 15, Color: rl.NewColor(250, 230, 120, 255)},
+	}
+}
+
+// Light level bands in use
+var bands = DefaultBands()
+
+// ValidateBands checks that the bands have distinct names and cover every light level from 0 to 15 exactly once.
+func ValidateBands(bands []Band) error {
+	names := map[string]bool{}
+	owner := [16]string{}
+	for _, band := range bands {
+		if band.Name == "" {
+			return fmt.Errorf("%w: band %d-%d has no name", ErrMalformedInput, band.Min, band.Max)
+		}
+		if names[band.Name] {
+			return fmt.Errorf("%w: band %q is defined twice", ErrMalformedInput, band.Name)
+		}
+		names[band.Name] = true
+
+		if band.Min < 0 || band.Max > 15 || band.Min > band.Max {
+			return fmt.Errorf("%w: band %q: range %d-%d is not within 0-15", ErrOutOfRange, band.Name, band.Min, band.Max)
+		}
+		for level := band.Min; level <= band.Max; level++ {
+			if owner[level] != "" {
+				return fmt.Errorf("%w: bands %q and %q overlap at level %d", ErrMalformedInput, owner[level], band.Name, level)
+			}
+			owner[level] = band.Name
+		}
+	}
+	for level, name := range owner {
+		if name == "" {
+			return fmt.Errorf("%w: no band covers level %d", ErrMalformedInput, level)
+		}
+	}
+	return nil
+}
+
+// ParseBands reads bands from a comma-separated list of name:min-max:#rrggbb, e.g.
+// "too dark:0-3:#282878,dim:4-7:#785aa0,safe:8-11:#6ebe6e,bright:12-15:#fae678".
+// The result is sorted by level and validated.
+func ParseBands(spec string) ([]Band, error) {
+	result := []Band{}
+	for _, entry := range strings.Split(spec, ",") {
+		parts := strings.Split(entry, ":")
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("%w: band %q: expected name:min-max:#rrggbb", ErrMalformedInput, entry)
+		}
+		name := strings.TrimSpace(parts[0])
+
+		bounds := strings.Split(parts[1], "-")
+		if len(bounds) != 2 {
+			return nil, fmt.Errorf("%w: band %q: bad range %q", ErrMalformedInput, name, parts[1])
+		}
+		min, errMin := strconv.Atoi(strings.TrimSpace(bounds[0]))
+		max, errMax := strconv.Atoi(strings.TrimSpace(bounds[1]))
+		if errMin != nil || errMax != nil {
+			return nil, fmt.Errorf("%w: band %q: bad range %q", ErrMalformedInput, name, parts[1])
+		}
+
+		hex := strings.TrimPrefix(strings.TrimSpace(parts[2]), "#")
+		rgb, err := strconv.ParseUint(hex, 16, 32)
+		if err != nil || len(hex) != 6 {
+			return nil, fmt.Errorf("%w: band %q: bad color %q", ErrMalformedInput, name, parts[2])
+		}
+
+		result = append(result, Band{
+			Name:  name,
+			Min:   int32(min),
+			Max:   int32(max),
+			Color: rl.NewColor(uint8(rgb>>16), uint8(rgb>>8), uint8(rgb), 255),
+		})
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Min < result[j].Min })
+	if err := ValidateBands(result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// bandOf returns the index of the band containing the level, or -1.
+func bandOf(bands []Band, level int32) int {
+	for i, band := range bands {
+		if band.Min <= level && level <= band.Max {
+			return i
+		}
+	}
+	return -1
+}
+
+// BandCounts returns how many cells fall in each band. Blockers are not counted.
+func (layout Layout) BandCounts(bands []Band) []int {
+	counts := make([]int, len(bands))
+	for _, cell := range layout.Cells() {
+		if cell.Source < 0 {
+			continue
+		}
+		if i := bandOf(bands, cell.Level); i >= 0 {
+			counts[i]++
+		}
+	}
+	return counts
+}
+
+// bandSummary formats the per-band counts for the footer, e.g. "too dark 200 | dim 30 | safe 20 | bright 6".
+func (layout Layout) bandSummary(bands []Band) string {
+	counts := layout.BandCounts(bands)
+	parts := make([]string, len(bands))
+	for i, band := range bands {
+		parts[i] = fmt.Sprintf("%s %d", band.Name, counts[i])
+	}
+	return strings.Join(parts, " | ")
+}
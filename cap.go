@@ -0,0 +1,102 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gen2brain/raylib-go/raylib"
+	"os"
+)
+
+// DefaultPaintedCap is the light cap <Z> paints unless -cap says otherwise.
+const DefaultPaintedCap = 11
+
+// capsSidecarPath is where the light caps of a layout file are kept: next to it, as a CSV grid (see Caps). Layout
+// files only hold emissions, so every format shares the same sidecar.
+func capsSidecarPath(path string) string {
+	return path + ".caps.csv"
+}
+
+// Caps returns the light cap of every cell (see Cell.Cap) as Caps[y][x], 0 for no cap, and whether any cell has one.
+func Caps(grid cellGrid) ([][]int32, bool) {
+	width, height := grid.Size()
+	cells := grid.Cells()
+	caps := make([][]int32, height)
+	capped := false
+	for y := range caps {
+		caps[y] = make([]int32, width)
+		for x := range caps[y] {
+			caps[y][x] = cells[y*int(width)+x].Cap
+			capped = capped || caps[y][x] != 0
+		}
+	}
+	return caps, capped
+}
+
+// ApplyCaps sets the light cap of every cell from a grid the size of the layout, as Caps returns it.
+func (layout Layout) ApplyCaps(caps [][]int32) error {
+	width, height := layout.Size()
+	if len(caps) != int(height) {
+		return fmt.Errorf("%w: %d rows of caps for a grid %d high", ErrMalformedInput, len(caps), height)
+	}
+	for y, row := range caps {
+		if len(row) != int(width) {
+			return &InputError{Line: y + 1, Msg: fmt.Sprintf("%d caps for a grid %d wide", len(row), width)}
+		}
+		for x, levelCap := range row {
+			if levelCap < 0 || levelCap > 15 {
+				return &InputError{Line: y + 1, Column: x + 1,
+					Msg: fmt.Sprintf("cap %d (must be 0 to 15)", levelCap)}
+			}
+			layout.SetCap(Point{X: int32(x), Y: int32(y)}, levelCap)
+		}
+	}
+	return nil
+}
+
+// loadCapsSidecar applies the caps kept next to the layout file at path, if there are any.
+func loadCapsSidecar(path string, layout Layout) error {
+	file, err := os.Open(capsSidecarPath(path))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	caps, err := ReadGridCSV(file)
+	if err == nil {
+		err = layout.ApplyCaps(caps)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", capsSidecarPath(path), err)
+	}
+	return nil
+}
+
+// saveCapsSidecar writes the caps of grid next to the layout file at path, or removes the sidecar left there if no
+// cell has a cap, so that loading the file does not bring back old caps.
+func saveCapsSidecar(path string, grid cellGrid) error {
+	caps, capped := Caps(grid)
+	if !capped {
+		if err := os.Remove(capsSidecarPath(path)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	file, err := os.Create(capsSidecarPath(path))
+	if err != nil {
+		return err
+	}
+	if err := writeGridCSV(file, caps); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// raylibDrawCapShade washes the square at (x, y) (in pixels) out towards gray, more so the lower the cap, marking a
+// cell whose light is capped.
+func raylibDrawCapShade(x int32, y int32, levelCap int32) {
+	alpha := 0.15 + 0.3*float32(15-levelCap)/15
+	rl.DrawRectangle(x, y, SquareSideLengthPx, SquareSideLengthPx, rl.ColorAlpha(rl.Gray, alpha))
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"sort"
+)
+
+// Segment is a unit edge between two grid corners. Corner (x, y) is the top-left corner of cell (x, y).
+type Segment struct {
+	From Point
+	To   Point
+}
+
+// Contour returns the outline of a set of cells: every cell edge with the set on one side only.
+// Segments are sorted, so the result does not depend on map iteration order.
+func Contour(cells map[Point]bool) []Segment {
+	segments := []Segment{}
+	for p := range cells {
+		if !cells[Point{X: p.X, Y: p.Y - 1}] {
+			segments = append(segments, Segment{From: Point{X: p.X, Y: p.Y}, To: Point{X: p.X + 1, Y: p.Y}})
+		}
+		if !cells[Point{X: p.X, Y: p.Y + 1}] {
+			segments = append(segments, Segment{From: Point{X: p.X, Y: p.Y + 1}, To: Point{X: p.X + 1, Y: p.Y + 1}})
+		}
+		if !cells[Point{X: p.X - 1, Y: p.Y}] {
+			segments = append(segments, Segment{From: Point{X: p.X, Y: p.Y}, To: Point{X: p.X, Y: p.Y + 1}})
+		}
+		if !cells[Point{X: p.X + 1, Y: p.Y}] {
+			segments = append(segments, Segment{From: Point{X: p.X + 1, Y: p.Y}, To: Point{X: p.X + 1, Y: p.Y + 1}})
+		}
+	}
+
+	less := func(a Point, b Point) bool {
+		if a.Y != b.Y {
+			return a.Y < b.Y
+		}
+		return a.X < b.X
+	}
+	sort.Slice(segments, func(i, j int) bool {
+		if segments[i].From != segments[j].From {
+			return less(segments[i].From, segments[j].From)
+		}
+		return less(segments[i].To, segments[j].To)
+	})
+	return segments
+}
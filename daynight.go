@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"mclighting000/lighting"
+)
+
+// Minecraft's day, in ticks: tick 0 is sunrise (06:00), Noon and Midnight are a quarter and three quarters through.
+const (
+	DayLength = 24000
+	Noon      = 6000
+	Midnight  = 18000
+)
+
+// DayStepTicks is how far <+> and <-> move the time of day: an hour of game time.
+const DayStepTicks = 1000
+
+// MaxSkyDarkening is how many levels the sky light loses at night: it goes from 15 down to 4.
+const MaxSkyDarkening = 11
+
+// celestialAngle is the position of the sun as a fraction of a turn, 0 at noon, as Minecraft computes it from the
+// time of day: the sun lingers a little around noon and midnight.
+func celestialAngle(time int32) float32 {
+	angle := float32(time%DayLength)/DayLength - 0.25
+	if angle < 0 {
+		angle++
+	}
+	eased := 1 - float32((math.Cos(float64(angle)*math.Pi)+1)/2)
+	return angle + (eased-angle)/3
+}
+
+// SkyDarkening is how many levels sky light is dimmed by at the given time of day, in ticks since sunrise: 0 by day,
+// MaxSkyDarkening at night and in between at dusk and dawn. This is Minecraft's sky light subtraction in clear
+// weather; block light is never dimmed.
+func SkyDarkening(time int32) int32 {
+	brightness := 1 - (float32(math.Cos(float64(celestialAngle(time))*2*math.Pi))*2 + 0.5)
+	if brightness < 0 {
+		brightness = 0
+	} else if brightness > 1 {
+		brightness = 1
+	}
+	return int32(brightness * MaxSkyDarkening)
+}
+
+// darkenedSky is the sky light a cell shows, darkening levels dimmer.
+func darkenedSky(cell Cell, darkening int32) int32 {
+	return int32Max(cell.SkyLevel-darkening, 0)
+}
+
+// AdvanceTime moves the time of day by ticks, which may be negative, wrapping around midnight.
+func (simulation *Simulation) AdvanceTime(ticks int32) {
+	simulation.Time = ((simulation.Time+ticks)%DayLength + DayLength) % DayLength
+}
+
+// timeStatus describes the time of day for the footer, e.g. "18:30, sky 4": the clock time and the level the
+// open sky is at.
+func timeStatus(time int32) string {
+	hours := (time/1000 + 6) % 24
+	minutes := time % 1000 * 60 / 1000
+	return fmt.Sprintf("%02d:%02d, sky %d", hours, minutes, lighting.MaxSkyLevel-SkyDarkening(time))
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"encoding/csv"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"strconv"
+)
+
+// FieldKind selects what a distance field measures the distance to.
+type FieldKind int
+
+const (
+	// FieldSource measures the distance to the nearest light source (Source > 0).
+	FieldSource FieldKind = iota
+	// FieldBlocker measures the distance to the nearest light-blocking block (Source < 0).
+	FieldBlocker
+)
+
+func (kind FieldKind) String() string {
+	switch kind {
+	case FieldSource:
+		return "source"
+	case FieldBlocker:
+		return "blocker"
+	default:
+		return "unknown"
+	}
+}
+
+// NoDistance is reported for every cell when there is nothing of the requested kind anywhere in the layout.
+const NoDistance = int32(-1)
+
+func (kind FieldKind) matches(cell Cell) bool {
+	if kind == FieldSource {
+		return cell.Source > 0
+	}
+	return cell.Source < 0
+}
+
+// DistanceField returns, per cell, the Manhattan distance to the nearest cell of the given kind.
+// The result is indexed as field[y][x].
+//
+// This is a multi-source BFS: every matching cell is pushed at distance 0, then each pop pushes its unvisited
+// neighbors at distance+1. Blockers do not stop the search; the distance is purely geometric.
+func (layout Layout) DistanceField(kind FieldKind) [][]int32 {
+	width, height := layout.Size()
+	field := make([][]int32, height)
+	for y := range field {
+		field[y] = make([]int32, width)
+		for x := range field[y] {
+			field[y][x] = NoDistance
+		}
+	}
+
+	queue := make([]Point, 0, len(layout.Cells()))
+	for i, cell := range layout.Cells() {
+		if kind.matches(cell) {
+			point := Point{X: int32(i) % width, Y: int32(i) / width}
+			field[point.Y][point.X] = 0
+			queue = append(queue, point)
+		}
+	}
+
+	for len(queue) > 0 {
+		point := queue[0]
+		queue = queue[1:]
+
+		for _, neighbor := range point.Neighbors() {
+			if !layout.Contains(neighbor) {
+				continue
+			}
+			if field[neighbor.Y][neighbor.X] != NoDistance {
+				continue
+			}
+			field[neighbor.Y][neighbor.X] = field[point.Y][point.X] + 1
+			queue = append(queue, neighbor)
+		}
+	}
+
+	return field
+}
+
+// writeGridCSV writes a per-cell field (indexed [y][x]) as CSV, one row per grid row.
+func writeGridCSV(w io.Writer, field [][]int32) error {
+	writer := csv.NewWriter(w)
+	for _, row := range field {
+		record := make([]string, len(row))
+		for x, value := range row {
+			record[x] = strconv.Itoa(int(value))
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteDistanceFieldCSV writes the field as CSV, one row per grid row.
+func WriteDistanceFieldCSV(w io.Writer, field [][]int32) error {
+	return writeGridCSV(w, field)
+}
+
+// distanceFieldMaxGray and distanceFieldMinGray bound the gray values of reachable cells, so that the farthest cell
+// is still distinguishable from NoDistance (which is black).
+const (
+	distanceFieldMaxGray = 255
+	distanceFieldMinGray = 32
+)
+
+// distanceGray maps a distance to a gray value: the nearer, the brighter.
+func distanceGray(distance int32, maxDistance int32) uint8 {
+	if distance == NoDistance {
+		return 0
+	}
+	if maxDistance == 0 {
+		return distanceFieldMaxGray
+	}
+	// In 64 bits, so that the product cannot wrap around whatever the distances.
+	span := int64(distanceFieldMaxGray - distanceFieldMinGray)
+	return uint8(distanceFieldMaxGray - int64(distance)*span/int64(maxDistance))
+}
+
+func maxDistance(field [][]int32) int32 {
+	max := int32(0)
+	for _, row := range field {
+		for _, distance := range row {
+			max = int32Max(max, distance)
+		}
+	}
+	return max
+}
+
+// DistanceFieldImage renders the field as a grayscale image with one pixel per cell.
+func DistanceFieldImage(field [][]int32) *image.Gray {
+	max := maxDistance(field)
+
+	width := 0
+	if len(field) > 0 {
+		width = len(field[0])
+	}
+	img := image.NewGray(image.Rect(0, 0, width, len(field)))
+	for y, row := range field {
+		for x, distance := range row {
+			img.SetGray(x, y, color.Gray{Y: distanceGray(distance, max)})
+		}
+	}
+	return img
+}
+
+// WriteDistanceFieldPNG writes the field as a grayscale PNG.
+func WriteDistanceFieldPNG(w io.Writer, field [][]int32) error {
+	return png.Encode(w, DistanceFieldImage(field))
+}
+
+// exportDistanceField writes distance-<kind>.csv and distance-<kind>.png to the working directory and returns
+// their names.
+func (layout Layout) exportDistanceField(kind FieldKind) ([]string, error) {
+	field := layout.DistanceField(kind)
+	csvPath, err := writeExport("distance-"+kind.String()+".csv", nil, func(w io.Writer) error {
+		return WriteDistanceFieldCSV(w, field)
+	})
+	if err != nil {
+		return nil, err
+	}
+	pngPath, err := writeExport("distance-"+kind.String()+".png", nil, func(w io.Writer) error {
+		return WriteDistanceFieldPNG(w, field)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return []string{csvPath, pngPath}, nil
+}
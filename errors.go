@@ -0,0 +1,45 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Error categories. Errors returned by the engine wrap one of these, so callers can tell them apart with
+// errors.Is; the rest of the message says which cell or value is at fault.
+var (
+	// A value (emission, level, count...) is outside what is allowed.
+	ErrOutOfRange = errors.New("value out of range")
+	// A point is not on the grid.
+	ErrOutOfBounds = errors.New("outside the grid")
+	// Input (a file, flag or spec string) could not be parsed. See also InputError.
+	ErrMalformedInput = errors.New("malformed input")
+	// Light levels did not settle within the allowed time or passes.
+	ErrNotConverged = errors.New("did not converge")
+	// An operation was stopped before it finished, or its result is no longer valid.
+	ErrCancelled = errors.New("cancelled")
+)
+
+// InputError is an ErrMalformedInput with the position of the problem. Line and Column count from 1; 0 means
+// unknown.
+type InputError struct {
+	Line   int
+	Column int
+	Msg    string
+}
+
+func (err *InputError) Error() string {
+	switch {
+	case err.Line > 0 && err.Column > 0:
+		return fmt.Sprintf("%v: line %d, column %d: %s", ErrMalformedInput, err.Line, err.Column, err.Msg)
+	case err.Line > 0:
+		return fmt.Sprintf("%v: line %d: %s", ErrMalformedInput, err.Line, err.Msg)
+	default:
+		return fmt.Sprintf("%v: %s", ErrMalformedInput, err.Msg)
+	}
+}
+
+// Is makes errors.Is(err, ErrMalformedInput) hold.
+func (err *InputError) Is(target error) bool {
+	return target == ErrMalformedInput
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestInputError(t *testing.T) {
+	tests := []struct {
+		err  *InputError
+		want string
+	}{
+		{&InputError{Line: 3, Column: 7, Msg: "bad"}, "malformed input: line 3, column 7: bad"},
+		{&InputError{Line: 3, Msg: "bad"}, "malformed input: line 3: bad"},
+		{&InputError{Msg: "bad"}, "malformed input: bad"},
+	}
+	for _, test := range tests {
+		if got := test.err.Error(); got != test.want {
+			t.Errorf("%#v reads %q, want %q", test.err, got, test.want)
+		}
+
+		// Wrapped further up, it is still both an ErrMalformedInput and an InputError.
+		wrapped := fmt.Errorf("layout.csv: %w", test.err)
+		var inputErr *InputError
+		if !errors.Is(wrapped, ErrMalformedInput) || !errors.As(wrapped, &inputErr) || inputErr != test.err {
+			t.Errorf("%v does not classify as an ErrMalformedInput InputError", wrapped)
+		}
+		if errors.Is(wrapped, ErrOutOfRange) {
+			t.Errorf("%v classifies as ErrOutOfRange", wrapped)
+		}
+	}
+}
+
+// Every error path names its category, and the cell or value at fault.
+func TestErrorPaths(t *testing.T) {
+	layout := NewLayout(6, 4)
+	layout.SetSource(Point{X: 1, Y: 1}, 9)
+	layout.SetSource(Point{X: 4, Y: 2}, -1)
+	layout.Converge(ConvergeMaxIterations)
+
+	// The error of calls returning one or two values besides it
+	errorOf := func(_ interface{}, err error) error { return err }
+	errorOf2 := func(_ interface{}, _ interface{}, err error) error { return err }
+	tests := []struct {
+		name     string
+		err      error
+		category error
+		mentions []string
+	}{
+		{"emission out of range", errorOf(LayoutFromEmissions([][]int32{{0, 0}, {0, 16}})),
+			ErrMalformedInput, []string{"line 2, column 2", "16"}},
+		{"ragged grid", errorOf(LayoutFromEmissions([][]int32{{0, 0}, {0}})),
+			ErrMalformedInput, []string{"line 2", "1 cells"}},
+		{"CSV cell not a number", errorOf(ReadGridCSV(strings.NewReader("0,1\n2,x\n"))),
+			ErrMalformedInput, []string{"line 2, column 2", `"x"`}},
+		{"ASCII cell unknown", errorOf(ParseASCII(strings.NewReader("..\n.?\n"))),
+			ErrMalformedInput, []string{"line 2, column 2", "'?'"}},
+		{"unknown column", errorOf(ParseColumns("level,colour")), ErrMalformedInput, []string{`"colour"`}},
+		{"reach of a cell off the grid", errorOf2(layout.SourceReach(Point{X: 6, Y: 0})),
+			ErrOutOfBounds, []string{"{6 0}"}},
+		{"reach of an empty cell", errorOf(layout.ReachArea(Point{X: 2, Y: 3})),
+			ErrOutOfRange, []string{"{2 3}", "emission 0"}},
+		{"reach of a blocker", errorOf(layout.ReachArea(Point{X: 4, Y: 2})),
+			ErrOutOfRange, []string{"{4 2}", "emission -1"}},
+		{"occlusion of a source", errorOf(layout.OcclusionGain(Point{X: 1, Y: 1})),
+			ErrOutOfRange, []string{"{1 1}"}},
+		{"minimal source out of reach", errorOf(layout.MinSourceFor(Point{X: 0, Y: 0}, Point{X: 5, Y: 3}, 14)),
+			ErrOutOfRange, []string{"{5 3}", "level 14"}},
+		{"minimal source off the grid", errorOf(layout.MinSourceFor(Point{X: 0, Y: 9}, Point{X: 5, Y: 3}, 1)),
+			ErrOutOfBounds, []string{"{0 9}"}},
+		{"headless not settled", runHeadlessError(t, 1), ErrNotConverged, []string{"1 iterations"}},
+	}
+	categories := []error{ErrOutOfRange, ErrOutOfBounds, ErrMalformedInput, ErrNotConverged, ErrCancelled}
+	for _, test := range tests {
+		if test.err == nil {
+			t.Errorf("%s: no error", test.name)
+			continue
+		}
+		for _, category := range categories {
+			if is := errors.Is(test.err, category); is != (category == test.category) {
+				t.Errorf("%s: %q classifies as %v: %v", test.name, test.err, category, is)
+			}
+		}
+		for _, mention := range test.mentions {
+			if !strings.Contains(test.err.Error(), mention) {
+				t.Errorf("%s: %q does not mention %s", test.name, test.err, mention)
+			}
+		}
+	}
+}
+
+// runHeadlessError runs a layout that takes more than the given iterations to settle through runHeadless, and
+// returns its error.
+func runHeadlessError(t *testing.T, iterations int) error {
+	t.Helper()
+	layout := NewLayout(6, 1)
+	layout.SetSource(Point{X: 0, Y: 0}, 9)
+	path := t.TempDir() + "/layout.csv"
+	if err := saveLayoutFile(path, layout); err != nil {
+		t.Fatal(err)
+	}
+	return runHeadless(path, iterations, func(Layout) error { return nil })
+}
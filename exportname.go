@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ExportNaming chooses the names exported files are written under.
+type ExportNaming int
+
+const (
+	// NamingFixed writes each export under its own name, e.g. grid.png, replacing the previous one.
+	NamingFixed ExportNaming = iota
+	// NamingHash puts a hash of the exported content and the export parameters in the name, e.g.
+	// grid-3fa2c1-cell_24px-numbers_on.png, so the same export always gets the same name and is only written once.
+	NamingHash
+)
+
+func (naming ExportNaming) String() string {
+	if naming == NamingHash {
+		return "hash"
+	}
+	return "fixed"
+}
+
+// ParseExportNaming reads an ExportNaming from its String.
+func ParseExportNaming(setting string) (ExportNaming, error) {
+	switch setting {
+	case "fixed":
+		return NamingFixed, nil
+	case "hash":
+		return NamingHash, nil
+	default:
+		return NamingFixed, fmt.Errorf("%w: unknown export naming %q (want fixed or hash)", ErrMalformedInput, setting)
+	}
+}
+
+// exportNaming is how exports are named, set once from the command line.
+var exportNaming = NamingFixed
+
+// ContentHashLength is the number of hex digits of the content hash that go in a name.
+const ContentHashLength = 6
+
+// ExportParams are the settings an export was made with, by name, e.g. {"cell": "24px"}. The names are part of
+// the file names, so they are fixed strings, not flag names: renaming a flag must not rename every export.
+type ExportParams map[string]string
+
+// Canonical joins the parameters into one name part: sorted by name, each written name_value (or name alone if
+// the value is empty) and separated by '-'. Letters are lowered and every run of characters other than a-z, 0-9
+// and '.' becomes a single '_', so {"Cell": "24px", "numbers": "on"} is "cell_24px-numbers_on".
+func (params ExportParams) Canonical() string {
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		part := canonicalNamePart(name)
+		if value := canonicalNamePart(params[name]); value != "" {
+			part += "_" + value
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, "-")
+}
+
+// canonicalNamePart lowers s and collapses every run of characters other than a-z, 0-9 and '.' into one '_',
+// trimming them off the ends.
+func canonicalNamePart(s string) string {
+	var result strings.Builder
+	separate := false
+	for _, r := range strings.ToLower(s) {
+		if ('a' <= r && r <= 'z') || ('0' <= r && r <= '9') || r == '.' {
+			if separate && result.Len() > 0 {
+				result.WriteByte('_')
+			}
+			separate = false
+			result.WriteRune(r)
+		} else {
+			separate = true
+		}
+	}
+	return result.String()
+}
+
+// contentDigest returns the first ContentHashLength hex digits of the SHA-256 of content.
+func contentDigest(content []byte) string {
+	hash := sha256.Sum256(content)
+	return hex.EncodeToString(hash[:])[:ContentHashLength]
+}
+
+// cellGrid is a grid of cells, a Layout or a Snapshot.
+type cellGrid interface {
+	Size() (width, height int32)
+	Cells() []Cell
+}
+
+// exportName returns the name an export goes under: name itself with NamingFixed, or with NamingHash the digest
+// of its content and the canonical parameters put in before the extension, so "grid.png" becomes e.g.
+// "grid-3fa2c1-cell_24px-numbers_on.png". name may include a directory.
+func exportName(name string, digest string, params ExportParams) string {
+	if exportNaming != NamingHash {
+		return name
+	}
+	extension := filepath.Ext(name)
+	base := strings.TrimSuffix(name, extension)
+	for _, part := range []string{digest, params.Canonical()} {
+		if part != "" {
+			base += "-" + part
+		}
+	}
+	return base + extension
+}
+
+// writeExport writes an export with write, to the file named by exportName, and returns the name. With NamingHash,
+// the export is rendered in memory first and the digest is that of the bytes it comes to, so only what ends up in
+// the file decides its name. A file already there holds those same bytes, so it is kept; a file that failed to be
+// written is removed, so it is not kept the next time.
+func writeExport(name string, params ExportParams, write func(w io.Writer) error) (string, error) {
+	if exportNaming != NamingHash {
+		return name, writeFile(name, write)
+	}
+
+	var content bytes.Buffer
+	if err := write(&content); err != nil {
+		return name, err
+	}
+	path := exportName(name, contentDigest(content.Bytes()), params)
+	if _, err := os.Stat(path); err == nil {
+		return path, nil
+	}
+	err := writeFile(path, func(w io.Writer) error {
+		_, err := w.Write(content.Bytes())
+		return err
+	})
+	if err != nil {
+		os.Remove(path)
+	}
+	return path, err
+}
+
+// writeFile creates the file at path, or truncates it, and writes it with write.
+func writeFile(path string, write func(w io.Writer) error) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = write(file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestExportParamsCanonical(t *testing.T) {
+	tests := []struct {
+		params ExportParams
+		want   string
+	}{
+		{nil, ""},
+		{ExportParams{}, ""},
+		{ExportParams{"cell": "24px"}, "cell_24px"},
+		{ExportParams{"numbers": "on", "cell": "24px"}, "cell_24px-numbers_on"},
+		{ExportParams{"Cell": "24PX", "NUMBERS": "On"}, "cell_24px-numbers_on"},
+		{ExportParams{"palette": "Viridis (dark)"}, "palette_viridis_dark"},
+		{ExportParams{"  sources ": "--blocked--"}, "sources_blocked"},
+		{ExportParams{"scale": "1.5"}, "scale_1.5"},
+		{ExportParams{"linear": ""}, "linear"},
+		{ExportParams{"y": "-4"}, "y_4"},
+	}
+	for _, test := range tests {
+		if got := test.params.Canonical(); got != test.want {
+			t.Errorf("%v.Canonical() = %q, want %q", test.params, got, test.want)
+		}
+	}
+}
+
+// withExportNaming runs test in a temporary working directory, with exports named by naming.
+func withExportNaming(t *testing.T, naming ExportNaming, test func()) {
+	t.Helper()
+	dir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	previous := exportNaming
+	exportNaming = naming
+	defer func() {
+		exportNaming = previous
+		if err := os.Chdir(dir); err != nil {
+			t.Fatal(err)
+		}
+	}()
+	test()
+}
+
+// The parameter names are part of every hash-named file. Changing one renames all the exports of that kind, so
+// these names are pinned.
+func TestExportNamesPinned(t *testing.T) {
+	layout := NewLayout(4, 3)
+	layout.SetSource(Point{X: 1, Y: 1}, 12)
+	layout.SetSource(Point{X: 3, Y: 0}, -1)
+	layout.Converge(ConvergeMaxIterations)
+
+	withExportNaming(t, NamingHash, func() {
+		exports := []struct {
+			export func() (string, error)
+			want   string
+		}{
+			{func() (string, error) { return writeGridPNGFile("grid.png", layout.Snapshot(), 24, true) },
+				`^grid-[0-9a-f]{6}-cell_24px-numbers_on\.png$`},
+			{func() (string, error) { return writeGridPNGFile("grid.png", layout.Snapshot(), 8, false) },
+				`^grid-[0-9a-f]{6}-cell_8px-numbers_off\.png$`},
+			{func() (string, error) { return writeMCFunctionFile("layout.mcfunction", layout, 64) },
+				`^layout-[0-9a-f]{6}-y_64\.mcfunction$`},
+			{func() (string, error) { return layout.exportFlowField() }, `^flow-[0-9a-f]{6}\.csv$`},
+		}
+		for _, export := range exports {
+			path, err := export.export()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !regexp.MustCompile(export.want).MatchString(path) {
+				t.Errorf("exported to %q, want a name matching %s", path, export.want)
+			}
+		}
+
+		paths, err := layout.exportWalkability("walkability.csv", 8, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, want := range []string{
+			`^walkability-[0-9a-f]{6}-sources_blocked-threshold_8\.csv$`,
+			`^walkability-[0-9a-f]{6}-sources_blocked-threshold_8\.bin$`,
+		} {
+			if !regexp.MustCompile(want).MatchString(paths[i]) {
+				t.Errorf("exported to %q, want a name matching %s", paths[i], want)
+			}
+		}
+	})
+}
+
+// The name only depends on the bytes written: evolving a settled layout further changes its cells' ages, but not
+// what the export holds.
+func TestExportNameFollowsContent(t *testing.T) {
+	layout := NewLayout(16, 16)
+	layout.SetSource(Point{X: 5, Y: 9}, 15)
+	layout.Converge(ConvergeMaxIterations)
+
+	withExportNaming(t, NamingHash, func() {
+		first, err := writeMCFunctionFile("layout.mcfunction", layout, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 10; i++ {
+			layout.Evolve()
+		}
+		again, err := writeMCFunctionFile("layout.mcfunction", layout, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if again != first {
+			t.Errorf("the same export was named %q, then %q", first, again)
+		}
+
+		layout.SetSource(Point{X: 5, Y: 9}, 14)
+		changed, err := writeMCFunctionFile("layout.mcfunction", layout, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if changed == first {
+			t.Errorf("a different export got the same name %q", first)
+		}
+	})
+}
+
+func TestWriteExportHash(t *testing.T) {
+	withExportNaming(t, NamingHash, func() {
+		writes := 0
+		write := func(w io.Writer) error {
+			writes++
+			_, err := io.WriteString(w, "content")
+			return err
+		}
+		path, err := writeExport("out.csv", ExportParams{"mode": "a"}, write)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := "out-" + contentDigest([]byte("content")) + "-mode_a.csv"; path != want {
+			t.Errorf("exported to %q, want %q", path, want)
+		}
+
+		// An identical export is already there: it is rendered to find its name, but the file is left alone.
+		written := time.Unix(1000000000, 0)
+		if err := os.Chtimes(path, written, written); err != nil {
+			t.Fatal(err)
+		}
+		if again, err := writeExport("out.csv", ExportParams{"mode": "a"}, write); err != nil || again != path {
+			t.Errorf("exporting again gave %q, %v; want %q", again, err, path)
+		}
+		if info, err := os.Stat(path); err != nil || !info.ModTime().Equal(written) {
+			t.Errorf("the existing export was rewritten")
+		}
+		if writes != 2 {
+			t.Errorf("rendered %d times, want 2", writes)
+		}
+
+		// A failed export leaves no file behind.
+		failure := errors.New("failed")
+		if _, err := writeExport("bad.csv", nil, func(w io.Writer) error { return failure }); err != failure {
+			t.Errorf("got error %v, want %v", err, failure)
+		}
+		if matches, _ := filepath.Glob("bad*"); len(matches) != 0 {
+			t.Errorf("a failed export left %v", matches)
+		}
+	})
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/csv"
+	"github.com/gen2brain/raylib-go/raylib"
+	"io"
+	"strconv"
+)
+
+// Flow tells where a cell's light comes from: the offset (DX, DY) of the neighbor whose level it inherits.
+// The zero value means no flow (sources, blockers, dark cells, or cells that have not settled yet).
+type Flow struct {
+	DX int32
+	DY int32
+
+	// Set if several neighbors supply the same level. DX and DY are then 0.
+	Tied bool
+}
+
+// HasArrow tells if the flow points at a single neighbor.
+func (flow Flow) HasArrow() bool {
+	return flow.DX != 0 || flow.DY != 0
+}
+
+// FlowField returns, per lit non-source cell, the neighbor it gets its light from: the one exactly one level
+// brighter. The result is indexed as field[y][x].
+func (layout Layout) FlowField() [][]Flow {
+	width, height := layout.Size()
+	field := make([][]Flow, height)
+	for y := int32(0); y < height; y++ {
+		field[y] = make([]Flow, width)
+		for x := int32(0); x < width; x++ {
+			point := Point{X: x, Y: y}
+			cell := layout.Get(point)
+			if cell.Source != 0 || cell.Level <= 0 {
+				continue
+			}
+
+			suppliers := 0
+			for _, neighbor := range point.Neighbors() {
+				// Off the grid, Get returns a blocker.
+				other := layout.Get(neighbor)
+				if other.Source < 0 || other.Level != cell.Level+1 {
+					continue
+				}
+				suppliers++
+				field[y][x] = Flow{DX: neighbor.X - x, DY: neighbor.Y - y}
+			}
+			if suppliers > 1 {
+				field[y][x] = Flow{Tied: true}
+			}
+		}
+	}
+	return field
+}
+
+// WriteFlowFieldCSV writes one "x,y,dx,dy,tied" row per cell that has a flow, after a header.
+func WriteFlowFieldCSV(w io.Writer, field [][]Flow) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"x", "y", "dx", "dy", "tied"}); err != nil {
+		return err
+	}
+	for y, row := range field {
+		for x, flow := range row {
+			if !flow.HasArrow() && !flow.Tied {
+				continue
+			}
+			record := []string{
+				strconv.Itoa(x),
+				strconv.Itoa(y),
+				strconv.Itoa(int(flow.DX)),
+				strconv.Itoa(int(flow.DY)),
+				strconv.FormatBool(flow.Tied),
+			}
+			if err := writer.Write(record); err != nil {
+				return err
+			}
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// exportFlowField writes flow.csv to the working directory and returns its name.
+func (layout Layout) exportFlowField() (string, error) {
+	return writeExport("flow.csv", nil, func(w io.Writer) error {
+		return WriteFlowFieldCSV(w, layout.FlowField())
+	})
+}
+
+// MinFlowArrowSpacingPx is how close arrows may get on screen; with smaller cells, only every few cells gets one.
+const MinFlowArrowSpacingPx = int32(12)
+
+// raylibDrawFlowField draws an arrow from each cell towards the neighbor its light comes from, or a dot for ties.
+func raylibDrawFlowField(field [][]Flow) {
+	stride := (MinFlowArrowSpacingPx + SquareSideLengthPx - 1) / SquareSideLengthPx
+	color := rl.DarkBlue
+
+	for y := int32(0); y < int32(len(field)); y += stride {
+		for x := int32(0); x < int32(len(field[y])); x += stride {
+			flow := field[y][x]
+			centerX := float32(x*SquareSideLengthPx + SquareSideLengthPx/2)
+			centerY := float32(y*SquareSideLengthPx + SquareSideLengthPx/2)
+
+			if flow.Tied {
+				rl.DrawCircle(int32(centerX), int32(centerY), 2, color)
+				continue
+			}
+			if !flow.HasArrow() {
+				continue
+			}
+
+			// From behind the center to near the cell edge, with a two-stroke head.
+			length := float32(SquareSideLengthPx) * 0.4
+			dx := float32(flow.DX)
+			dy := float32(flow.DY)
+			tail := rl.NewVector2(centerX-dx*length/2, centerY-dy*length/2)
+			tip := rl.NewVector2(centerX+dx*length, centerY+dy*length)
+			rl.DrawLineV(tail, tip, color)
+
+			head := length / 2
+			rl.DrawLineV(tip, rl.NewVector2(tip.X-dx*head-dy*head/2, tip.Y-dy*head+dx*head/2), color)
+			rl.DrawLineV(tip, rl.NewVector2(tip.X-dx*head+dy*head/2, tip.Y-dy*head-dx*head/2), color)
+		}
+	}
+}
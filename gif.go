@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"io"
+)
+
+// Limits on GIF recordings: GIFMaxFrames keeps a forgotten recording from filling memory and disk. Cells are
+// GIFCellPx wide, less if that would make frames wider or higher than GIFMaxSidePx, down to one pixel per cell.
+const (
+	GIFMaxFrames = 600
+	GIFMaxSidePx = 512
+	GIFCellPx    = 16
+)
+
+// gifPalette is the fixed palette of recorded frames: black for boundaries, numbers and blockers' "x", then stops
+// along the yellow ramp of empty cells and the orange ramp of sources. Levels in between show as the nearest stop.
+func gifPalette() color.Palette {
+	palette := color.Palette{color.RGBA{A: 255}}
+	for _, level := range []int32{0, 2, 4, 6, 8, 10, 12, 14, 15} {
+		palette = append(palette, CellColor(Cell{Level: level}, BlendLinear))
+	}
+	for _, level := range []int32{3, 6, 9, 12, 14, 15} {
+		palette = append(palette, CellColor(Cell{Source: level, Level: level}, BlendLinear))
+	}
+	return palette
+}
+
+// GIFRecording accumulates rendered frames of the grid, for an animated GIF of the light spreading. Frames are
+// drawn with RenderGrid, not read back from the window, so recording needs no window.
+type GIFRecording struct {
+	// Delay per frame, in hundredths of a second
+	Delay int
+
+	palette color.Palette
+	frames  []*image.Paletted
+}
+
+// NewGIFRecording starts an empty recording played back at fps frames per second.
+func NewGIFRecording(fps int) *GIFRecording {
+	return &GIFRecording{Delay: 100 / fps, palette: gifPalette()}
+}
+
+// Add renders a snapshot as the next frame. It returns false, adding nothing, once GIFMaxFrames are recorded.
+func (recording *GIFRecording) Add(snapshot *Snapshot) bool {
+	if len(recording.frames) >= GIFMaxFrames {
+		return false
+	}
+	width, height := snapshot.Size()
+	cellPx := int32(GIFCellPx)
+	if side := int32Max(width, height); side*cellPx > GIFMaxSidePx {
+		cellPx = int32Max(1, GIFMaxSidePx/side)
+	}
+
+	var frame *image.Paletted
+	if cellPx == GIFCellPx {
+		// Small grids look as in the window, numbers and all.
+		rendered := RenderGrid(snapshot, cellPx, true, BlendLinear)
+		frame = image.NewPaletted(rendered.Bounds(), recording.palette)
+		draw.Draw(frame, frame.Bounds(), rendered, image.Point{}, draw.Src)
+	} else {
+		// Big grids are only colored squares, filled straight in the palette, which keeps up with the frame rate.
+		frame = image.NewPaletted(image.Rect(0, 0, int(width*cellPx), int(height*cellPx)), recording.palette)
+		indices := map[color.RGBA]uint8{}
+		for y := int32(0); y < height; y++ {
+			for x := int32(0); x < width; x++ {
+				cell, _ := snapshot.At(Point{X: x, Y: y})
+				// Keyed by the fill, which is cheaper to get than the blended color.
+				fill := cellFillColor(cell)
+				index, known := indices[fill]
+				if !known {
+					index = uint8(recording.palette.Index(CellColor(cell, BlendLinear)))
+					indices[fill] = index
+				}
+				for py := y * cellPx; py < (y+1)*cellPx; py++ {
+					row := frame.Pix[int(py)*frame.Stride:]
+					for px := x * cellPx; px < (x+1)*cellPx; px++ {
+						row[px] = index
+					}
+				}
+			}
+		}
+	}
+	recording.frames = append(recording.frames, frame)
+	return true
+}
+
+// Len is the number of frames recorded.
+func (recording *GIFRecording) Len() int {
+	return len(recording.frames)
+}
+
+// WriteGIF encodes the frames as an animated GIF that loops forever.
+func (recording *GIFRecording) WriteGIF(w io.Writer) error {
+	animation := &gif.GIF{Image: recording.frames, Delay: make([]int, len(recording.frames))}
+	for i := range animation.Delay {
+		animation.Delay[i] = recording.Delay
+	}
+	return gif.EncodeAll(w, animation)
+}
+
+// exportGIF writes propagation.gif to the working directory and returns its name.
+func (recording *GIFRecording) exportGIF() (string, error) {
+	params := ExportParams{"delay": fmt.Sprintf("%dcs", recording.Delay)}
+	return writeExport("propagation.gif", params, recording.WriteGIF)
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+)
+
+// DefaultExportCellPx is the side of a cell in PNG exports unless -png-cell-px says otherwise: a 16x16 grid comes
+// out 1024 pixels wide.
+const DefaultExportCellPx = 64
+
+// glyphs is a 3x5 pixel font for the characters cells show. Images have no font to draw with otherwise.
+var glyphs = map[byte][5]string{
+	'0': {"###", "#.#", "#.#", "#.#", "###"},
+	'1': {".#.", "##.", ".#.", ".#.", "###"},
+	'2': {"###", "..#", "###", "#..", "###"},
+	'3': {"###", "..#", "###", "..#", "###"},
+	'4': {"#.#", "#.#", "###", "..#", "..#"},
+	'5': {"###", "#..", "###", "..#", "###"},
+	'6': {"###", "#..", "###", "#.#", "###"},
+	'7': {"###", "..#", "..#", "..#", "..#"},
+	'8': {"###", "#.#", "###", "#.#", "###"},
+	'9': {"###", "#.#", "###", "..#", "###"},
+	'x': {"...", "#.#", ".#.", "#.#", "..."},
+}
+
+// glyphPixel is the side of one font pixel at a font size. Like a real font, a line is taller than its glyphs:
+// 7 font pixels, the 5 of a glyph with one above and one below.
+func glyphPixel(size int32) int32 {
+	if size < 7 {
+		return 1
+	}
+	return size / 7
+}
+
+// measureGlyphs is the width of text in the glyph font, with one font pixel between characters. It has the
+// signature layoutCellText takes, so that exports place numbers as the window does.
+func measureGlyphs(text string, size int32) int32 {
+	if text == "" {
+		return 0
+	}
+	pixel := glyphPixel(size)
+	return int32(len(text))*4*pixel - pixel
+}
+
+// drawGlyphs draws a line of text with its top-left corner at (x, y).
+func drawGlyphs(img draw.Image, text string, x int32, y int32, size int32, c color.Color) {
+	pixel := glyphPixel(size)
+	y += pixel
+	ink := image.NewUniform(c)
+	for i := 0; i < len(text); i++ {
+		glyph := glyphs[text[i]]
+		left := x + int32(i)*4*pixel
+		for row, line := range glyph {
+			for column := 0; column < len(line); column++ {
+				if line[column] != '#' {
+					continue
+				}
+				dot := image.Rect(int(left+int32(column)*pixel), int(y+int32(row)*pixel),
+					int(left+int32(column+1)*pixel), int(y+int32(row+1)*pixel))
+				draw.Draw(img, dot, ink, image.Point{}, draw.Src)
+			}
+		}
+	}
+}
+
+// RenderGrid draws a snapshot the way the window does, cellPx pixels per cell: cell colors (blended as blending
+// says), square boundaries and, if numbers is set, the levels and emissions. It needs no window or GPU.
+func RenderGrid(snapshot *Snapshot, cellPx int32, numbers bool, blending Blending) *image.RGBA {
+	width, height := snapshot.Size()
+	img := image.NewRGBA(image.Rect(0, 0, int(width*cellPx), int(height*cellPx)))
+	black := image.NewUniform(color.Black)
+	for y := int32(0); y < height; y++ {
+		for x := int32(0); x < width; x++ {
+			cell, _ := snapshot.At(Point{X: x, Y: y})
+			left, top := x*cellPx, y*cellPx
+			square := image.Rect(int(left), int(top), int(left+cellPx), int(top+cellPx))
+			draw.Draw(img, square, image.NewUniform(CellColor(cell, blending)), image.Point{}, draw.Src)
+
+			if numbers {
+				for _, label := range layoutCellText(cell, cellPx, measureGlyphs) {
+					drawGlyphs(img, label.Text, left+label.X, top+label.Y, label.Size, color.Black)
+				}
+			}
+
+			// Square boundaries, one pixel wide
+			for _, edge := range []image.Rectangle{
+				image.Rect(square.Min.X, square.Min.Y, square.Max.X, square.Min.Y+1),
+				image.Rect(square.Min.X, square.Max.Y-1, square.Max.X, square.Max.Y),
+				image.Rect(square.Min.X, square.Min.Y, square.Min.X+1, square.Max.Y),
+				image.Rect(square.Max.X-1, square.Min.Y, square.Max.X, square.Max.Y),
+			} {
+				draw.Draw(img, edge, black, image.Point{}, draw.Src)
+			}
+		}
+	}
+	return img
+}
+
+// WriteGridPNG writes RenderGrid's image as a PNG, blended in linear light.
+func WriteGridPNG(w io.Writer, snapshot *Snapshot, cellPx int32, numbers bool) error {
+	return png.Encode(w, RenderGrid(snapshot, cellPx, numbers, BlendLinear))
+}
+
+// writeGridPNGFile writes WriteGridPNG's image to the file named by exportName for path, and returns the name.
+func writeGridPNGFile(path string, snapshot *Snapshot, cellPx int32, numbers bool) (string, error) {
+	params := ExportParams{"cell": fmt.Sprintf("%dpx", cellPx), "numbers": "off"}
+	if numbers {
+		params["numbers"] = "on"
+	}
+	return writeExport(path, params, func(w io.Writer) error {
+		return WriteGridPNG(w, snapshot, cellPx, numbers)
+	})
+}
+
+// exportGridPNG writes grid.png to the working directory and returns its name.
+func exportGridPNG(snapshot *Snapshot, cellPx int32, numbers bool) (string, error) {
+	return writeGridPNGFile("grid.png", snapshot, cellPx, numbers)
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"io"
+)
+
+// runHeadless reads a layout file (see loadLayoutFile) from path, or from stdin if path is empty, runs it to
+// convergence and hands it to output. The output is produced even if the layout did not settle within
+// maxIterations passes, but then an ErrNotConverged error is returned.
+func runHeadless(path string, maxIterations int, output func(Layout) error) error {
+	if path == "" {
+		path = "-"
+	}
+	layout, err := loadLayoutFile(path)
+	if err != nil {
+		return err
+	}
+
+	iterations, converged := layout.Converge(maxIterations)
+	if err := output(layout); err != nil {
+		return err
+	}
+	if !converged {
+		return fmt.Errorf("%w within %d iterations", ErrNotConverged, iterations)
+	}
+	return nil
+}
+
+// writeLevelsCSV writes the light levels of the layout as CSV, one row per grid row.
+func writeLevelsCSV(w io.Writer, layout Layout) error {
+	width, height := layout.Size()
+	levels := make([][]int32, height)
+	for y := int32(0); y < height; y++ {
+		levels[y] = make([]int32, width)
+		for x := int32(0); x < width; x++ {
+			levels[y][x] = layout.Get(Point{X: x, Y: y}).Level
+		}
+	}
+	return writeGridCSV(w, levels)
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRunHeadless(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "layout.csv")
+	if err := os.WriteFile(path, []byte("0,0,0,0\n0,4,-1,0\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var output bytes.Buffer
+	err := runHeadless(path, ConvergeMaxIterations, func(layout Layout) error {
+		return writeLevelsCSV(&output, layout)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "2,3,2,1\n3,4,0,0\n"; output.String() != want {
+		t.Errorf("levels are\n%swant\n%s", output.String(), want)
+	}
+
+	// The output is there even when the layout does not settle in time, for a look at how far it got.
+	output.Reset()
+	err = runHeadless(path, 1, func(layout Layout) error { return writeLevelsCSV(&output, layout) })
+	if !errors.Is(err, ErrNotConverged) || output.Len() == 0 {
+		t.Errorf("runHeadless with 1 iteration gave %v and %d bytes, want ErrNotConverged and the levels",
+			err, output.Len())
+	}
+
+	if err := runHeadless(filepath.Join(t.TempDir(), "missing.csv"), 1, nil); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("runHeadless on a missing file gave %v, want an os.ErrNotExist error", err)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/jpeg"
+	_ "image/png"
+	"os"
+)
+
+// DefaultImageThreshold is the luminance below which an imported image pixel stays dark unless
+// -from-image-threshold says otherwise.
+const DefaultImageThreshold = 128
+
+// ImportImage builds a width x height layout from a PNG or JPEG image, lighting cells by brightness: luminance
+// 0-255 maps to emission 0-15, pixels darker than threshold stay empty and pure black ones become blockers.
+//
+// An image larger than the grid is downsampled, each cell taking the average luminance of the pixels it covers;
+// along a side where the image is smaller, it is placed at the top-left and the remaining cells are empty.
+// Transparent pixels count as empty.
+func ImportImage(path string, width int32, height int32, threshold uint8) (Layout, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Layout{}, err
+	}
+	defer file.Close()
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return Layout{}, fmt.Errorf("%s: %w", path, err)
+	}
+
+	bounds := img.Bounds()
+	// Pixels [span(i), span(i+1)) along a side of `pixels` go to cell i of `cells`, one each if the image is smaller.
+	span := func(i int32, cells int32, pixels int) int {
+		if pixels <= int(cells) {
+			return int(i)
+		}
+		return int(int64(i) * int64(pixels) / int64(cells))
+	}
+
+	layout := NewLayout(width, height)
+	for y := int32(0); y < height && int(y) < bounds.Dy(); y++ {
+		for x := int32(0); x < width && int(x) < bounds.Dx(); x++ {
+			var sum, count, opaque int
+			for py := span(y, height, bounds.Dy()); py < span(y+1, height, bounds.Dy()); py++ {
+				for px := span(x, width, bounds.Dx()); px < span(x+1, width, bounds.Dx()); px++ {
+					pixel := img.At(bounds.Min.X+px, bounds.Min.Y+py)
+					count++
+					if _, _, _, alpha := pixel.RGBA(); alpha == 0 {
+						continue
+					}
+					sum += int(color.GrayModel.Convert(pixel).(color.Gray).Y)
+					opaque++
+				}
+			}
+			// Mostly transparent cells are left empty.
+			if opaque*2 <= count {
+				continue
+			}
+
+			luminance := (sum + opaque/2) / opaque
+			switch {
+			case luminance == 0:
+				layout.SetSource(Point{X: x, Y: y}, -1)
+			case luminance >= int(threshold):
+				layout.SetSource(Point{X: x, Y: y}, int32((luminance*15+127)/255))
+			}
+		}
+	}
+	return layout, nil
+}
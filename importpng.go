@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ColorMapping maps a pixel color to a cell emission.
+type ColorMapping struct {
+	Color  color.RGBA
+	Source int32
+}
+
+// ColorTable is the list of colors an imported PNG is matched against.
+type ColorTable []ColorMapping
+
+// DefaultColorTable maps pure white to 15, the gray ramp (level * 17) to levels 1-14 and pure black to a blocker.
+func DefaultColorTable() ColorTable {
+	table := ColorTable{{Color: color.RGBA{A: 255}, Source: -1}}
+	for level := int32(1); level <= 15; level++ {
+		gray := uint8(level * 17)
+		table = append(table, ColorMapping{Color: color.RGBA{R: gray, G: gray, B: gray, A: 255}, Source: level})
+	}
+	return table
+}
+
+// ParseColorTable reads a color table, one mapping per line: a hex color and an emission, e.g. "#ffaa00 12".
+// Blank lines and lines starting with '#' followed by a space are ignored.
+func ParseColorTable(r io.Reader) (ColorTable, error) {
+	table := ColorTable{}
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "# ") {
+			continue
+		}
+
+		fields := strings.Fields(text)
+		if len(fields) != 2 {
+			return nil, &InputError{Line: line, Msg: fmt.Sprintf("expected \"#rrggbb emission\", got %q", text)}
+		}
+
+		hex := strings.TrimPrefix(fields[0], "#")
+		rgb, err := strconv.ParseUint(hex, 16, 32)
+		if err != nil || len(hex) != 6 {
+			return nil, &InputError{Line: line, Column: 1, Msg: fmt.Sprintf("bad color %q", fields[0])}
+		}
+
+		source, err := strconv.Atoi(fields[1])
+		if err != nil || source < -1 || source > 15 {
+			return nil, &InputError{Line: line, Column: 2, Msg: fmt.Sprintf("bad emission %q (must be -1 to 15)", fields[1])}
+		}
+
+		table = append(table, ColorMapping{
+			Color:  color.RGBA{R: uint8(rgb >> 16), G: uint8(rgb >> 8), B: uint8(rgb), A: 255},
+			Source: int32(source),
+		})
+	}
+	return table, scanner.Err()
+}
+
+func colorDistance(a color.RGBA, b color.RGBA) float64 {
+	dr := float64(a.R) - float64(b.R)
+	dg := float64(a.G) - float64(b.G)
+	db := float64(a.B) - float64(b.B)
+	return math.Sqrt(dr*dr + dg*dg + db*db)
+}
+
+// Match returns the emission of the table color nearest to c, if it is within tolerance (RGB distance).
+func (table ColorTable) Match(c color.RGBA, tolerance float64) (int32, bool) {
+	best := -1
+	bestDistance := math.Inf(1)
+	for i, mapping := range table {
+		if distance := colorDistance(c, mapping.Color); distance < bestDistance {
+			best = i
+			bestDistance = distance
+		}
+	}
+	if best < 0 || bestDistance > tolerance {
+		return 0, false
+	}
+	return table[best].Source, true
+}
+
+// ImportSummary reports what an import could not map.
+type ImportSummary struct {
+	// Cell count per color that matched nothing in the table (those cells are left empty).
+	Unmapped map[color.RGBA]int
+
+	// Set if the image was larger than the grid and got cropped.
+	Cropped bool
+
+	// Set if the image was larger than the grid and the grid grew to fit it.
+	Resized bool
+}
+
+// PNGImport says how ImportPNG reads an image.
+type PNGImport struct {
+	Table ColorTable
+
+	// How far (RGB distance) a pixel may be from a table color and still match it.
+	Tolerance float64
+
+	// Side of a cell in the image: 1 for pixel art, the export's cell size to read back a grid PNG. Each cell takes
+	// the color at its center, clear of the boundaries exports draw. 0 means 1.
+	CellPx int32
+
+	// Grow the grid to fit images larger than it, rather than cropping them.
+	Resize bool
+}
+
+// ImportPNG builds a width x height layout from a PNG, one cell per CellPx x CellPx square, mapping each cell's
+// color through the table. Fully transparent pixels are empty cells. Images larger than the grid are cropped to its
+// top-left corner or, with Resize, grow the grid to their size.
+func ImportPNG(r io.Reader, width int32, height int32, options PNGImport) (Layout, ImportSummary, error) {
+	img, err := png.Decode(r)
+	if err != nil {
+		return Layout{}, ImportSummary{}, err
+	}
+
+	cellPx := int32Max(options.CellPx, 1)
+	bounds := img.Bounds()
+	cellsX, cellsY := int32(bounds.Dx())/cellPx, int32(bounds.Dy())/cellPx
+
+	summary := ImportSummary{Unmapped: map[color.RGBA]int{}}
+	if cellsX > width || cellsY > height {
+		if options.Resize {
+			width, height = int32Max(width, cellsX), int32Max(height, cellsY)
+			summary.Resized = true
+		} else {
+			summary.Cropped = true
+		}
+	}
+	layout := NewLayout(width, height)
+
+	for y := int32(0); y < height && y < cellsY; y++ {
+		for x := int32(0); x < width && x < cellsX; x++ {
+			center := image.Point{X: int(x*cellPx + cellPx/2), Y: int(y*cellPx + cellPx/2)}.Add(bounds.Min)
+			pixel := color.RGBAModel.Convert(img.At(center.X, center.Y)).(color.RGBA)
+			if pixel.A == 0 {
+				continue
+			}
+
+			source, ok := options.Table.Match(pixel, options.Tolerance)
+			if !ok {
+				summary.Unmapped[pixel]++
+				continue
+			}
+			cell, _ := layout.At(Point{X: x, Y: y})
+			cell.Source = source
+		}
+	}
+
+	return layout, summary, nil
+}
+
+// importPNGFile imports a PNG file, logging what could not be mapped.
+func importPNGFile(path string, width int32, height int32, options PNGImport) (Layout, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Layout{}, err
+	}
+	defer file.Close()
+
+	layout, summary, err := ImportPNG(file, width, height, options)
+	if err != nil {
+		return Layout{}, fmt.Errorf("%s: %w", path, err)
+	}
+
+	if summary.Cropped {
+		log.Printf("%s is larger than the %dx%d grid; cropped to its top-left corner\n", path, width, height)
+	}
+	if summary.Resized {
+		resizedWidth, resizedHeight := layout.Size()
+		log.Printf("%s is larger than the %dx%d grid; grid grown to %dx%d\n", path, width, height, resizedWidth,
+			resizedHeight)
+	}
+	for c, count := range summary.Unmapped {
+		log.Printf("%s: %d cell(s) of unmapped color #%02x%02x%02x left empty\n", path, count, c.R, c.G, c.B)
+	}
+	return layout, nil
+}
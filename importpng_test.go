@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+// exporterColorTable maps the colors grid PNG exports paint back to emissions: blockers, each source at its own
+// level and every level of an empty cell. Exports paint unlit empty cells as blockers, and a source lit past its
+// emission as the brighter one, so only layouts without either read back exactly.
+func exporterColorTable() ColorTable {
+	table := ColorTable{{Color: CellColor(Cell{Source: -1}, BlendLinear), Source: -1}}
+	for level := int32(1); level <= 15; level++ {
+		table = append(table,
+			ColorMapping{Color: CellColor(Cell{Level: level}, BlendLinear), Source: 0},
+			ColorMapping{Color: CellColor(Cell{Source: level, Level: level}, BlendLinear), Source: level})
+	}
+	return table
+}
+
+func TestImportPNGRoundTrip(t *testing.T) {
+	// Every emission along a row, brighter to the right and two cells apart so that none outshines the next, with
+	// blockers in two of the gaps. Every open cell is lit.
+	layout := NewLayout(32, 1)
+	for emission := int32(1); emission <= 15; emission++ {
+		layout.SetSource(Point{X: 2 * (emission - 1), Y: 0}, emission)
+	}
+	layout.SetSource(Point{X: 5, Y: 0}, -1)
+	layout.SetSource(Point{X: 11, Y: 0}, -1)
+	layout.Converge(ConvergeMaxIterations)
+
+	for _, cellPx := range []int32{3, 8, DefaultExportCellPx} {
+		var buf bytes.Buffer
+		if err := WriteGridPNG(&buf, layout.Snapshot(), cellPx, false); err != nil {
+			t.Fatal(err)
+		}
+		imported, summary, err := ImportPNG(&buf, 32, 1, PNGImport{Table: exporterColorTable(), CellPx: cellPx})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(summary.Unmapped) > 0 || summary.Cropped || summary.Resized {
+			t.Errorf("%dpx cells: summary %+v, want every cell mapped at the grid's size", cellPx, summary)
+		}
+		for x, cell := range imported.Cells() {
+			if want := layout.Cells()[x]; cell.Source != want.Source {
+				t.Errorf("%dpx cells: cell %d imported as %d, want %d", cellPx, x, cell.Source, want.Source)
+			}
+		}
+	}
+}
+
+// TestImportPNGOversized reads a 6x4 image into a 4x3 grid, cropped and resized.
+func TestImportPNGOversized(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 6, 4))
+	img.Set(1, 1, color.White)
+	img.Set(5, 3, color.Black)
+	img.Set(2, 0, color.RGBA{R: 255, A: 255})
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		resize  bool
+		width   int32
+		height  int32
+		blocker bool
+	}{
+		{resize: false, width: 4, height: 3},
+		{resize: true, width: 6, height: 4, blocker: true},
+	}
+	for _, test := range tests {
+		options := PNGImport{Table: DefaultColorTable(), Tolerance: 24, Resize: test.resize}
+		layout, summary, err := ImportPNG(bytes.NewReader(buf.Bytes()), 4, 3, options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if summary.Cropped == test.resize || summary.Resized != test.resize {
+			t.Errorf("resize %v: cropped %v and resized %v", test.resize, summary.Cropped, summary.Resized)
+		}
+		if width, height := layout.Size(); width != test.width || height != test.height {
+			t.Errorf("resize %v: grid is %dx%d, want %dx%d", test.resize, width, height, test.width, test.height)
+		}
+		if source := layout.Get(Point{X: 1, Y: 1}).Source; source != 15 {
+			t.Errorf("resize %v: white pixel imported as %d, want 15", test.resize, source)
+		}
+		corner := Point{X: 5, Y: 3}
+		if blocker := layout.Contains(corner) && layout.Get(corner).Source == -1; blocker != test.blocker {
+			t.Errorf("resize %v: blocker at (5, 3) is %v, want %v", test.resize, blocker, test.blocker)
+		}
+		if count := summary.Unmapped[color.RGBA{R: 255, A: 255}]; count != 1 {
+			t.Errorf("resize %v: %d red cell(s) unmapped, want 1", test.resize, count)
+		}
+	}
+}
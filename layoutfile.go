@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// LayoutFromEmissions builds a layout from a grid of emissions, indexed [y][x]: -1 for blockers, 0 for empty cells,
+// 1 to 15 for sources. Every row must be as long as the first.
+func LayoutFromEmissions(grid [][]int32) (Layout, error) {
+	if len(grid) == 0 || len(grid[0]) == 0 {
+		return Layout{}, &InputError{Msg: "the grid is empty"}
+	}
+	layout := NewLayout(int32(len(grid[0])), int32(len(grid)))
+	for y, row := range grid {
+		if len(row) != len(grid[0]) {
+			return Layout{}, &InputError{Line: y + 1,
+				Msg: fmt.Sprintf("%d cells, the first row has %d", len(row), len(grid[0]))}
+		}
+		for x, emission := range row {
+			if emission < -1 || emission > 15 {
+				return Layout{}, &InputError{Line: y + 1, Column: x + 1,
+					Msg: fmt.Sprintf("emission %d (must be -1 to 15)", emission)}
+			}
+			layout.SetSource(Point{X: int32(x), Y: int32(y)}, emission)
+		}
+	}
+	return layout, nil
+}
+
+// Emissions returns the emission of every cell as Emissions[y][x], the grid LayoutFromEmissions takes.
+func (layout Layout) Emissions() [][]int32 {
+	width, height := layout.Size()
+	grid := make([][]int32, height)
+	for y := range grid {
+		grid[y] = make([]int32, width)
+		for x := range grid[y] {
+			grid[y][x] = layout.Get(Point{X: int32(x), Y: int32(y)}).Source
+		}
+	}
+	return grid
+}
+
+// asciiCells are the characters of the ASCII layout format, by emission: '.' for empty, '1'-'9' and 'a'-'f' for
+// sources, '#' for blockers.
+const asciiCells = ".123456789abcdef"
+
+// ParseASCII reads a layout in the ASCII format: one line per row and one character per cell, '.' (or '0') for an
+// empty cell, '1'-'9' and 'a'-'f' for emissions 1 to 15, and '#' for a blocker. Blank lines at the end are ignored.
+func ParseASCII(r io.Reader) (Layout, error) {
+	var grid [][]int32
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimRight(scanner.Text(), "\r")
+		row := make([]int32, len(text))
+		for x := 0; x < len(text); x++ {
+			switch c := text[x]; {
+			case c == '#':
+				row[x] = -1
+			case c == '0':
+				row[x] = 0
+			case strings.IndexByte(asciiCells, c) >= 0:
+				row[x] = int32(strings.IndexByte(asciiCells, c))
+			default:
+				return Layout{}, &InputError{Line: line, Column: x + 1,
+					Msg: fmt.Sprintf("%q is not a cell (expected '.', '#', 0-9 or a-f)", c)}
+			}
+		}
+		grid = append(grid, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return Layout{}, err
+	}
+	for len(grid) > 0 && len(grid[len(grid)-1]) == 0 {
+		grid = grid[:len(grid)-1]
+	}
+	return LayoutFromEmissions(grid)
+}
+
+// WriteASCII writes the layout's emissions in the format ParseASCII reads.
+func (layout Layout) WriteASCII(w io.Writer) error {
+	var text strings.Builder
+	for _, row := range layout.Emissions() {
+		for _, emission := range row {
+			if emission < 0 {
+				text.WriteByte('#')
+			} else {
+				text.WriteByte(asciiCells[emission])
+			}
+		}
+		text.WriteByte('\n')
+	}
+	_, err := io.WriteString(w, text.String())
+	return err
+}
+
+// LayoutFormat is a file format for emission grids.
+type LayoutFormat int
+
+const (
+	// LayoutCSV is one line of comma-separated emissions per row, the format writeGridCSV writes.
+	LayoutCSV LayoutFormat = iota
+	// LayoutJSON is an array of rows, each an array of emissions.
+	LayoutJSON
+	// LayoutASCII is one character per cell, see ParseASCII.
+	LayoutASCII
+)
+
+// layoutFormatOf picks the format of a layout file from its extension: .json for JSON, .txt for ASCII, CSV
+// otherwise.
+func layoutFormatOf(path string) LayoutFormat {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return LayoutJSON
+	case ".txt":
+		return LayoutASCII
+	default:
+		return LayoutCSV
+	}
+}
+
+// ReadLayout reads a grid of emissions (see LayoutFromEmissions) in the given format.
+func ReadLayout(r io.Reader, format LayoutFormat) (Layout, error) {
+	var grid [][]int32
+	var err error
+	switch format {
+	case LayoutASCII:
+		return ParseASCII(r)
+	case LayoutJSON:
+		err = json.NewDecoder(r).Decode(&grid)
+	default:
+		grid, err = ReadGridCSV(r)
+	}
+	if err != nil {
+		return Layout{}, err
+	}
+	return LayoutFromEmissions(grid)
+}
+
+// WriteLayout writes the layout's emissions in the given format.
+func (layout Layout) WriteLayout(w io.Writer, format LayoutFormat) error {
+	switch format {
+	case LayoutASCII:
+		return layout.WriteASCII(w)
+	case LayoutJSON:
+		return json.NewEncoder(w).Encode(layout.Emissions())
+	default:
+		return writeGridCSV(w, layout.Emissions())
+	}
+}
+
+// sniffLayoutFormat guesses the format of a layout from its start: JSON if its first non-blank line starts with "[",
+// CSV if that line has a comma or every non-blank line is a single number (a grid one cell wide, such as "-1" or
+// "12"), ASCII otherwise.
+func sniffLayoutFormat(start string) LayoutFormat {
+	lines := strings.Split(start, "\n")
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	if len(lines) == 0 {
+		return LayoutASCII
+	}
+	firstLine := strings.TrimSpace(lines[0])
+	switch {
+	case strings.HasPrefix(firstLine, "["):
+		return LayoutJSON
+	case strings.Contains(firstLine, ","):
+		return LayoutCSV
+	}
+	for _, line := range lines {
+		if line = strings.TrimSpace(line); line == "" {
+			continue
+		}
+		if _, err := strconv.Atoi(line); err != nil {
+			return LayoutASCII
+		}
+	}
+	return LayoutCSV
+}
+
+// loadLayoutFile reads a layout from path, in the format its extension says (see layoutFormatOf), with the light
+// caps kept next to it if any (see capsSidecarPath), or from stdin if path is "-", in the format its start looks
+// like (see sniffLayoutFormat).
+func loadLayoutFile(path string) (Layout, error) {
+	if path == "-" {
+		r := bufio.NewReader(os.Stdin)
+		peeked, err := r.Peek(4096)
+		if err != nil && err != io.EOF {
+			return Layout{}, err
+		}
+		layout, err := ReadLayout(r, sniffLayoutFormat(string(peeked)))
+		if err != nil {
+			return Layout{}, fmt.Errorf("stdin: %w", err)
+		}
+		return layout, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return Layout{}, err
+	}
+	defer file.Close()
+	layout, err := ReadLayout(file, layoutFormatOf(path))
+	if err != nil {
+		return Layout{}, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := loadCapsSidecar(path, layout); err != nil {
+		return Layout{}, err
+	}
+	return layout, nil
+}
+
+// saveLayoutFile writes the layout's emissions to path, in the format its extension says (see layoutFormatOf), and
+// its light caps next to it (see saveCapsSidecar).
+func saveLayoutFile(path string, layout Layout) error {
+	if err := saveCapsSidecar(path, layout); err != nil {
+		return err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := layout.WriteLayout(file, layoutFormatOf(path)); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
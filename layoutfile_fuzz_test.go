@@ -0,0 +1,49 @@
+//go:build go1.18
+
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+// FuzzReadLayout checks that no input makes ReadLayout panic, and that whatever it accepts is written back in a
+// form it reads again as the same emissions.
+func FuzzReadLayout(f *testing.F) {
+	seeds := []string{
+		"0,1,2\n-1,0,15\n",
+		"-1\n",
+		"[[0, 1], [-1, 15]]",
+		"..#\n.f.\n",
+		"#\n\n",
+		"1,2\n3\n",
+		"[[16]]",
+		"\"a,\nb\"",
+	}
+	for _, seed := range seeds {
+		for _, format := range []LayoutFormat{LayoutCSV, LayoutJSON, LayoutASCII} {
+			f.Add([]byte(seed), uint8(format))
+		}
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte, formatByte uint8) {
+		format := LayoutFormat(formatByte % 3)
+		layout, err := ReadLayout(bytes.NewReader(data), format)
+		if err != nil {
+			return
+		}
+
+		var written bytes.Buffer
+		if err := layout.WriteLayout(&written, format); err != nil {
+			t.Fatalf("writing %v back: %v", layout.Emissions(), err)
+		}
+		again, err := ReadLayout(&written, format)
+		if err != nil {
+			t.Fatalf("reading back %q: %v", written.String(), err)
+		}
+		if !reflect.DeepEqual(again.Emissions(), layout.Emissions()) {
+			t.Fatalf("read back %v, want %v", again.Emissions(), layout.Emissions())
+		}
+	})
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSniffLayoutFormat(t *testing.T) {
+	tests := []struct {
+		start string
+		want  LayoutFormat
+	}{
+		{"[[0, 1], [-1, 15]]\n", LayoutJSON},
+		{"\n  [\n  [0]\n]", LayoutJSON},
+		{"0,1,2\n-1,0,0\n", LayoutCSV},
+		{"\n\n 0, 1\n", LayoutCSV},
+		// Grids one cell wide.
+		{"-1\n", LayoutCSV},
+		{"12\n0\n-1\n", LayoutCSV},
+		{"3\r\n\r\n4\r\n", LayoutCSV},
+		{"#\n.\n", LayoutASCII},
+		{"..#\n.f.\n", LayoutASCII},
+		{"1\n#\n", LayoutASCII},
+		{"", LayoutASCII},
+	}
+	for _, test := range tests {
+		if got := sniffLayoutFormat(test.start); got != test.want {
+			t.Errorf("sniffLayoutFormat(%q) = %v, want %v", test.start, got, test.want)
+		}
+	}
+}
+
+// A one-column grid read the way it is sniffed gives the emissions it was written with.
+func TestSniffedOneColumnLayout(t *testing.T) {
+	input := "-1\n12\n0\n"
+	layout, err := ReadLayout(strings.NewReader(input), sniffLayoutFormat(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]int32{{-1}, {12}, {0}}
+	got := layout.Emissions()
+	if len(got) != len(want) {
+		t.Fatalf("read %v, want %v", got, want)
+	}
+	for y := range want {
+		if len(got[y]) != 1 || got[y][0] != want[y][0] {
+			t.Fatalf("read %v, want %v", got, want)
+		}
+	}
+}
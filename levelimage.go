@@ -0,0 +1,53 @@
+package main
+
+import (
+	"image"
+	"image/color"
+)
+
+// LevelImage shows a snapshot's light levels as a 16-bit grayscale image, one pixel per cell: level 0 is black and
+// level 15 is white. It can be passed to anything that takes an image.Image (png.Encode, scalers, diff tools).
+type LevelImage struct {
+	Snapshot *Snapshot
+}
+
+func (img LevelImage) ColorModel() color.Model {
+	return color.Gray16Model
+}
+
+func (img LevelImage) Bounds() image.Rectangle {
+	width, height := img.Snapshot.Size()
+	return image.Rect(0, 0, int(width), int(height))
+}
+
+func (img LevelImage) At(x, y int) color.Color {
+	cell, exists := img.Snapshot.At(Point{X: int32(x), Y: int32(y)})
+	if !exists {
+		return color.Gray16{}
+	}
+	return color.Gray16{Y: uint16(levelScale(cell.Level, 0xffff))}
+}
+
+// ColorImage shows a snapshot in the colors of the grid on screen (see CellColor), one pixel per cell.
+// Unless Blending says otherwise, fills are composited in linear light.
+type ColorImage struct {
+	Snapshot *Snapshot
+	Blending Blending
+}
+
+func (img ColorImage) ColorModel() color.Model {
+	return color.RGBAModel
+}
+
+func (img ColorImage) Bounds() image.Rectangle {
+	width, height := img.Snapshot.Size()
+	return image.Rect(0, 0, int(width), int(height))
+}
+
+func (img ColorImage) At(x, y int) color.Color {
+	cell, exists := img.Snapshot.At(Point{X: int32(x), Y: int32(y)})
+	if !exists {
+		return color.RGBA{}
+	}
+	return CellColor(cell, img.Blending)
+}
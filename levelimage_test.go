@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+// imageTestSnapshot returns the snapshot of a converged 7x3 layout, wider than tall.
+func imageTestSnapshot() *Snapshot {
+	layout := NewLayout(7, 3)
+	layout.SetSource(Point{X: 1, Y: 1}, 15)
+	layout.SetSource(Point{X: 5, Y: 0}, 6)
+	layout.SetSource(Point{X: 3, Y: 1}, -1)
+	layout.Converge(ConvergeMaxIterations)
+	return layout.Snapshot()
+}
+
+func TestLevelImage(t *testing.T) {
+	snapshot := imageTestSnapshot()
+	img := LevelImage{Snapshot: snapshot}
+	if img.ColorModel() != color.Gray16Model {
+		t.Errorf("color model is %v, want Gray16Model", img.ColorModel())
+	}
+	if want := image.Rect(0, 0, 7, 3); img.Bounds() != want {
+		t.Fatalf("bounds are %v, want %v", img.Bounds(), want)
+	}
+
+	for y := 0; y < 3; y++ {
+		for x := 0; x < 7; x++ {
+			cell, _ := snapshot.At(Point{X: int32(x), Y: int32(y)})
+			want := color.Gray16{Y: uint16(cell.Level * 0xffff / 15)}
+			if got := img.At(x, y); got != want {
+				t.Errorf("(%d, %d) at level %d is %v, want %v", x, y, cell.Level, got, want)
+			}
+		}
+	}
+	if got := img.At(1, 1); got != (color.Gray16{Y: 0xffff}) {
+		t.Errorf("the level 15 source is %v, want white", got)
+	}
+	if got := img.At(7, 0); got != (color.Gray16{}) {
+		t.Errorf("outside the grid is %v, want black", got)
+	}
+}
+
+// The color image is the PNG export shrunk to one pixel per cell: the center of every cell of the export has the
+// color of that cell's pixel.
+func TestColorImageMatchesPNGExport(t *testing.T) {
+	snapshot := imageTestSnapshot()
+	const cellPx = 5
+
+	var exported bytes.Buffer
+	if err := WriteGridPNG(&exported, snapshot, cellPx, false); err != nil {
+		t.Fatal(err)
+	}
+	grid, err := png.Decode(&exported)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Through png.Encode too, as users would.
+	var encoded bytes.Buffer
+	if err := png.Encode(&encoded, ColorImage{Snapshot: snapshot}); err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(&encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := image.Rect(0, 0, 7, 3); img.Bounds() != want {
+		t.Fatalf("bounds are %v, want %v", img.Bounds(), want)
+	}
+
+	for y := 0; y < 3; y++ {
+		for x := 0; x < 7; x++ {
+			want := color.RGBAModel.Convert(grid.At(x*cellPx+cellPx/2, y*cellPx+cellPx/2))
+			if got := color.RGBAModel.Convert(img.At(x, y)); got != want {
+				t.Errorf("(%d, %d) is %v, the export has %v", x, y, got, want)
+			}
+		}
+	}
+}
+
+func TestColorImageBlending(t *testing.T) {
+	snapshot := imageTestSnapshot()
+	cell, _ := snapshot.At(Point{X: 0, Y: 0})
+	for _, blending := range []Blending{BlendSRGB, BlendLinear} {
+		img := ColorImage{Snapshot: snapshot, Blending: blending}
+		if got, want := img.At(0, 0), CellColor(cell, blending); got != want {
+			t.Errorf("blended in %v, (0, 0) is %v, want %v", blending, got, want)
+		}
+	}
+	if got := (ColorImage{Snapshot: snapshot}).At(0, 3); got != (color.RGBA{}) {
+		t.Errorf("outside the grid is %v, want transparent", got)
+	}
+}
@@ -0,0 +1,30 @@
+package lighting
+
+import (
+	"math"
+)
+
+// MaxAge is where the per-cell age counter saturates.
+const MaxAge = int32(math.MaxInt32)
+
+// AgeField returns, per cell, the number of evolve passes since its light level last changed.
+// The result is indexed as field[y][x].
+func (layout Layout) AgeField() [][]int32 {
+	width, height := layout.Size()
+	field := make([][]int32, height)
+	for y := int32(0); y < height; y++ {
+		field[y] = make([]int32, width)
+		for x := int32(0); x < width; x++ {
+			field[y][x] = layout.age(int(y*width + x))
+		}
+	}
+	return field
+}
+
+// ResetAges restarts the age counter of every cell.
+func (layout Layout) ResetAges() {
+	for i := range layout.cells {
+		layout.cells[i].Age = 0
+	}
+	layout.syncAges()
+}
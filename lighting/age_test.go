@@ -0,0 +1,57 @@
+package lighting
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// Ages count the passes since each cell's level last changed, whether or not the passes visited the cell.
+func TestAgeField(t *testing.T) {
+	rng := rand.New(rand.NewSource(259))
+	layout := randomLayout(rng, 32, 32)
+	want := make([]int32, len(layout.cells))
+
+	for pass := 0; pass < 200; pass++ {
+		if pass%25 == 0 {
+			layout.SetSource(Point{X: rng.Int31n(32), Y: rng.Int31n(32)}, rng.Int31n(17)-1)
+		}
+		before := levels(layout)
+		layout.Evolve()
+		for i, level := range levels(layout) {
+			if level != before[i] {
+				want[i] = 0
+			} else {
+				want[i]++
+			}
+		}
+
+		field := layout.AgeField()
+		snapshot := layout.Snapshot()
+		for i, age := range want {
+			p := layout.point(i)
+			if field[p.Y][p.X] != age {
+				t.Fatalf("pass %d: age at %v is %d, want %d", pass, p, field[p.Y][p.X], age)
+			}
+			if cell, _ := snapshot.At(p); cell.Age != age {
+				t.Fatalf("pass %d: snapshot age at %v is %d, want %d", pass, p, cell.Age, age)
+			}
+		}
+	}
+
+	// Passes that go through every cell pick up where evolve left off.
+	layout.PropagateBFS()
+	for i := range want {
+		want[i]++
+	}
+	for i, age := range want {
+		if got := layout.Cells()[i].Age; got != age {
+			t.Fatalf("age after PropagateBFS at %v is %d, want %d", layout.point(i), got, age)
+		}
+	}
+
+	layout.ResetAges()
+	layout.Evolve()
+	if age := layout.AgeField()[0][0]; age != 1 {
+		t.Errorf("age one pass after ResetAges is %d, want 1", age)
+	}
+}
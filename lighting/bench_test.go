@@ -0,0 +1,144 @@
+package lighting
+
+import (
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// benchmarkLayout is the layout the evolve benchmarks run on: 64x64, with random sources and blockers, converged.
+func benchmarkLayout() Layout {
+	layout := randomLayout(rand.New(rand.NewSource(64)), 64, 64)
+	layout.Converge(100)
+	return layout
+}
+
+// mapLayout keeps cells in a map, the way Layout did before it was backed by a slice, for the benchmarks to compare
+// against.
+type mapLayout map[Point]*Cell
+
+func newMapLayout(layout Layout) mapLayout {
+	cells := mapLayout{}
+	for i := range layout.cells {
+		cell := layout.cells[i]
+		cells[layout.point(i)] = &cell
+	}
+	return cells
+}
+
+// pass updates every cell once, in place.
+func (cells mapLayout) pass() {
+	for p, cell := range cells {
+		max := int32(0)
+		for _, neighbor := range p.Neighbors() {
+			if other, exists := cells[neighbor]; exists && other.Level > max {
+				max = other.Level
+			}
+		}
+		if cell.Source < 0 {
+			cell.Level = 0
+		} else {
+			cell.Level = int32Max(int32Max(max-1, 0), cell.Source)
+		}
+	}
+}
+
+// Updating every cell of the slice-backed layout once, on one goroutine.
+func BenchmarkPassSlice64(b *testing.B) {
+	layout := benchmarkLayout()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		layout.Sweep(i)
+	}
+}
+
+// The same, with cells kept in a map.
+func BenchmarkPassMap64(b *testing.B) {
+	cells := newMapLayout(benchmarkLayout())
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		cells.pass()
+	}
+}
+
+// A full evolve pass over every cell, through the worker pool.
+func BenchmarkEvolvePool64(b *testing.B) {
+	layout := benchmarkLayout()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		layout.MarkAllDirty()
+		layout.Evolve()
+	}
+}
+
+// A full evolve pass with a goroutine per cell, as evolve did before it had a worker pool.
+func BenchmarkEvolveGoroutinePerCell64(b *testing.B) {
+	layout := benchmarkLayout()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var changed int64
+		var wait sync.WaitGroup
+		wait.Add(len(layout.cells))
+		for j := range layout.cells {
+			go func(j int) {
+				defer wait.Done()
+				if layout.evolveCell(j) {
+					atomic.AddInt64(&changed, 1)
+				}
+			}(j)
+		}
+		wait.Wait()
+	}
+}
+
+// Evolving a converged layout, which only has to find out that no cell is dirty.
+func BenchmarkEvolveSettled64(b *testing.B) {
+	layout := benchmarkLayout()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		layout.Evolve()
+	}
+}
+
+// Evolving a large converged layout after a change to one cell, which only has to visit the cells around it.
+func BenchmarkEvolveOneDirty1024(b *testing.B) {
+	layout := NewLayout(1024, 1024)
+	layout.Converge(100)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		layout.MarkDirty(Point{X: 512, Y: 512})
+		layout.Evolve()
+	}
+}
+
+// Converging from dark in double-buffered steps. passes/op is the number of generations it takes.
+func BenchmarkStepConverge64(b *testing.B) {
+	layout := randomCappedLayout(rand.New(rand.NewSource(64)), 64, 64)
+	passes := 0
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, steps := stepToFixedPoint(b, layout)
+		passes += steps
+	}
+	b.ReportMetric(float64(passes)/float64(b.N), "passes/op")
+}
+
+// Converging from dark in alternating sweeps, the same layout as BenchmarkStepConverge64.
+func BenchmarkSweepConverge64(b *testing.B) {
+	layout := randomCappedLayout(rand.New(rand.NewSource(64)), 64, 64)
+	passes := 0
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		swept := layout.Clone()
+		b.StartTimer()
+		passes += sweepToFixedPoint(b, swept)
+	}
+	b.ReportMetric(float64(passes)/float64(b.N), "passes/op")
+}
@@ -0,0 +1,134 @@
+package lighting
+
+// PropagateBFS computes the converged light field in one call, the way Minecraft does: sources are queued with
+// their emission, and every cell popped off the queue lights its non-blocking neighbors to one level less, queueing
+// those it brightened. Suppressed cells keep their level and shine with it. The result is the same field that
+// repeated evolve passes converge to.
+// Returns how many light levels changed, and ages cells like an evolve pass would.
+func (layout Layout) PropagateBFS() int {
+	layout.catchUpAges()
+	levels := make([]int32, len(layout.cells))
+	queue := make([]int, 0, len(layout.cells))
+	for i, cell := range layout.cells {
+		if cell.Suppressed {
+			levels[i] = cell.Level
+			queue = append(queue, i)
+		} else if cell.Source > 0 {
+			levels[i] = cell.Capped(cell.Source)
+			queue = append(queue, i)
+		}
+	}
+	layout.flood(levels, queue)
+
+	changed := 0
+	for i := range layout.cells {
+		cell := &layout.cells[i]
+		if cell.Level != levels[i] {
+			cell.Level = levels[i]
+			cell.Age = 0
+			changed++
+		} else if cell.Age < MaxAge {
+			cell.Age++
+		}
+	}
+
+	// Every cell is settled now.
+	if layout.dirty != nil {
+		for _, i := range layout.dirty.list {
+			layout.dirty.marked[i] = false
+		}
+		layout.dirty.list = layout.dirty.list[:0]
+	}
+	return changed
+}
+
+// RemoveSource clears the cell at p (a source, or a blocker) and fixes the light around it at once, instead of
+// letting it fade one level per evolve pass. Like Minecraft, it first runs a darkness flood from p, zeroing every
+// cell whose level could have come from p, and noting the lit cells at its rim; then it floods light back in from
+// those. Cells that another source lights as brightly stay lit.
+func (layout Layout) RemoveSource(p Point) {
+	cell, exists := layout.At(p)
+	if !exists {
+		return
+	}
+	layout.catchUpAges()
+	cell.Source = 0
+	layout.MarkDirty(p)
+	if cell.Suppressed {
+		// Its light stays, stale, until the cell is poked.
+		return
+	}
+
+	levels := make([]int32, len(layout.cells))
+	for i := range layout.cells {
+		levels[i] = layout.cells[i].Level
+	}
+
+	// The darkness flood queues each zeroed cell with the level it had.
+	type darkened struct {
+		i     int
+		level int32
+	}
+	start := int(p.Y*layout.Width + p.X)
+	dark := []darkened{{i: start, level: levels[start]}}
+	levels[start] = 0
+	relight := []int{}
+
+	for head := 0; head < len(dark); head++ {
+		for _, neighbor := range layout.point(dark[head].i).Neighbors() {
+			if !layout.Contains(neighbor) {
+				continue
+			}
+			j := int(neighbor.Y*layout.Width + neighbor.X)
+			level := levels[j]
+			if layout.cells[j].Source < 0 || level == 0 {
+				continue
+			}
+			if level >= dark[head].level || layout.cells[j].Suppressed {
+				// Lit by something else, at least as brightly, or stuck with its light
+				relight = append(relight, j)
+				continue
+			}
+			levels[j] = 0
+			dark = append(dark, darkened{i: j, level: level})
+			if source := layout.cells[j].Source; source > 0 {
+				// A dimmer source lost its surplus light, but keeps its own.
+				levels[j] = layout.cells[j].Capped(source)
+				relight = append(relight, j)
+			}
+		}
+	}
+
+	layout.flood(levels, relight)
+	for i, level := range levels {
+		if cell := &layout.cells[i]; cell.Level != level {
+			cell.Level = level
+			cell.Age = 0
+			layout.MarkDirty(layout.point(i))
+		}
+	}
+}
+
+// flood spreads light from the queued cells (indices into the layout's cells) over levels, until no cell can be
+// brightened any more. Blockers stay dark, and capped cells take no more than their cap.
+func (layout Layout) flood(levels []int32, queue []int) {
+	for head := 0; head < len(queue); head++ {
+		i := queue[head]
+		level := levels[i] - 1
+		if level <= 0 {
+			continue
+		}
+		for _, neighbor := range layout.point(i).Neighbors() {
+			if !layout.Contains(neighbor) {
+				continue
+			}
+			j := int(neighbor.Y*layout.Width + neighbor.X)
+			next := layout.cells[j].Capped(level)
+			if layout.cells[j].Source < 0 || layout.cells[j].Suppressed || levels[j] >= next {
+				continue
+			}
+			levels[j] = next
+			queue = append(queue, j)
+		}
+	}
+}
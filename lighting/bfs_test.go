@@ -0,0 +1,139 @@
+package lighting
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// randomLayout returns a width x height layout with about a tenth of its cells sources and a quarter blockers.
+func randomLayout(rng *rand.Rand, width int32, height int32) Layout {
+	layout := NewLayout(width, height)
+	for y := int32(0); y < height; y++ {
+		for x := int32(0); x < width; x++ {
+			switch r := rng.Intn(20); {
+			case r < 2:
+				layout.SetSource(Point{X: x, Y: y}, 1+rng.Int31n(15))
+			case r < 7:
+				layout.SetSource(Point{X: x, Y: y}, -1)
+			}
+		}
+	}
+	return layout
+}
+
+// levels returns the light level of every cell, indexed y*Width+x.
+func levels(layout Layout) []int32 {
+	result := make([]int32, len(layout.Cells()))
+	for i, cell := range layout.Cells() {
+		result[i] = cell.Level
+	}
+	return result
+}
+
+// assertSameLevels fails the test at the first cell whose level differs between got and want.
+func assertSameLevels(t *testing.T, got Layout, want Layout) {
+	t.Helper()
+	gotLevels, wantLevels := levels(got), levels(want)
+	for i := range wantLevels {
+		if gotLevels[i] != wantLevels[i] {
+			t.Fatalf("level at %v is %d, want %d", got.point(i), gotLevels[i], wantLevels[i])
+		}
+	}
+}
+
+func TestPropagateBFSMatchesConverge(t *testing.T) {
+	rng := rand.New(rand.NewSource(257))
+	for round := 0; round < 200; round++ {
+		stepped := randomLayout(rng, 1+rng.Int31n(40), 1+rng.Int31n(40))
+		flooded := stepped.Clone()
+
+		if _, converged := stepped.Converge(1000); !converged {
+			t.Fatalf("round %d: evolve did not converge", round)
+		}
+		flooded.PropagateBFS()
+		assertSameLevels(t, flooded, stepped)
+
+		// A second flood finds nothing to change.
+		if changed := flooded.PropagateBFS(); changed != 0 {
+			t.Fatalf("round %d: flooding a converged layout changed %d levels", round, changed)
+		}
+	}
+}
+
+// Flooding from a field left over from other sources gives the same levels as flooding from dark.
+func TestPropagateBFSForgetsOldLight(t *testing.T) {
+	rng := rand.New(rand.NewSource(2570))
+	for round := 0; round < 50; round++ {
+		layout := randomLayout(rng, 24, 24)
+		layout.PropagateBFS()
+		for i := 0; i < 20; i++ {
+			layout.SetSource(Point{X: rng.Int31n(24), Y: rng.Int31n(24)}, rng.Int31n(17)-1)
+		}
+		fresh := layout.Clone()
+		for i := range fresh.cells {
+			fresh.cells[i].Level = 0
+		}
+
+		layout.PropagateBFS()
+		fresh.PropagateBFS()
+		assertSameLevels(t, layout, fresh)
+	}
+}
+
+// Two torches light overlapping areas. Removing one must leave the light of the other where it is at least as
+// bright, and everything else as if the removed torch had never been there.
+func TestRemoveSourceOverlappingTorches(t *testing.T) {
+	layout := NewLayout(25, 9)
+	removed, kept := Point{X: 8, Y: 4}, Point{X: 14, Y: 4}
+	layout.SetSource(removed, 14)
+	layout.SetSource(kept, 14)
+	layout.PropagateBFS()
+
+	want := NewLayout(25, 9)
+	want.SetSource(kept, 14)
+	want.PropagateBFS()
+
+	layout.RemoveSource(removed)
+	assertSameLevels(t, layout, want)
+
+	// Halfway between the torches, the kept one still lights the cell as it did.
+	if level := layout.Get(Point{X: 11, Y: 4}).Level; level != 11 {
+		t.Errorf("level between the torches is %d, want 11", level)
+	}
+	if level := layout.Get(removed).Level; level != 8 {
+		t.Errorf("level where the removed torch was is %d, want 8", level)
+	}
+}
+
+// A dimmer torch inside the area of a removed brighter one keeps its own light.
+func TestRemoveSourceKeepsDimmerSource(t *testing.T) {
+	layout := NewLayout(15, 15)
+	layout.SetSource(Point{X: 7, Y: 7}, 15)
+	layout.SetSource(Point{X: 9, Y: 7}, 6)
+	layout.SetSource(Point{X: 7, Y: 10}, -1)
+	layout.PropagateBFS()
+
+	want := NewLayout(15, 15)
+	want.SetSource(Point{X: 9, Y: 7}, 6)
+	want.SetSource(Point{X: 7, Y: 10}, -1)
+	want.PropagateBFS()
+
+	layout.RemoveSource(Point{X: 7, Y: 7})
+	assertSameLevels(t, layout, want)
+}
+
+func TestRemoveSourceMatchesPropagateBFS(t *testing.T) {
+	rng := rand.New(rand.NewSource(258))
+	for round := 0; round < 200; round++ {
+		layout := randomLayout(rng, 1+rng.Int31n(30), 1+rng.Int31n(30))
+		layout.PropagateBFS()
+		p := Point{X: rng.Int31n(layout.Width), Y: rng.Int31n(layout.Height)}
+
+		want := layout.Clone()
+		want.SetSource(p, 0)
+		want.PropagateBFS()
+
+		layout.RemoveSource(p)
+		assertSameLevels(t, layout, want)
+	}
+}
@@ -0,0 +1,20 @@
+package lighting
+
+// Capped clamps a light level to the cell's cap (see Cell.Cap).
+func (cell Cell) Capped(level int32) int32 {
+	if cell.Cap > 0 && level > cell.Cap {
+		return cell.Cap
+	}
+	return level
+}
+
+// SetCap changes the light cap of the cell at p (see Cell.Cap), and marks it dirty if that is a change, so that its
+// light and the light downstream of it follow. Points off the grid are ignored.
+func (layout Layout) SetCap(p Point, levelCap int32) {
+	cell, exists := layout.At(p)
+	if !exists || cell.Cap == levelCap {
+		return
+	}
+	cell.Cap = levelCap
+	layout.MarkDirty(p)
+}
@@ -0,0 +1,105 @@
+package lighting
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+// corridorLayout is a corridor one cell wide down row 1 of a 20x3 grid, walled in by blockers, with a 15 source at
+// its west end and its cells 3 to 6 capped at 8.
+func corridorLayout() Layout {
+	layout := NewLayout(20, 3)
+	for x := int32(0); x < 20; x++ {
+		layout.SetSource(Point{X: x, Y: 0}, -1)
+		layout.SetSource(Point{X: x, Y: 2}, -1)
+	}
+	layout.SetSource(Point{X: 0, Y: 1}, 15)
+	for x := int32(3); x <= 6; x++ {
+		layout.SetCap(Point{X: x, Y: 1}, 8)
+	}
+	return layout
+}
+
+// A 15 source shining through a corridor capped at 8 comes out at 8 at most, and dims from there on: the cap holds
+// for everything downstream. Every way of propagating light settles on the same field.
+func TestCapCorridor(t *testing.T) {
+	want := []int32{15, 14, 13, 8, 7, 6, 5, 4, 3, 2, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+
+	check := func(name string, layout Layout) {
+		t.Helper()
+		for x, level := range want {
+			if got := layout.Get(Point{X: int32(x), Y: 1}).Level; got != level {
+				t.Errorf("%s: level at x=%d is %d, want %d", name, x, got, level)
+			}
+		}
+	}
+
+	evolved := corridorLayout()
+	if _, converged := evolved.Converge(100); !converged {
+		t.Fatal("evolve did not converge")
+	}
+	check("Converge", evolved)
+
+	flooded := corridorLayout()
+	flooded.PropagateBFS()
+	check("PropagateBFS", flooded)
+
+	stepped := corridorLayout()
+	for changed, steps := 1, 0; changed > 0; steps++ {
+		if steps > 100 {
+			t.Fatal("Step did not converge")
+		}
+		stepped, changed = stepped.Step()
+	}
+	check("Step", stepped)
+
+	swept := corridorLayout()
+	for pass := 0; swept.Sweep(pass) > 0; pass++ {
+		if pass > 100 {
+			t.Fatal("Sweep did not converge")
+		}
+	}
+	check("Sweep", swept)
+
+	relit := corridorLayout()
+	if !relit.RelightAll(time.Now().Add(10 * time.Second)) {
+		t.Fatal("RelightAll ran out of time")
+	}
+	check("RelightAll", relit)
+
+	// Light let in past the cap is held to it as well.
+	flooded.RemoveSource(Point{X: 5, Y: 0})
+	if level := flooded.Get(Point{X: 5, Y: 0}).Level; level != 5 {
+		t.Errorf("level of a cell opened onto the capped corridor is %d, want 5", level)
+	}
+}
+
+func TestCapsAgreeAcrossPropagation(t *testing.T) {
+	rng := rand.New(rand.NewSource(276))
+	for round := 0; round < 200; round++ {
+		layout := randomLayout(rng, 1+rng.Int31n(30), 1+rng.Int31n(30))
+		for i := 0; i < len(layout.cells)/5; i++ {
+			layout.SetCap(Point{X: rng.Int31n(layout.Width), Y: rng.Int31n(layout.Height)}, 1+rng.Int31n(15))
+		}
+		flooded := layout.Clone()
+		flooded.PropagateBFS()
+
+		if _, converged := layout.Converge(100); !converged {
+			t.Fatalf("round %d: evolve did not converge", round)
+		}
+		assertSameLevels(t, layout, flooded)
+		for i, cell := range layout.cells {
+			if cell.Cap > 0 && cell.Level > cell.Cap {
+				t.Fatalf("round %d: level %d at %v is over its cap %d", round, cell.Level, layout.point(i), cell.Cap)
+			}
+		}
+
+		p := Point{X: rng.Int31n(layout.Width), Y: rng.Int31n(layout.Height)}
+		removed := flooded.Clone()
+		removed.SetSource(p, 0)
+		removed.PropagateBFS()
+		flooded.RemoveSource(p)
+		assertSameLevels(t, flooded, removed)
+	}
+}
@@ -0,0 +1,15 @@
+package lighting
+
+// Converge runs evolve passes until one changes nothing, or maxIter passes have changed something. It returns how
+// many passes changed levels, and whether the layout settled.
+// Light from a single source settles within as many passes as its emission. No setup oscillates, since levels are
+// bounded and stale light fades one level per pass, so hitting the cap only means it was too low.
+func (layout Layout) Converge(maxIter int) (iterations int, converged bool) {
+	for iterations < maxIter {
+		if layout.Evolve() == 0 {
+			return iterations, true
+		}
+		iterations++
+	}
+	return iterations, layout.Settled()
+}
@@ -0,0 +1,53 @@
+package lighting
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestConvergeSingleSource(t *testing.T) {
+	for round := 0; round < 20; round++ {
+		layout := NewLayout(16, 16)
+		layout.SetSource(Point{X: 8, Y: 8}, 15)
+
+		iterations, converged := layout.Converge(100)
+		if !converged || iterations > 15 {
+			t.Fatalf("Converge(100) = %d, %v; want at most 15 iterations, converged", iterations, converged)
+		}
+		if level := layout.Get(Point{X: 0, Y: 0}).Level; level != 0 {
+			t.Errorf("corner level is %d, want 0", level)
+		}
+		if level := layout.Get(Point{X: 15, Y: 15}).Level; level != 1 {
+			t.Errorf("corner level is %d, want 1", level)
+		}
+	}
+}
+
+// No layout oscillates, so a cap too low to settle is the only way not to converge, and then Converge says so
+// instead of looping on.
+func TestConvergeHitsCap(t *testing.T) {
+	layout := NewLayout(16, 16)
+	layout.SetSource(Point{X: 8, Y: 8}, 15)
+	if iterations, converged := layout.Converge(3); iterations != 3 || converged {
+		t.Errorf("Converge(3) = %d, %v; want 3, false", iterations, converged)
+	}
+	if _, converged := layout.Converge(100); !converged {
+		t.Errorf("did not converge after the cap was raised")
+	}
+	if iterations, converged := layout.Converge(0); iterations != 0 || !converged {
+		t.Errorf("Converge(0) on a settled layout = %d, %v; want 0, true", iterations, converged)
+	}
+}
+
+// Starting from dark, light only spreads, one cell per pass at least, and no further than 14 cells: any layout
+// settles within 15 passes.
+func TestConvergeRandomLayouts(t *testing.T) {
+	rng := rand.New(rand.NewSource(260))
+	for round := 0; round < 100; round++ {
+		layout := randomLayout(rng, 1+rng.Int31n(32), 1+rng.Int31n(32))
+		if iterations, converged := layout.Converge(100); !converged || iterations > 15 {
+			t.Fatalf("round %d: Converge(100) = %d, %v; want at most 15 iterations, converged",
+				round, iterations, converged)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package lighting
+
+// dirtySet tracks the cells evolve has to visit: those whose source changed, and the neighbors of those whose level
+// changed in the previous pass. Every other cell is settled, so a converged layout evolves without touching any.
+type dirtySet struct {
+	// Indexed like the layout's cells; set for the cells in list
+	marked []bool
+	list   []int
+
+	// The list of the previous pass, kept to be reused
+	spare []int
+
+	// Evolve passes run since ages were last caught up (see catchUpAges), and per cell, how many of them had run
+	// when its Age was last brought up to date. A cell is passes-synced[i] passes older than its Age says: the
+	// passes that skipped it. This way a pass only touches the ages of the cells it visits.
+	passes int64
+	synced []int64
+}
+
+// newDirtySet returns a set with all n cells marked, for a layout nothing is known about yet.
+func newDirtySet(n int) *dirtySet {
+	dirty := &dirtySet{marked: make([]bool, n), list: make([]int, n), synced: make([]int64, n)}
+	for i := range dirty.list {
+		dirty.marked[i] = true
+		dirty.list[i] = i
+	}
+	return dirty
+}
+
+// mark adds the cell at index i, if it is not in the set yet.
+func (dirty *dirtySet) mark(i int) {
+	if !dirty.marked[i] {
+		dirty.marked[i] = true
+		dirty.list = append(dirty.list, i)
+	}
+}
+
+// MarkDirty makes the next evolve pass visit the cell at p and its neighbors. Call it after changing a cell's
+// source or level by hand; SetSource does it already.
+func (layout Layout) MarkDirty(p Point) {
+	if layout.dirty == nil || !layout.Contains(p) {
+		return
+	}
+	layout.dirty.mark(int(p.Y*layout.Width + p.X))
+	layout.markNeighbors(p)
+}
+
+// MarkAllDirty makes the next evolve pass visit every cell, for after changes too broad to track.
+func (layout Layout) MarkAllDirty() {
+	if layout.dirty == nil {
+		return
+	}
+	for i := range layout.cells {
+		layout.dirty.mark(i)
+	}
+}
+
+// Settled tells if no cell is dirty, so that evolving would change nothing.
+func (layout Layout) Settled() bool {
+	return layout.dirty == nil || len(layout.dirty.list) == 0
+}
+
+// markNeighbors marks the neighbors of p that are on the grid.
+func (layout Layout) markNeighbors(p Point) {
+	for _, neighbor := range p.Neighbors() {
+		if layout.Contains(neighbor) {
+			layout.dirty.mark(int(neighbor.Y*layout.Width + neighbor.X))
+		}
+	}
+}
+
+// SetSource changes the emission of the cell at p, and marks it dirty if that is a change. Points off the grid are
+// ignored.
+func (layout Layout) SetSource(p Point, source int32) {
+	cell, exists := layout.At(p)
+	if !exists || cell.Source == source {
+		return
+	}
+	cell.Source = source
+	layout.MarkDirty(p)
+}
+
+// pendingAge is how many passes the cell at index i is older than its Age field says (see dirtySet.synced).
+func (layout Layout) pendingAge(i int) int32 {
+	if layout.dirty == nil {
+		return 0
+	}
+	if pending := layout.dirty.passes - layout.dirty.synced[i]; pending < int64(MaxAge) {
+		return int32(pending)
+	}
+	return MaxAge
+}
+
+// age is the age of the cell at index i, with the passes that skipped it counted in.
+func (layout Layout) age(i int) int32 {
+	return addAge(layout.cells[i].Age, layout.pendingAge(i))
+}
+
+// catchUpAges adds the passes that skipped each cell to its age. Anything that sets or increments the ages of
+// cells evolve did not visit has to call it first.
+func (layout Layout) catchUpAges() {
+	if layout.dirty == nil || layout.dirty.passes == 0 {
+		return
+	}
+	for i := range layout.cells {
+		layout.cells[i].Age = layout.age(i)
+	}
+	layout.syncAges()
+}
+
+// syncAges takes the Age of every cell as up to date, for after they were all set from elsewhere.
+func (layout Layout) syncAges() {
+	if layout.dirty == nil {
+		return
+	}
+	for i := range layout.dirty.synced {
+		layout.dirty.synced[i] = 0
+	}
+	layout.dirty.passes = 0
+}
+
+// addAge adds n passes to an age, saturating at MaxAge.
+func addAge(age int32, n int32) int32 {
+	if age > MaxAge-n {
+		return MaxAge
+	}
+	return age + n
+}
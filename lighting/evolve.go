@@ -0,0 +1,156 @@
+package lighting
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// Evolve the cellular automata
+// Return >0 if it needs to continue.
+func (layout *Layout) Evolve() int {
+	// Only dirty cells can change (see dirtySet). If there are none, we have reached convergence, and the pass costs
+	// nothing. The ages of the cells a pass skips are caught up later (see dirtySet.synced).
+	dirty := layout.dirty
+	if dirty == nil {
+		return 0
+	}
+	if len(dirty.list) == 0 {
+		dirty.passes++
+		return 0
+	}
+
+	// Visited cells have their age brought up to date here, and count this pass in evolveCell.
+	work := dirty.list
+	dirty.list = dirty.spare[:0]
+	for _, i := range work {
+		dirty.marked[i] = false
+		layout.cells[i].Age = layout.age(i)
+		dirty.synced[i] = dirty.passes + 1
+	}
+	dirty.passes++
+
+	// Data- and flow-independently execute for each dirty block, in chunks handed to the evolve workers (see
+	// evolvePool). It doesn't matter if this execution is concurrent or not.
+	// A worker may see a neighbor's level from before or after that neighbor's update. Convergence will be reached
+	// anyway: a cell that read a stale level is revisited next pass, since the neighbor that changed marks it dirty.
+	// Levels are only ever touched atomically, so this is free of data races.
+	jobs, workers := evolvePool()
+	chunk := (len(work) + workers - 1) / workers
+	changedCells := make([][]int, (len(work)+chunk-1)/chunk)
+
+	// Wait for every chunk to be processed.
+	joiner := new(sync.WaitGroup)
+	joiner.Add(len(changedCells))
+	for k := range changedCells {
+		from, to := k*chunk, (k+1)*chunk
+		if to > len(work) {
+			to = len(work)
+		}
+		jobs <- evolveJob{layout: layout, cells: work[from:to], changed: &changedCells[k], done: joiner}
+	}
+
+	joiner.Wait()
+
+	// If the number of blocks that has been altered (i.e. light level changes) is 0
+	// then we have reached convergence.
+	changed := 0
+	for _, cells := range changedCells {
+		changed += len(cells)
+		for _, i := range cells {
+			layout.markNeighbors(layout.point(i))
+		}
+	}
+	dirty.spare = work
+	return changed
+}
+
+// evolveCell updates the light level of the cell at index i, and tells if it changed.
+func (layout *Layout) evolveCell(i int) bool {
+	cell := &layout.cells[i]
+
+	// Suppressed cells are not updated, and stay settled as far as the dirty set goes, until poked.
+	if cell.Suppressed {
+		if cell.Age < MaxAge {
+			cell.Age++
+		}
+		return false
+	}
+
+	// If the emission is negative, then it is a light-blocking block, by our definition.
+	if cell.Source < 0 {
+		if cell.Level != 0 {
+			atomic.StoreInt32(&cell.Level, 0)
+			cell.Age = 0
+			return true
+		}
+		if cell.Age < MaxAge {
+			cell.Age++
+		}
+		return false
+	}
+
+	// Compare the old and new light levels for each pixel.
+	// This worker is the only writer of its cell, so its own level can be read directly.
+	oldLightLevel := cell.Level
+
+	// Determine my (ambient) light level based on my neighbors' (use "largest of n integers" function).
+	// Note that a cell's light level may increase, stay the same or decrease.
+	level := int32Max(layout.maxNeighborsLightLevel(layout.point(i))-1, 0)
+
+	// If it's light generating, then assume the largest of:
+	// - environmental light level and
+	// - self-generated light level
+	// as its own light level.
+	if cell.Source > 0 {
+		level = int32Max(cell.Source, level)
+	}
+	level = cell.Capped(level)
+
+	if level != oldLightLevel {
+		atomic.StoreInt32(&cell.Level, level)
+		cell.Age = 0
+		return true
+	}
+	if cell.Age < MaxAge {
+		cell.Age++
+	}
+	return false
+}
+
+// evolveJob is a chunk of cells (indices into the layout's cells) for an evolve worker to update.
+// The worker appends the cells whose level changed to *changed, then marks the job done.
+type evolveJob struct {
+	layout  *Layout
+	cells   []int
+	changed *[]int
+	done    *sync.WaitGroup
+}
+
+var (
+	evolveJobs      chan evolveJob
+	evolveWorkers   int
+	startEvolvePool sync.Once
+)
+
+// evolvePool returns the queue of the evolve workers and how many there are: one per GOMAXPROCS, started on the
+// first call and kept for the life of the program, so evolving every frame does not launch goroutines.
+func evolvePool() (chan<- evolveJob, int) {
+	startEvolvePool.Do(func() {
+		evolveWorkers = runtime.GOMAXPROCS(0)
+		evolveJobs = make(chan evolveJob, evolveWorkers)
+		for i := 0; i < evolveWorkers; i++ {
+			go func() {
+				for job := range evolveJobs {
+					for _, i := range job.cells {
+						if job.layout.evolveCell(i) {
+							*job.changed = append(*job.changed, i)
+						}
+					}
+					job.done.Done()
+				}
+			}()
+		}
+	})
+	return evolveJobs, evolveWorkers
+}
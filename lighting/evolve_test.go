@@ -0,0 +1,90 @@
+package lighting
+
+import (
+	"math/rand"
+	"sync"
+	"testing"
+)
+
+// Evolve's workers share the grid, so run this with -race: it must report nothing, and the count returned must be
+// exact.
+func TestEvolveChangedCount(t *testing.T) {
+	rng := rand.New(rand.NewSource(255))
+	layout := randomLayout(rng, 128, 128)
+	for pass := 0; pass < 100; pass++ {
+		before := levels(layout)
+		changed := layout.Evolve()
+
+		after := levels(layout)
+		want := 0
+		for i := range before {
+			if before[i] != after[i] {
+				want++
+			}
+		}
+		if changed != want {
+			t.Fatalf("pass %d: Evolve() = %d, but %d levels changed", pass, changed, want)
+		}
+		if changed == 0 {
+			break
+		}
+
+		// Keep some sources changing, so that light comes and goes.
+		if pass < 20 {
+			layout.SetSource(Point{X: rng.Int31n(128), Y: rng.Int31n(128)}, rng.Int31n(17)-1)
+		}
+	}
+
+	want := layout.Clone()
+	want.PropagateBFS()
+	assertSameLevels(t, layout, want)
+}
+
+// Layouts evolved from several goroutines at once share the worker pool, but not their cells.
+func TestEvolveLayoutsConcurrently(t *testing.T) {
+	rng := rand.New(rand.NewSource(222))
+	layouts := make([]Layout, 8)
+	for i := range layouts {
+		layouts[i] = randomLayout(rng, 96, 96)
+	}
+
+	var wait sync.WaitGroup
+	for i := range layouts {
+		wait.Add(1)
+		go func(layout Layout) {
+			defer wait.Done()
+			layout.Converge(100)
+		}(layouts[i])
+	}
+	wait.Wait()
+
+	for _, layout := range layouts {
+		want := layout.Clone()
+		want.PropagateBFS()
+		assertSameLevels(t, layout, want)
+	}
+}
+
+// Snapshots taken between passes hold a copy of the cells, which the next passes do not touch.
+func TestSnapshotBetweenPasses(t *testing.T) {
+	layout := NewLayout(64, 64)
+	layout.SetSource(Point{X: 32, Y: 32}, 15)
+	layout.Evolve()
+	snapshot := layout.Snapshot()
+	want := levels(layout)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			for j, cell := range snapshot.Cells() {
+				if cell.Level != want[j] {
+					t.Errorf("snapshot level at %v changed from %d to %d", layout.point(j), want[j], cell.Level)
+					return
+				}
+			}
+		}
+	}()
+	layout.Converge(100)
+	<-done
+}
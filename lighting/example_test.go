@@ -0,0 +1,86 @@
+package lighting_test
+
+import (
+	"fmt"
+	"mclighting000/lighting"
+)
+
+// row returns the light levels of row y of the layout.
+func row(layout lighting.Layout, y int32) []int32 {
+	levels := make([]int32, layout.Width)
+	for x := range levels {
+		levels[x] = layout.Get(lighting.Point{X: int32(x), Y: y}).Level
+	}
+	return levels
+}
+
+func ExampleNewLayout() {
+	layout := lighting.NewLayout(7, 1)
+	layout.SetSource(lighting.Point{X: 1, Y: 0}, 4)
+	layout.SetSource(lighting.Point{X: 4, Y: 0}, -1)
+	layout.Converge(100)
+
+	fmt.Println(row(layout, 0))
+	// Output: [3 4 3 2 0 0 0]
+}
+
+func ExampleLayout_Step() {
+	layout := lighting.NewLayout(5, 1)
+	layout.SetSource(lighting.Point{X: 0, Y: 0}, 4)
+
+	// Light moves one cell per generation.
+	for changed := 1; changed > 0; {
+		layout, changed = layout.Step()
+		fmt.Println(row(layout, 0))
+	}
+	// Output:
+	// [4 0 0 0 0]
+	// [4 3 0 0 0]
+	// [4 3 2 0 0]
+	// [4 3 2 1 0]
+	// [4 3 2 1 0]
+}
+
+func ExampleLayout_SetSource() {
+	layout := lighting.NewLayout(3, 3)
+	layout.SetSource(lighting.Point{X: 1, Y: 1}, 15)
+	layout.PropagateBFS()
+	fmt.Println(layout.Get(lighting.Point{X: 0, Y: 0}).Level)
+
+	// Turning the source down relights its surroundings on the next passes.
+	layout.SetSource(lighting.Point{X: 1, Y: 1}, 5)
+	layout.Converge(100)
+	fmt.Println(layout.Get(lighting.Point{X: 0, Y: 0}).Level)
+	// Output:
+	// 13
+	// 3
+}
+
+func ExampleLayout_PropagateBFS() {
+	layout := lighting.NewLayout(4, 4)
+	layout.SetSource(lighting.Point{X: 0, Y: 0}, 6)
+	for y := int32(0); y < 3; y++ {
+		layout.SetSource(lighting.Point{X: 1, Y: y}, -1)
+	}
+	layout.PropagateBFS()
+
+	for y := int32(0); y < 4; y++ {
+		fmt.Println(row(layout, y))
+	}
+	// Output:
+	// [6 0 0 0]
+	// [5 0 0 0]
+	// [4 0 0 0]
+	// [3 2 1 0]
+}
+
+func ExampleLayout_RemoveSource() {
+	layout := lighting.NewLayout(9, 1)
+	layout.SetSource(lighting.Point{X: 2, Y: 0}, 5)
+	layout.SetSource(lighting.Point{X: 6, Y: 0}, 5)
+	layout.PropagateBFS()
+
+	layout.RemoveSource(lighting.Point{X: 2, Y: 0})
+	fmt.Println(row(layout, 0))
+	// Output: [0 0 1 2 3 4 5 4 3]
+}
@@ -0,0 +1,189 @@
+// Package lighting simulates block light in Minecraft, without any rendering, so that it can be used on its own.
+//
+// Each block is a cell in a cellular system, with two separate properties: its emission (0-15 if it emits light,
+// 0 if it does not) and its light level. The light level is independent of the emission, as light from a brighter
+// block nearby can override it. Negative emissions mean light-blocking: in the Java edition of Minecraft, block
+// light is either completely masked out or completely passes through.
+package lighting
+
+import (
+	"fmt"
+	"math"
+	"sync/atomic"
+)
+
+// Point is the position of a cell: X across the grid, Y down.
+type Point struct {
+	X int32
+	Y int32
+}
+
+// Neighbors returns the four cells next to p, which may be off the grid.
+func (p Point) Neighbors() []Point {
+	points := []Point{
+		{
+			X: p.X - 1,
+			Y: p.Y,
+		},
+		{
+			X: p.X + 1,
+			Y: p.Y,
+		},
+		{
+			X: p.X,
+			Y: p.Y - 1,
+		},
+		{
+			X: p.X,
+			Y: p.Y + 1,
+		},
+	}
+
+	return points
+}
+
+// Cell keeps the emission status and light level.
+type Cell struct {
+	// [0,15] If light source, then >0.
+	Source int32
+
+	// [0,15] Light level
+	Level int32
+
+	// [0,15] Sky light level, which is kept apart from the block light in Level (see PropagateSky)
+	SkyLevel int32
+
+	// [0,15] Most light the cell can hold, whatever the sources: its level is clamped to it as light propagates, so
+	// light passing through comes out dimmed for every cell downstream too. 0, the default, means no cap; a cell
+	// meant to stay dark is a blocker.
+	Cap int32
+
+	// If set, light updates skip the cell, which keeps its level, stale or not, until poked (see Suppress).
+	Suppressed bool
+
+	// Number of evolve passes since Level last changed, saturating at MaxAge. In the layout's own cells, it leaves
+	// out the latest passes that skipped the cell; snapshots and AgeField count them in.
+	// This is bookkeeping for the age overlay, not part of the lighting state.
+	Age int32
+}
+
+// Layout is a grid of cells.
+// Copies of a Layout share its cells; use Clone for an independent one.
+type Layout struct {
+	Width  int32
+	Height int32
+
+	// Indexed y*Width+x
+	cells []Cell
+
+	// Cells the next evolve pass has to visit
+	dirty *dirtySet
+}
+
+// OutsideCell is what Get returns for points off the grid: a dark blocker, which neither gives nor takes light.
+var OutsideCell = Cell{Source: -1}
+
+// NewLayout returns a width x height layout of dark, empty cells. Cells are indexed with int32, so it panics if
+// there would be more than math.MaxInt32 of them.
+func NewLayout(width int32, height int32) Layout {
+	if width < 0 || height < 0 || int64(width)*int64(height) > math.MaxInt32 {
+		panic(fmt.Sprintf("lighting: a %dx%d layout cannot be indexed with int32", width, height))
+	}
+	return Layout{
+		Width:  width,
+		Height: height,
+		cells:  make([]Cell, width*height),
+		dirty:  newDirtySet(int(width * height)),
+	}
+}
+
+// Cells returns the cells of the layout, indexed y*Width+x. The slice is shared with the layout: mark cells dirty
+// after changing their source through it.
+func (layout Layout) Cells() []Cell {
+	return layout.cells
+}
+
+// Restore sets every cell to its state in a snapshot of the same size.
+func (layout Layout) Restore(snapshot *Snapshot) {
+	copy(layout.cells, snapshot.cells)
+	layout.syncAges()
+	layout.MarkAllDirty()
+}
+
+// CopyFrom sets every cell to its state in another layout of the same size, such as one StepInto wrote.
+func (layout Layout) CopyFrom(other Layout) {
+	for i := range layout.cells {
+		layout.cells[i] = other.cells[i]
+		layout.cells[i].Age = other.age(i)
+	}
+	layout.syncAges()
+	layout.MarkAllDirty()
+}
+
+// Size returns the number of cells along each axis of the layout.
+func (layout Layout) Size() (width, height int32) {
+	return layout.Width, layout.Height
+}
+
+// Contains tells if p is on the grid.
+func (layout Layout) Contains(p Point) bool {
+	return 0 <= p.X && p.X < layout.Width && 0 <= p.Y && p.Y < layout.Height
+}
+
+// Get returns the cell at p, or OutsideCell if p is off the grid.
+func (layout Layout) Get(p Point) Cell {
+	if !layout.Contains(p) {
+		return OutsideCell
+	}
+	return layout.cells[p.Y*layout.Width+p.X]
+}
+
+// Set replaces the cell at p and marks it dirty. Points off the grid are ignored.
+func (layout Layout) Set(p Point, cell Cell) {
+	if layout.Contains(p) {
+		layout.cells[p.Y*layout.Width+p.X] = cell
+		layout.MarkDirty(p)
+	}
+}
+
+// At returns the cell at p for editing in place, or false if p is off the grid.
+// Mark the cell dirty after changing its source, or use SetSource.
+func (layout Layout) At(p Point) (*Cell, bool) {
+	if !layout.Contains(p) {
+		return nil, false
+	}
+	return &layout.cells[p.Y*layout.Width+p.X], true
+}
+
+// point returns the position of the cell at index i of the backing store.
+func (layout Layout) point(i int) Point {
+	return Point{X: int32(i) % layout.Width, Y: int32(i) / layout.Width}
+}
+
+// Calculate the maximum of all neighbors' light levels.
+func (layout Layout) maxNeighborsLightLevel(p Point) int32 {
+	// Evolve() writes levels while its other threads read them here, so levels are loaded atomically.
+	// Evolve() is meant to be called many times until it converges (i.e. no more light level changes occur).
+	// Whichever generation a neighbor's level comes from, convergence will be reached.
+
+	max := int32(0)
+	for _, neighbor := range p.Neighbors() {
+		// Off the grid is dark.
+		if !layout.Contains(neighbor) {
+			continue
+		}
+		level := atomic.LoadInt32(&layout.cells[neighbor.Y*layout.Width+neighbor.X].Level)
+		if max < level {
+			max = level
+		}
+	}
+	return max
+}
+
+func int32Max(a int32, b int32) int32 {
+	if a < b {
+		return b
+	} else {
+		return a
+	}
+}
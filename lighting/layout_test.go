@@ -0,0 +1,143 @@
+package lighting
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLayoutAccessors(t *testing.T) {
+	layout := NewLayout(3, 2)
+	if width, height := layout.Size(); width != 3 || height != 2 {
+		t.Fatalf("Size() = %d, %d; want 3, 2", width, height)
+	}
+
+	layout.Set(Point{X: 2, Y: 1}, Cell{Source: 7})
+	if cell := layout.Get(Point{X: 2, Y: 1}); cell.Source != 7 {
+		t.Errorf("Get after Set = %+v, want source 7", cell)
+	}
+	if cell := layout.Cells()[1*3+2]; cell.Source != 7 {
+		t.Errorf("Cells()[5] = %+v, want source 7", cell)
+	}
+	cell, exists := layout.At(Point{X: 0, Y: 1})
+	if !exists {
+		t.Fatal("At(0, 1) is off the grid")
+	}
+	cell.Source = 3
+	if source := layout.Get(Point{X: 0, Y: 1}).Source; source != 3 {
+		t.Errorf("source edited through At is %d, want 3", source)
+	}
+
+	for _, outside := range []Point{{X: -1, Y: 0}, {X: 3, Y: 0}, {X: 0, Y: -1}, {X: 0, Y: 2}} {
+		if layout.Contains(outside) {
+			t.Errorf("Contains(%v) is true", outside)
+		}
+		if cell := layout.Get(outside); cell != OutsideCell {
+			t.Errorf("Get(%v) = %+v, want OutsideCell", outside, cell)
+		}
+		if _, exists := layout.At(outside); exists {
+			t.Errorf("At(%v) exists", outside)
+		}
+		// Ignored
+		layout.Set(outside, Cell{Source: 15})
+		layout.SetSource(outside, 15)
+	}
+}
+
+func TestNeighbors(t *testing.T) {
+	got := Point{X: 4, Y: -2}.Neighbors()
+	want := []Point{{X: 3, Y: -2}, {X: 5, Y: -2}, {X: 4, Y: -3}, {X: 4, Y: -1}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Neighbors() = %v, want %v", got, want)
+	}
+}
+
+func TestNewLayoutTooLarge(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("NewLayout(65536, 65536) did not panic")
+		}
+	}()
+	NewLayout(65536, 65536)
+}
+
+func TestClone(t *testing.T) {
+	layout := NewLayout(5, 5)
+	layout.SetSource(Point{X: 2, Y: 2}, 9)
+	layout.Converge(100)
+
+	clone := layout.Clone()
+	clone.SetSource(Point{X: 2, Y: 2}, 0)
+	clone.Converge(100)
+	if level := layout.Get(Point{X: 2, Y: 2}).Level; level != 9 {
+		t.Errorf("relighting a clone changed the original: level %d, want 9", level)
+	}
+	if level := clone.Get(Point{X: 2, Y: 2}).Level; level != 0 {
+		t.Errorf("clone level is %d, want 0", level)
+	}
+}
+
+// Step reads one generation and writes the next, so its result does not depend on anything but the layout, and
+// the layout stepped from keeps its levels.
+func TestStepIsDeterministic(t *testing.T) {
+	layout := NewLayout(9, 9)
+	layout.SetSource(Point{X: 4, Y: 4}, 6)
+	layout.SetSource(Point{X: 4, Y: 5}, -1)
+
+	first, changed := layout.Step()
+	second, _ := layout.Step()
+	if !reflect.DeepEqual(levels(first), levels(second)) {
+		t.Error("stepping the same layout twice gave different levels")
+	}
+	if changed != 1 {
+		t.Errorf("first step changed %d levels, want 1", changed)
+	}
+	if level := layout.Get(Point{X: 4, Y: 4}).Level; level != 0 {
+		t.Errorf("the stepped layout's level changed to %d", level)
+	}
+
+	for changed > 0 {
+		first, changed = first.Step()
+	}
+	want := layout.Clone()
+	want.PropagateBFS()
+	assertSameLevels(t, first, want)
+}
+
+// Stepping reads the ages evolve left behind without settling them in the layout stepped from.
+func TestStepLeavesAges(t *testing.T) {
+	layout := NewLayout(8, 8)
+	layout.SetSource(Point{X: 1, Y: 1}, 5)
+	layout.Converge(100)
+	for i := 0; i < 5; i++ {
+		layout.Evolve()
+	}
+	cells := append([]Cell(nil), layout.Cells()...)
+	snapshot := layout.Snapshot()
+
+	next, _ := layout.Step()
+	for i, cell := range layout.Cells() {
+		if cell != cells[i] {
+			t.Fatalf("Step changed the cell at %v from %+v to %+v", layout.point(i), cells[i], cell)
+		}
+		if want := snapshot.Cells()[i].Age + 1; next.Cells()[i].Age != want {
+			t.Fatalf("stepped age at %v is %d, want %d", layout.point(i), next.Cells()[i].Age, want)
+		}
+	}
+
+	// Copied back, the stepped cells keep their ages.
+	layout.CopyFrom(next)
+	for i, age := range flatten(layout.AgeField()) {
+		if want := next.Cells()[i].Age; age != want {
+			t.Fatalf("age copied to %v is %d, want %d", layout.point(i), age, want)
+		}
+	}
+}
+
+// flatten flattens a field indexed [y][x] into a slice indexed y*width+x.
+func flatten(field [][]int32) []int32 {
+	result := []int32{}
+	for _, row := range field {
+		result = append(result, row...)
+	}
+	return result
+}
@@ -0,0 +1,76 @@
+package lighting
+
+import (
+	"time"
+)
+
+// Clone returns a deep copy of the layout.
+func (layout Layout) Clone() Layout {
+	clone := layout
+	clone.cells = append([]Cell(nil), layout.cells...)
+	for i := range clone.cells {
+		clone.cells[i].Age = layout.age(i)
+	}
+	clone.dirty = newDirtySet(len(clone.cells))
+	return clone
+}
+
+// RelightAround relaxes the light levels of the cells within the given Manhattan radius of center, one cell at a
+// time, until nothing changes. Cells outside the region are left alone and act as its boundary.
+// It returns false if the deadline passed before the region settled.
+func (layout Layout) RelightAround(center Point, radius int32, deadline time.Time) bool {
+	region := []Point{}
+	for dy := -radius; dy <= radius; dy++ {
+		for dx := -radius + int32Abs(dy); dx <= radius-int32Abs(dy); dx++ {
+			point := Point{X: center.X + dx, Y: center.Y + dy}
+			if layout.Contains(point) {
+				region = append(region, point)
+			}
+		}
+	}
+
+	for {
+		changed := false
+		for _, point := range region {
+			cell, _ := layout.At(point)
+			if cell.Suppressed {
+				continue
+			}
+
+			level := int32(0)
+			if cell.Source >= 0 {
+				level = int32Max(layout.maxNeighborsLightLevel(point)-1, 0)
+				level = cell.Capped(int32Max(level, cell.Source))
+			}
+			if level != cell.Level {
+				cell.Level = level
+				changed = true
+			}
+		}
+		if !changed {
+			return true
+		}
+		if time.Now().After(deadline) {
+			return false
+		}
+	}
+}
+
+// RelightAll recomputes every light level from the sources, starting from dark, so that light from removed or
+// dimmed sources goes away too. It returns false if the deadline passed first.
+func (layout Layout) RelightAll(deadline time.Time) bool {
+	for i := range layout.cells {
+		if !layout.cells[i].Suppressed {
+			layout.cells[i].Level = 0
+		}
+	}
+	width, height := layout.Size()
+	return layout.RelightAround(Point{X: width / 2, Y: height / 2}, width+height, deadline)
+}
+
+func int32Abs(a int32) int32 {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
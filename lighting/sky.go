@@ -0,0 +1,69 @@
+package lighting
+
+// MaxSkyLevel is the sky light the top row gets from above the grid.
+const MaxSkyLevel = int32(15)
+
+// PropagateSky computes the sky light of every cell (see Cell.SkyLevel) in one call. The top row is open to the
+// sky: its cells get MaxSkyLevel from above. Sky light goes down through non-blocking cells without dimming, and
+// sideways or up one level dimmer per cell, like block light. Blockers stop it, and caps clamp it (see Cell.Cap).
+// Suppressed cells keep their sky level and shine with it, as PropagateBFS has them do with block light.
+// Returns how many sky levels changed. Ages are left alone: they count block light changes.
+func (layout Layout) PropagateSky() int {
+	levels := make([]int32, len(layout.cells))
+	// Cells by the level they were queued with. Light only dims along the way, so emptying the buckets from the
+	// brightest down settles each cell at its final level before it spreads further.
+	buckets := make([][]int, MaxSkyLevel+1)
+	for i, cell := range layout.cells {
+		if cell.Suppressed {
+			levels[i] = cell.SkyLevel
+			buckets[levels[i]] = append(buckets[levels[i]], i)
+		} else if i < int(layout.Width) && cell.Source >= 0 {
+			levels[i] = cell.Capped(MaxSkyLevel)
+			buckets[levels[i]] = append(buckets[levels[i]], i)
+		}
+	}
+
+	for level := MaxSkyLevel; level > 0; level-- {
+		// The bucket grows while it is emptied: light going down stays at the same level.
+		for head := 0; head < len(buckets[level]); head++ {
+			i := buckets[level][head]
+			if levels[i] != level {
+				// Queued again since, brighter: that entry spreads its light instead.
+				continue
+			}
+			p := layout.point(i)
+			for _, neighbor := range p.Neighbors() {
+				if !layout.Contains(neighbor) {
+					continue
+				}
+				j := int(neighbor.Y*layout.Width + neighbor.X)
+				if layout.cells[j].Source < 0 || layout.cells[j].Suppressed {
+					continue
+				}
+				next := level - 1
+				if neighbor.Y == p.Y+1 {
+					next = level
+				}
+				next = layout.cells[j].Capped(next)
+				if next > levels[j] {
+					levels[j] = next
+					buckets[next] = append(buckets[next], j)
+				}
+			}
+		}
+	}
+
+	changed := 0
+	for i := range layout.cells {
+		if cell := &layout.cells[i]; cell.SkyLevel != levels[i] {
+			cell.SkyLevel = levels[i]
+			changed++
+		}
+	}
+	return changed
+}
+
+// CombinedLevel is the light level Minecraft renders a cell with: the brighter of its block and sky light.
+func (cell Cell) CombinedLevel() int32 {
+	return int32Max(cell.Level, cell.SkyLevel)
+}
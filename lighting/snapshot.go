@@ -0,0 +1,48 @@
+package lighting
+
+// Snapshot is an immutable copy of a layout's cells.
+// Readers such as the renderer use it instead of the live cells, so that they never race with evolve.
+type Snapshot struct {
+	width  int32
+	height int32
+
+	// Indexed y*width+x
+	cells []Cell
+}
+
+// NewSnapshot makes a snapshot of width x height cells, indexed y*width+x. It keeps cells, which must not be changed
+// afterwards.
+func NewSnapshot(width int32, height int32, cells []Cell) *Snapshot {
+	return &Snapshot{width: width, height: height, cells: cells}
+}
+
+// Snapshot copies the current state of every cell, with ages brought up to date.
+func (layout Layout) Snapshot() *Snapshot {
+	snapshot := &Snapshot{
+		width:  layout.Width,
+		height: layout.Height,
+		cells:  append([]Cell(nil), layout.cells...),
+	}
+	for i := range snapshot.cells {
+		snapshot.cells[i].Age = layout.age(i)
+	}
+	return snapshot
+}
+
+// Cells returns a copy of the cells, indexed y*width+x.
+func (snapshot *Snapshot) Cells() []Cell {
+	return append([]Cell(nil), snapshot.cells...)
+}
+
+// Size returns the number of cells along each axis of the grid.
+func (snapshot *Snapshot) Size() (width, height int32) {
+	return snapshot.width, snapshot.height
+}
+
+// At returns the cell at p, or false if p is outside the grid.
+func (snapshot *Snapshot) At(p Point) (Cell, bool) {
+	if p.X < 0 || p.X >= snapshot.width || p.Y < 0 || p.Y >= snapshot.height {
+		return Cell{}, false
+	}
+	return snapshot.cells[p.Y*snapshot.width+p.X], true
+}
@@ -0,0 +1,81 @@
+package lighting
+
+// nextLevel is the light level the cell at p gets in the next generation, computed from the current one only.
+func (layout Layout) nextLevel(p Point, cell Cell) int32 {
+	if cell.Suppressed {
+		return cell.Level
+	}
+	if cell.Source < 0 {
+		return 0
+	}
+	return cell.Capped(int32Max(int32Max(layout.maxNeighborsLightLevel(p)-1, 0), cell.Source))
+}
+
+// StepInto writes the next generation of the layout into next, which must be the same size, and returns how many
+// levels changed. The layout is only read, so the result does not depend on the order cells are visited in.
+func (layout Layout) StepInto(next Layout) int {
+	changed := 0
+	for i, cell := range layout.cells {
+		cell.Age = layout.age(i)
+		level := layout.nextLevel(layout.point(i), cell)
+		if level != cell.Level {
+			changed++
+			cell.Age = 0
+		} else if cell.Age < MaxAge {
+			cell.Age++
+		}
+		cell.Level = level
+		next.cells[i] = cell
+	}
+	return changed
+}
+
+// Step returns the next generation of the layout and how many light levels changed, leaving the layout untouched.
+// Unlike evolve, every cell reads its neighbors from the same generation, so the same layout always steps to
+// the same result.
+func (layout Layout) Step() (Layout, int) {
+	next := NewLayout(layout.Width, layout.Height)
+	changed := layout.StepInto(next)
+	return next, changed
+}
+
+// Sweep updates the layout in place, visiting cells in an order that depends on the pass: rows forward, rows
+// backward, columns forward, columns backward, then over again. A cell sees the levels its neighbors got earlier in
+// the same pass, so light crosses open ground in a handful of passes instead of one cell per pass. The order is
+// fixed, so runs are as reproducible as with Step, and the levels converge to the same field.
+// Returns how many levels changed.
+func (layout Layout) Sweep(pass int) int {
+	layout.catchUpAges()
+	width, height := layout.Size()
+	outer, inner := height, width
+	columns := pass%4 >= 2
+	if columns {
+		outer, inner = width, height
+	}
+	backward := pass%2 == 1
+
+	changed := 0
+	for a := int32(0); a < outer; a++ {
+		for b := int32(0); b < inner; b++ {
+			major, minor := a, b
+			if backward {
+				major, minor = outer-1-a, inner-1-b
+			}
+			p := Point{X: minor, Y: major}
+			if columns {
+				p = Point{X: major, Y: minor}
+			}
+
+			cell := &layout.cells[p.Y*width+p.X]
+			if level := layout.nextLevel(p, *cell); level != cell.Level {
+				cell.Level = level
+				cell.Age = 0
+				changed++
+				layout.MarkDirty(p)
+			} else if cell.Age < MaxAge {
+				cell.Age++
+			}
+		}
+	}
+	return changed
+}
@@ -0,0 +1,90 @@
+package lighting
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// randomCappedLayout is randomLayout with about one cell in twenty capped at a random level.
+func randomCappedLayout(rng *rand.Rand, width int32, height int32) Layout {
+	layout := randomLayout(rng, width, height)
+	for i := width * height / 20; i > 0; i-- {
+		layout.SetCap(Point{X: rng.Int31n(width), Y: rng.Int31n(height)}, 1+rng.Int31n(15))
+	}
+	return layout
+}
+
+// sweepToFixedPoint sweeps the layout in alternating orders until a pass changes nothing, and returns the number of
+// passes that changed something.
+func sweepToFixedPoint(t testing.TB, layout Layout) int {
+	passes := 0
+	for layout.Sweep(passes) > 0 {
+		passes++
+		if passes > 1000 {
+			t.Fatal("sweeps did not settle in 1000 passes")
+		}
+	}
+	return passes
+}
+
+// stepToFixedPoint steps the layout until a generation changes nothing, and returns the settled generation and the
+// number of steps that changed something.
+func stepToFixedPoint(t testing.TB, layout Layout) (Layout, int) {
+	steps := 0
+	for {
+		next, changed := layout.Step()
+		if changed == 0 {
+			return layout, steps
+		}
+		layout = next
+		steps++
+		if steps > 1000 {
+			t.Fatal("steps did not settle in 1000 generations")
+		}
+	}
+}
+
+// Sweeping in place settles on the same field as stepping and evolving, caps and blockers included, whether it
+// starts from dark or from the light of sources that have since changed.
+func TestSweepMatchesConverge(t *testing.T) {
+	rng := rand.New(rand.NewSource(258))
+	for round := 0; round < 200; round++ {
+		layout := randomCappedLayout(rng, 1+rng.Int31n(40), 1+rng.Int31n(40))
+		if round%2 == 1 {
+			// Stale light to get rid of: converge, then move sources and blockers about.
+			layout.Converge(1000)
+			for i := 0; i < 10; i++ {
+				layout.SetSource(Point{X: rng.Int31n(layout.Width), Y: rng.Int31n(layout.Height)}, rng.Int31n(17)-1)
+			}
+		}
+		swept, evolved := layout.Clone(), layout.Clone()
+		stepped, _ := stepToFixedPoint(t, layout.Clone())
+
+		if _, converged := evolved.Converge(1000); !converged {
+			t.Fatalf("round %d: evolve did not converge", round)
+		}
+		sweepToFixedPoint(t, swept)
+		assertSameLevels(t, swept, evolved)
+		assertSameLevels(t, stepped, evolved)
+	}
+}
+
+// Sweeps carry light across open ground in a few passes; steps move it one cell per generation.
+func TestSweepFewerPasses(t *testing.T) {
+	layout := NewLayout(64, 64)
+	layout.SetSource(Point{X: 10, Y: 50}, 15)
+	layout.SetSource(Point{X: 40, Y: 20}, 12)
+	for y := int32(10); y < 60; y++ {
+		layout.SetSource(Point{X: 25, Y: y}, -1)
+	}
+
+	_, steps := stepToFixedPoint(t, layout.Clone())
+	sweeps := sweepToFixedPoint(t, layout.Clone())
+	// One generation to light the sources themselves, then one per cell the brightest one reaches.
+	if steps != 15 {
+		t.Errorf("stepping took %d generations, want 15", steps)
+	}
+	if sweeps > 4 {
+		t.Errorf("sweeping took %d passes, want at most 4 (stepping took %d)", sweeps, steps)
+	}
+}
@@ -0,0 +1,32 @@
+package lighting
+
+// Suppress makes light updates skip the cell at p, like a light update that never arrived in Minecraft: the cell
+// keeps its current level, stale or not, and passes it on to its neighbors, until Poke wakes it up.
+func (layout Layout) Suppress(p Point) {
+	if cell, exists := layout.At(p); exists {
+		cell.Suppressed = true
+	}
+}
+
+// Poke sends a light update to the cell at p, or if it is not suppressed, to its neighbors. The update wakes the
+// whole suppressed patch it reaches, so that the next evolve passes heal it.
+func (layout Layout) Poke(p Point) {
+	if !layout.Contains(p) {
+		return
+	}
+	queue := []Point{p}
+	if !layout.Get(p).Suppressed {
+		queue = p.Neighbors()
+	}
+	for len(queue) > 0 {
+		point := queue[0]
+		queue = queue[1:]
+		cell, exists := layout.At(point)
+		if !exists || !cell.Suppressed {
+			continue
+		}
+		cell.Suppressed = false
+		layout.MarkDirty(point)
+		queue = append(queue, point.Neighbors()...)
+	}
+}
@@ -12,90 +12,51 @@
 package main
 
 import (
+	"errors"
+	"flag"
+	"fmt"
 	"github.com/gen2brain/raylib-go/raylib"
 	"log"
+	"math"
+	"mclighting000/lighting"
+	"os"
+	"path/filepath"
+	"runtime"
 	"strconv"
-	"sync"
+	"strings"
+	"time"
 )
 
-type Point struct {
-	X int32
-	Y int32
-}
-
-func (p Point) neighbors() []Point {
-	points := []Point{
-		{
-			X: p.X - 1,
-			Y: p.Y,
-		},
-		{
-			X: p.X + 1,
-			Y: p.Y,
-		},
-		{
-			X: p.X,
-			Y: p.Y - 1,
-		},
-		{
-			X: p.X,
-			Y: p.Y + 1,
-		},
-	}
+// The engine's types, for the frontend
+type (
+	Point    = lighting.Point
+	Cell     = lighting.Cell
+	Snapshot = lighting.Snapshot
+)
 
-	return points
+// Layout is the engine's layout (16x16 unless -size or -width/-height say otherwise), with the frontend's tools,
+// overlays and exports as methods.
+type Layout struct {
+	lighting.Layout
 }
 
-// Cell keeps the emission status and light level.
-type Cell struct {
-	// [0,15] If light source, then >0.
-	Source int32
-
-	// [0,15] Light level
-	Level int32
+// NewLayout returns a width x height layout of dark, empty cells.
+func NewLayout(width int32, height int32) Layout {
+	return Layout{lighting.NewLayout(width, height)}
 }
 
-// Layout is a 16x16 grid of squares
-type Layout map[Point]*Cell
-
-const LayoutNSide = 16
+// PointLightGrabRadius is how close (in cells) the cursor has to be to a point light marker to grab it.
+const PointLightGrabRadius = float32(0.5)
 
-func makeEmptyLayout() Layout {
-	pattern := Layout{}
-	for x := int32(0); x < LayoutNSide; x++ {
-		for y := int32(0); y < LayoutNSide; y++ {
-			point := Point{X: x, Y: y}
-			_, exists := pattern[point]
-			if exists {
-				continue
-			}
-			pattern[point] = &Cell{Source: 0, Level: 0}
-		}
-	}
-	return pattern
-}
+// DefaultLayoutSide is the grid size when neither -size nor -width/-height are given.
+const DefaultLayoutSide = int32(16)
 
-// Calculate the maximum of all neighbors' light levels.
-func (layout Layout) maxNeighborsLightLevel(p Point) int32 {
-	// You CAN do a proper lock, if you want.
-	// My experience is that you don't need to.
-	// evolve() is meant to be called many times until it converges (i.e. no more light level changes occur).
-	// Regardless of atomicity convergence will be reached.
+// TargetFPS is the frame rate of the window, and so of the simulation: one evolve pass per frame. 10 fps is fast
+// enough.
+const TargetFPS = 10
 
-	max := int32(0)
-	for _, neighbor := range p.neighbors() {
-		// You can generate (p Point).neighbors() beforehand, and then lock up the affected neighbors, before
-		// executing this loop. I don't.
-		element, exists := layout[neighbor]
-		if !exists {
-			continue
-		}
-		if max < element.Level {
-			max = element.Level
-		}
-	}
-	return max
-}
+// ConvergeMaxIterations is how many evolve passes <Space> may run to snap the grid to its steady state.
+const ConvergeMaxIterations = 256
 
 func int32Max(a int32, b int32) int32 {
 	if a < b {
@@ -105,87 +66,196 @@ func int32Max(a int32, b int32) int32 {
 	}
 }
 
-func cycleLight(level int32) int32 {
-	level++
-	if level >= 16 {
-		level = -1
+// toggleBlocker turns the cell at p into a light-blocking block, or a blocker back into an empty cell.
+func (layout Layout) toggleBlocker(p Point) {
+	if layout.Get(p).Source == -1 {
+		layout.SetSource(p, 0)
+	} else {
+		layout.SetSource(p, -1)
 	}
-	return level
 }
 
-// Evolve the cellular automata
-// Return >0 if it needs to continue.
-func (layout *Layout) evolve() int {
-	// If the number of blocks that has been altered (i.e. light level changes) is 0
-	// then we have reached convergence.
-	changed := 0
+// cellMenuItems builds the right-click context menu of the cell at p.
+func (layout Layout) cellMenuItems(p Point) []MenuItem {
+	cell, _ := layout.At(p)
 
-	// Wait for the pixels (--> voxels) to be processed, since each one will get one logical "thread."
-	// The simulation is 2D, hence only 16 * 16 threads.
-	joiner := new(sync.WaitGroup)
-	joiner.Add(LayoutNSide * LayoutNSide)
+	sources := make([]MenuItem, 0, 15)
+	for source := int32(1); source <= 15; source++ {
+		source := source
+		sources = append(sources, MenuItem{
+			Label:  strconv.Itoa(int(source)),
+			Action: func() { layout.SetSource(p, source) },
+		})
+	}
 
-	// Data- and flow-independently execute for each block.
-	// It doesn't matter if this execution is concurrent or not.
-	// Dirty reads are allowed. Convergence will be reached anyway.
-	for point, cell := range *layout {
-		point := point
-		cell := cell
+	blockerLabel := "Make blocker"
+	if cell.Source < 0 {
+		blockerLabel = "Remove blocker"
+	}
 
-		// Define and then immediately launch a thread for each pixel (--> voxel).
-		go func() {
-			// Once this thread exits, make sure the joiner/wait group is notified.
-			defer joiner.Done()
+	return []MenuItem{
+		{Label: "Set source", Submenu: sources},
+		{Label: blockerLabel, Action: func() { layout.toggleBlocker(p) }},
+		{Label: "Clear", Disabled: cell.Source == 0, Action: func() { layout.RemoveSource(p) }},
+		{Separator: true},
+		{Label: "Copy level", Action: func() { rl.SetClipboardText(strconv.Itoa(int(cell.Level))) }},
+	}
+}
 
-			// If the emission is negative, then it is a light-blocking block, by our definition.
-			if cell.Source < 0 {
-				cell.Level = 0
-				return
-			}
+// SquareSideLengthPx is the size of a cell on screen. It starts at MaxSquareSideLengthPx and shrinks for grids that
+// would not fit otherwise (see squareSideFor).
+var SquareSideLengthPx = MaxSquareSideLengthPx
 
-			// Compare the old and new light levels for each pixel.
-			// We will record the count changed across all pixels.
-			oldLightLevel := cell.Level
+// Bounds on the size of a cell on screen, and the most room the grid may take on either axis.
+const (
+	MaxSquareSideLengthPx = int32(24)
+	MinSquareSideLengthPx = int32(4)
+	MaxGridPx             = int32(768)
+)
 
-			// Determine my (ambient) light level based on my neighbors' (use "largest of n integers" function).
-			neighmax := int32Max(layout.maxNeighborsLightLevel(point)-1, 0)
-			if cell.Level != neighmax {
-				// Note that a cell's light level may increase, stay the same or decrease.
-				cell.Level = neighmax
-			}
+// squareSideFor returns the cell size that fits a width x height grid within MaxGridPx.
+func squareSideFor(width int32, height int32) int32 {
+	px := MaxGridPx / int32Max(int32Max(width, height), 1)
+	if px > MaxSquareSideLengthPx {
+		return MaxSquareSideLengthPx
+	}
+	return int32Max(px, MinSquareSideLengthPx)
+}
 
-			// If it's light generating, then assume the largest of:
-			// - environmental light level and
-			// - self-generated light level
-			// as its own light level.
-			if cell.Source > 0 {
-				cell.Level = int32Max(cell.Source, cell.Level)
-			}
+// MinWindowWidthPx keeps the footer readable below small grids.
+const MinWindowWidthPx = int32(384)
 
-			if cell.Level != oldLightLevel {
-				// If no cells have changed, then, this statement is never executed anyway and "changed" stays at 0.
-				// Therefore, no need to do atomic addition. However, it CAN be done to get an accurate report of
-				// the number changed.
-				changed++
-			}
-		}()
+// Help text shown below the grid, one line each. Keep them short enough to fit the grid's width.
+var footerHelp = []string{
+	"left: increase; right: menu",
+	"shift+right: blocker; <R>: reset",
+	"mid-drag: point light; <X>: delete",
+	"<S>: split point lights; <T>: preview",
+	"<D>: distance; <A>: age; <N>: bands",
+	"<E>: export overlay; <W>: walkability",
+	"<C>: reach vs circle (<V>: SVG)",
+	"<L>: tile hovered source (click: commit)",
+	"<M>: min. source for target, then spot",
+	"<Q>: QA vs -expected; <J>: next open",
+	"<F>: light flow (shift: export CSV)",
+	"<B>: instant light vs stepping",
+	"<Space>: snap to steady state",
+	"hold <U>: suppress updates (shift: poke)",
+	"<K>: linear blending (shift: export PNG)",
+	"<0>-<9>: load slot (shift: save)",
+	"<P>: export PNG (shift: no numbers)",
+	"<G>: start/stop GIF recording; <F12>: screenshot",
+	"<O>: export layout.mcfunction",
+	"<H>: rank blockers by occlusion (CSV)",
+	"<I>: block / combined / sky light",
+	"<+>/<->: time of day (dims sky light)",
+	"hold <Z>: paint light cap (shift: clear)",
+	"credit @0wulfaz",
+}
+
+// FooterFontPx is the font size of the text below the grid.
+const FooterFontPx = int32(14)
+
+// footerLineHeightPx is the distance between two lines of the footer.
+const footerLineHeightPx = FooterFontPx * 3 / 2
+
+// footerHeightPx is the space below the grid: the help text plus one status line.
+func footerHeightPx() int32 {
+	return (int32(len(footerHelp)) + 1) * footerLineHeightPx
+}
+
+// raylibDrawFooter draws the help text and the status line below the grid.
+func raylibDrawFooter(status string) {
+	top := int32(rl.GetScreenHeight()) - footerHeightPx()
+	for i, line := range append(footerHelp, status) {
+		rl.DrawText(line, 0, top+int32(i)*footerLineHeightPx, FooterFontPx, theme.Text)
 	}
+}
 
-	joiner.Wait()
+// MinTwoValueCellPx is the smallest cell side (in pixels) at which a source cell shows both its emission and its
+// light level. Below this, only the primary value is drawn.
+const MinTwoValueCellPx = int32(20)
 
-	return changed
+// cellTextPaddingPx keeps the numbers off the square boundaries.
+const cellTextPaddingPx = int32(1)
+
+// Swap which value goes in the corner: if set, the light level is drawn small in the corner and the emission
+// large in the center.
+var levelInCorner = false
+
+// cellLabel is a single piece of text inside a cell. X and Y are relative to the cell's top-left corner.
+type cellLabel struct {
+	Text string
+	X    int32
+	Y    int32
+	Size int32
 }
 
-const SquareSideLengthPx = int32(24)
+// fitFontSize shrinks the font size until the text fits in the given width.
+func fitFontSize(text string, size int32, width int32, measure func(string, int32) int32) int32 {
+	for size > 1 && measure(text, size) > width {
+		size--
+	}
+	return size
+}
 
-func (layout Layout) raylibDraw() {
-	for x := int32(0); x < LayoutNSide; x++ {
-		for y := int32(0); y < LayoutNSide; y++ {
-			cell, exists := layout[Point{X: x, Y: y}]
+// layoutCellText computes where the numbers of a cell go. It only does the placement math; measure reports the
+// drawn width of a text at a font size, so that any renderer can share this.
+//
+// Blockers show an "x", empty cells show their light level and sources show both their emission and light level
+// (one small in the top-left corner and the other large in the center), unless the cell is too small.
+func layoutCellText(cell Cell, side int32, measure func(string, int32) int32) []cellLabel {
+	inner := side - 2*cellTextPaddingPx
 
-			if !exists {
-				log.Panicf("Bad layout: values missing at %v", Point{X: x, Y: y})
-			}
+	centered := func(text string) cellLabel {
+		size := fitFontSize(text, inner, inner, measure)
+		return cellLabel{
+			Text: text,
+			X:    (side - measure(text, size)) / 2,
+			Y:    (side - size) / 2,
+			Size: size,
+		}
+	}
+
+	if cell.Source < 0 {
+		return []cellLabel{centered("x")}
+	}
+
+	level := strconv.Itoa(int(cell.Level))
+	if cell.Source == 0 {
+		return []cellLabel{centered(level)}
+	}
+
+	source := strconv.Itoa(int(cell.Source))
+	primary, secondary := level, source
+	if levelInCorner {
+		primary, secondary = source, level
+	}
+
+	if side < MinTwoValueCellPx {
+		return []cellLabel{centered(primary)}
+	}
+
+	// The corner value takes up to a third of the cell so it doesn't run into the centered one.
+	cornerSize := fitFontSize(secondary, side/3, inner/2, measure)
+	return []cellLabel{
+		{
+			Text: secondary,
+			X:    cellTextPaddingPx,
+			Y:    cellTextPaddingPx,
+			Size: cornerSize,
+		},
+		centered(primary),
+	}
+}
+
+// raylibDrawSnapshot draws a snapshot of the layout. If shading is not nil, cells are filled with its colors
+// (indexed [y][x]) instead of by light level.
+func raylibDrawSnapshot(snapshot *Snapshot, shading [][]rl.Color) {
+	width, height := snapshot.Size()
+	for x := int32(0); x < width; x++ {
+		for y := int32(0); y < height; y++ {
+			cell, _ := snapshot.At(Point{X: x, Y: y})
 
 			// Admittedly the drawing logic isn't really well-thought-out.
 			// Rough view.
@@ -193,22 +263,25 @@ func (layout Layout) raylibDraw() {
 			//	2. Draw color inside square.
 			//	3. Print number, either ambient light (yellow) or its emission level (orange).
 
-			rl.DrawRectangle(x*SquareSideLengthPx, y*SquareSideLengthPx, SquareSideLengthPx, SquareSideLengthPx, rl.Gray)
+			rl.DrawRectangle(x*SquareSideLengthPx, y*SquareSideLengthPx, SquareSideLengthPx, SquareSideLengthPx, cellBackground)
 
-			var drawColor rl.Color
-			if cell.Source > 0 {
-				drawColor = rl.ColorAlpha(rl.Orange, float32(cell.Level*LayoutNSide)/256.0)
-			} else if cell.Source == 0 {
-				drawColor = rl.ColorAlpha(rl.Yellow, float32(cell.Level*LayoutNSide)/256.0)
+			drawColor := cellFillColor(cell)
+			if shading != nil {
+				drawColor = shading[y][x]
+			} else if liveBlending == BlendLinear {
+				// Opaque, so raylib's own blending has nothing left to do.
+				drawColor = CellColor(cell, BlendLinear)
 			}
 			rl.DrawRectangle(x*SquareSideLengthPx, y*SquareSideLengthPx, SquareSideLengthPx, SquareSideLengthPx, drawColor)
+			if cell.Cap > 0 {
+				raylibDrawCapShade(x*SquareSideLengthPx, y*SquareSideLengthPx, cell.Cap)
+			}
+			if cell.Suppressed {
+				raylibDrawHatching(x*SquareSideLengthPx, y*SquareSideLengthPx)
+			}
 
-			if cell.Source > 0 {
-				rl.DrawText(strconv.Itoa(int(cell.Source)), x*SquareSideLengthPx, y*SquareSideLengthPx, SquareSideLengthPx, rl.Black)
-			} else if cell.Source == 0 {
-				rl.DrawText(strconv.Itoa(int(cell.Level)), x*SquareSideLengthPx, y*SquareSideLengthPx, SquareSideLengthPx, rl.Black)
-			} else {
-				rl.DrawText("x", x*SquareSideLengthPx, y*SquareSideLengthPx, SquareSideLengthPx, rl.Black)
+			for _, label := range layoutCellText(cell, SquareSideLengthPx, rl.MeasureText) {
+				rl.DrawText(label.Text, x*SquareSideLengthPx+label.X, y*SquareSideLengthPx+label.Y, label.Size, rl.Black)
 			}
 
 			// Draw square boundaries
@@ -218,15 +291,429 @@ func (layout Layout) raylibDraw() {
 }
 
 func main() {
+	size := flag.Int("size", int(DefaultLayoutSide), "number of cells along each side of a square grid")
+	widthFlag := flag.Int("width", 0, "number of cells across the grid (default: -size)")
+	heightFlag := flag.Int("height", 0, "number of cells down the grid (default: -size)")
+	flag.BoolVar(&levelInCorner, "level-in-corner", false,
+		"draw a source's light level small in the corner and its emission large in the center")
+	walkThreshold := flag.Int("walk-threshold", 8,
+		"walkability exports (-walkability and <W>): cells with a light level below this are dark")
+	walkSourcesBlocked := flag.Bool("walk-sources-blocked", false,
+		"walkability exports (-walkability and <W>): treat light sources as unwalkable")
+	importPath := flag.String("import-png", "",
+		"start from a PNG lighting plan (one pixel per cell) instead of the test pattern")
+	fromImage := flag.String("from-image", "",
+		"start from a PNG or JPEG image scaled to the grid: brighter pixels become brighter sources, black ones blockers")
+	imageThreshold := flag.Int("from-image-threshold", DefaultImageThreshold,
+		"-from-image: pixels darker than this luminance (0-255) stay empty")
+	schematicPath := flag.String("schematic", "",
+		"start from a horizontal slice of a Sponge schematic (.schem): x and z become the grid, which takes its size")
+	schematicY := flag.Int("schematic-y", 0, "-schematic: height of the slice, 0 being the bottom of the schematic")
+	paintedCap := flag.Int("cap", DefaultPaintedCap, "light cap <Z> paints over cells: no light there exceeds it, "+
+		"whatever the sources (1-15)")
+	sky := flag.Bool("sky", false, "start showing sky light along with block light: the top row is open to the sky "+
+		"(<I> switches between block, combined and sky light)")
+	timeOfDay := flag.Int("time", Noon, "time of day in ticks, dimming sky light: 0 is sunrise, 6000 noon, "+
+		"18000 midnight (<+>/<-> move it an hour)")
+	daySpeed := flag.Int("day-speed", 0, "ticks the time of day moves on per frame, for a day/night cycle; 0 holds it")
+	importColors := flag.String("import-colors", "",
+		"color table for PNG imports, one \"#rrggbb emission\" per line (default: white=15, gray ramp, black=blocker)")
+	importTolerance := flag.Float64("import-tolerance", 24,
+		"PNG import: how far (RGB distance) a pixel may be from a table color and still match it")
+	importCellPx := flag.Int("import-cell-px", 1,
+		"PNG import: side of a cell in the image, e.g. -png-cell-px to read back a grid PNG export")
+	importResize := flag.Bool("import-resize", false,
+		"-import-png: grow the grid to fit a larger image instead of cropping it (dropped PNGs are always cropped)")
+	bandSpec := flag.String("bands", "",
+		"light level bands as name:min-max:#rrggbb,... covering 0-15 without overlap (default: too dark, dim, safe, bright)")
+	maxSource := flag.Int("max-source", 15, "highest emission a left click cycles a cell through")
+	cycleBlockers := flag.Bool("cycle-blockers", true, "left click cycles through a blocker after the highest emission")
+	tileDX := flag.Int("tile-dx", 4, "tiling: horizontal spacing between repeated stamps")
+	tileDY := flag.Int("tile-dy", 4, "tiling: vertical spacing between repeated stamps")
+	expectedPath := flag.String("expected", "",
+		"QA mode: expected light levels (CSV, or JSON array of rows) to compare the simulation with")
+	targetLevel := flag.Int("target-level", 8, "<M>: light level the minimal source search must reach at the target")
+	deterministic := flag.Bool("deterministic", false,
+		"reproducible evolve: cells are updated in a fixed order (see -sweep) instead of concurrently")
+	sweep := flag.Bool("sweep", true,
+		"deterministic mode: update in place in alternating sweeps, which converges in a few passes; "+
+			"if false, every step reads one generation and writes the next (light moves one cell per step)")
+	decay := flag.Int("decay", 0,
+		"phosphorescence: cells whose light drops fade by at most this many levels per tick (0: off)")
+	worldOrigin := flag.String("world-origin", "",
+		"world block coordinates \"x,y,z\" of grid cell (0, 0); shows hovered cells in world coordinates")
+	worldAxes := flag.String("world-axes", "+x+z", "world axes grid x and y run along, e.g. +x+z or -z+y")
+	themeSetting := flag.String("theme", "auto", "color theme: auto (follow the OS), light or dark")
+	reducedMotionSetting := flag.String("reduced-motion", "auto",
+		"show the steady state right away instead of animating propagation: auto (follow the OS), on or off")
+	headless := flag.Bool("headless", false,
+		"no window: read an emission grid (-1 blocker, 0 empty, 1-15 source) from the -layout file, the file "+
+			"given as argument or stdin, and print its converged light levels as CSV")
+	render := flag.String("render", "raylib",
+		"where to show the grid: raylib (a window) or tty (animated in the terminal)")
+	autosaveSeconds := flag.Int("autosave-seconds", DefaultAutosaveSeconds,
+		"seconds between autosaves of the grid, offered back after a crash; 0 turns autosave off")
+	slotsDir := flag.String("slots-dir", "slots", "directory the quick-save slots (<0>-<9>) are kept in")
+	layoutPath := flag.String("layout", "",
+		"start from the emission grid in this file (CSV, JSON rows for .json, one character per cell for .txt), "+
+			"or - for stdin; the grid takes the file's size")
+	tracePath := flag.String("trace", "",
+		"write step,x,y,source,level rows for every cell to this CSV file after each evolve pass, until the "+
+			"levels settle")
+	traceChangedOnly := flag.Bool("trace-changed-only", false, "-trace: only record cells whose level changed")
+	pngPath := flag.String("png", "", "headless: render the grid to this PNG file instead of printing the levels")
+	pngCellPx := flag.Int("png-cell-px", DefaultExportCellPx,
+		"side of a cell in PNG exports (-png and <P>), in pixels")
+	pngNumbers := flag.Bool("png-numbers", true, "-png: draw the levels and emissions in the cells")
+	mcfunctionPath := flag.String("mcfunction", "",
+		"headless: write the layout as Minecraft setblock commands to this .mcfunction file "+
+			"instead of printing the levels")
+	mcfunctionY := flag.Int("mcfunction-y", 0, "height of the layout in .mcfunction exports (-mcfunction and <O>), "+
+		"relative to where the function runs")
+	walkabilityPath := flag.String("walkability", "",
+		"headless: write the walkability matrix to this CSV file, and packed to the same name with a .bin "+
+			"extension, instead of printing the levels")
+	occlusionWorkers := flag.Int("occlusion-workers", runtime.GOMAXPROCS(0),
+		"<H>: blockers relit at the same time when ranking them by occlusion")
+	tidyColumns := flag.String("tidy", "",
+		"headless: print one row per cell with these columns instead of the level grid, "+
+			"e.g. source,level,owner,band or all (columns: source, level, opacity, medium, owner, room, band, cap)")
+	exportNamingSetting := flag.String("export-naming", "fixed",
+		"names of exported files: fixed (e.g. grid.png, overwritten each time) or hash (e.g. "+
+			"grid-3fa2c1-cell_64px-numbers_on.png, from the content and export settings; not written again if present)")
+	maxIterations := flag.Int("max-iterations", ConvergeMaxIterations,
+		"headless: evolve passes allowed to converge; exit with an error if they are not enough")
+	flag.Parse()
+
+	if *bandSpec != "" {
+		parsed, err := ParseBands(*bandSpec)
+		if err != nil {
+			log.Fatalf("Bands: %v", err)
+		}
+		bands = parsed
+	}
+
+	naming, err := ParseExportNaming(*exportNamingSetting)
+	if err != nil {
+		log.Fatalf("-export-naming: %v", err)
+	}
+	exportNaming = naming
+
+	if *pngCellPx < 1 {
+		log.Fatalf("-png-cell-px must be at least 1, got %d", *pngCellPx)
+	}
+
+	if *headless {
+		output := func(layout Layout) error { return writeLevelsCSV(os.Stdout, layout) }
+		if *tidyColumns != "" {
+			tidy, err := ParseColumns(*tidyColumns)
+			if err != nil {
+				log.Fatalf("-tidy: %v", err)
+			}
+			output = func(layout Layout) error { return layout.WriteTidyCSV(os.Stdout, tidy) }
+		}
+		// With content-hash naming, the name the file got is printed for scripts to pick up.
+		printName := func(path string, err error) error {
+			if err == nil && exportNaming == NamingHash {
+				fmt.Println(path)
+			}
+			return err
+		}
+		if *mcfunctionPath != "" {
+			output = func(layout Layout) error {
+				return printName(writeMCFunctionFile(*mcfunctionPath, layout, int32(*mcfunctionY)))
+			}
+		}
+		if *walkabilityPath != "" {
+			output = func(layout Layout) error {
+				paths, err := layout.exportWalkability(*walkabilityPath, int32(*walkThreshold), *walkSourcesBlocked)
+				if err == nil && exportNaming == NamingHash {
+					fmt.Println(strings.Join(paths, "\n"))
+				}
+				return err
+			}
+		}
+		if *pngPath != "" {
+			output = func(layout Layout) error {
+				return printName(writeGridPNGFile(*pngPath, layout.Snapshot(), int32(*pngCellPx), *pngNumbers))
+			}
+		}
+		path := flag.Arg(0)
+		if *layoutPath != "" {
+			if path != "" {
+				log.Fatalf("-layout and the argument %s both give the grid; use only one", path)
+			}
+			path = *layoutPath
+		}
+		if err := runHeadless(path, *maxIterations, output); err != nil {
+			log.Fatalf("Headless: %v", err)
+		}
+		return
+	}
+
+	if *render != "raylib" && *render != "tty" {
+		log.Fatalf("-render must be raylib or tty, got %q", *render)
+	}
+
+	if theme, err = chooseTheme(*themeSetting); err != nil {
+		log.Fatalf("-theme: %v", err)
+	}
+	reducedMotion, err := chooseReducedMotion(*reducedMotionSetting)
+	if err != nil {
+		log.Fatalf("-reduced-motion: %v", err)
+	}
+	var world *WorldTransform
+	if *worldOrigin != "" {
+		transform, err := ParseWorldTransform(*worldOrigin, *worldAxes)
+		if err != nil {
+			log.Fatalf("-world-origin/-world-axes: %v", err)
+		}
+		world = &transform
+	}
+
+	starts := 0
+	for _, path := range []string{*layoutPath, *importPath, *fromImage, *schematicPath} {
+		if path != "" {
+			starts++
+		}
+	}
+	if starts > 1 {
+		log.Fatalf("-layout, -import-png, -from-image and -schematic all give the starting grid; use only one")
+	}
+	if *imageThreshold < 0 || *imageThreshold > 255 {
+		log.Fatalf("-from-image-threshold must be between 0 and 255, got %d", *imageThreshold)
+	}
+
+	var loaded *Layout
+	loadedPath := ""
+	if *layoutPath != "" {
+		layout, err := loadLayoutFile(*layoutPath)
+		if err != nil {
+			log.Fatalf("Layout: %v", err)
+		}
+		loaded, loadedPath = &layout, *layoutPath
+	}
+	if *schematicPath != "" {
+		layout, err := importSchematicFile(*schematicPath, int32(*schematicY))
+		if err != nil {
+			log.Fatalf("Schematic: %v", err)
+		}
+		loaded, loadedPath = &layout, *schematicPath
+	}
+	if loaded != nil {
+		loadedWidth, loadedHeight := loaded.Size()
+		// An explicit size must agree with the file: the grid is never cropped or padded to fit.
+		sizeSet := false
+		flag.Visit(func(f *flag.Flag) {
+			sizeSet = sizeSet || f.Name == "size" || f.Name == "width" || f.Name == "height"
+		})
+		if *widthFlag == 0 {
+			*widthFlag = *size
+		}
+		if *heightFlag == 0 {
+			*heightFlag = *size
+		}
+		if sizeSet && (int32(*widthFlag) != loadedWidth || int32(*heightFlag) != loadedHeight) {
+			log.Fatalf("Layout %s is %dx%d, but the grid size was set to %dx%d",
+				loadedPath, loadedWidth, loadedHeight, *widthFlag, *heightFlag)
+		}
+		*widthFlag, *heightFlag = int(loadedWidth), int(loadedHeight)
+	}
+
+	if *widthFlag == 0 {
+		*widthFlag = *size
+	}
+	if *heightFlag == 0 {
+		*heightFlag = *size
+	}
+	if *widthFlag < 1 || *widthFlag > 1024 || *heightFlag < 1 || *heightFlag > 1024 {
+		log.Fatalf("the grid must be between 1 and 1024 cells on each side, got %dx%d", *widthFlag, *heightFlag)
+	}
+	width := int32(*widthFlag)
+	height := int32(*heightFlag)
+	SquareSideLengthPx = squareSideFor(width, height)
+	if SquareSideLengthPx < MaxSquareSideLengthPx {
+		log.Printf("%dx%d grid: cells shrunk to %dpx to fit the screen\n", width, height, SquareSideLengthPx)
+	}
+
+	if *tileDX < 1 || *tileDY < 1 {
+		log.Fatalf("-tile-dx and -tile-dy must be at least 1, got %d and %d", *tileDX, *tileDY)
+	}
+
+	if *decay < 0 {
+		log.Fatalf("-decay must not be negative, got %d", *decay)
+	}
+	if *autosaveSeconds < 0 {
+		log.Fatalf("-autosave-seconds must not be negative, got %d", *autosaveSeconds)
+	}
+	if *occlusionWorkers < 1 {
+		log.Fatalf("-occlusion-workers must be at least 1, got %d", *occlusionWorkers)
+	}
+	if *timeOfDay < 0 || *timeOfDay >= DayLength {
+		log.Fatalf("-time must be between 0 and %d, got %d", DayLength-1, *timeOfDay)
+	}
+	if *paintedCap < 1 || *paintedCap > 15 {
+		log.Fatalf("-cap must be between 1 and 15, got %d", *paintedCap)
+	}
+	if *maxSource < 1 {
+		log.Fatalf("-max-source must be at least 1, got %d", *maxSource)
+	}
+
+	colorTable := DefaultColorTable()
+	if *importColors != "" {
+		file, err := os.Open(*importColors)
+		if err != nil {
+			log.Fatalf("Color table: %v", err)
+		}
+		colorTable, err = ParseColorTable(file)
+		file.Close()
+		if err != nil {
+			log.Fatalf("Color table %s: %v", *importColors, err)
+		}
+	}
+	if *importCellPx < 1 {
+		log.Fatalf("-import-cell-px must be at least 1, got %d", *importCellPx)
+	}
+	pngImport := PNGImport{Table: colorTable, Tolerance: *importTolerance, CellPx: int32(*importCellPx),
+		Resize: *importResize}
+
 	// Test pattern (starter).
-	testPattern := makeEmptyLayout()
-	testPattern[Point{X: 1, Y: 1}] = &Cell{Source: 15, Level: 0}
+	testPattern := NewLayout(width, height)
+	if cell, exists := testPattern.At(Point{X: 1, Y: 1}); exists {
+		cell.Source = 15
+	}
 
-	// 64 px --- give it some space at the bottom for extra text
-	rl.InitWindow(LayoutNSide*SquareSideLengthPx, LayoutNSide*SquareSideLengthPx+64, "Minecraft lighting automata demo (pixels)")
+	if *importPath != "" {
+		imported, err := importPNGFile(*importPath, width, height, pngImport)
+		if err != nil {
+			log.Fatalf("PNG import: %v", err)
+		}
+		testPattern = imported
+		if width, height = imported.Size(); width > 1024 || height > 1024 {
+			log.Fatalf("PNG import: %s would grow the grid to %dx%d, past 1024 cells on a side", *importPath, width,
+				height)
+		}
+		SquareSideLengthPx = squareSideFor(width, height)
+	}
+	if *fromImage != "" {
+		imported, err := ImportImage(*fromImage, width, height, uint8(*imageThreshold))
+		if err != nil {
+			log.Fatalf("Image import: %v", err)
+		}
+		testPattern = imported
+	}
+	if loaded != nil {
+		testPattern = *loaded
+	}
+
+	// QA: discrepancies against measured light levels
+	var qa *QA
+	if *expectedPath != "" {
+		if qa, err = LoadQA(*expectedPath, width, height); err != nil {
+			log.Fatalf("Expected levels: %v", err)
+		}
+	}
+	showQA := qa != nil
+
+	// The renderer only reads published snapshots, never the cells evolve is working on.
+	simulation := NewSimulation(testPattern)
+	slots := NewSaveSlots(*slotsDir)
+	simulation.DecayPerTick = int32(*decay)
+	simulation.Deterministic = *deterministic
+	simulation.Sweep = *sweep
+	simulation.MaxSource = int32(*maxSource)
+	simulation.BlockerInCycle = *cycleBlockers
+	simulation.Time = int32(*timeOfDay)
+	if *sky {
+		simulation.View = ViewCombined
+		simulation.Layout.PropagateSky()
+	}
+	if *tracePath != "" {
+		trace, err := NewTrace(*tracePath, *traceChangedOnly)
+		if err != nil {
+			log.Fatalf("Trace: %v", err)
+		}
+		defer trace.Close()
+		simulation.Trace = trace
+	}
+
+	if *render == "tty" {
+		if err := runTTY(simulation, os.Stdout); err != nil {
+			log.Fatalf("Terminal renderer: %v", err)
+		}
+		return
+	}
 
-	// 10 fps is fast enough
-	rl.SetTargetFPS(10)
+	// Overlay shown instead of the light levels
+	overlay := OverlayNone
+
+	// Point lights, and the one being dragged (-1 if none)
+	pointLights := &PointLights{}
+	simulation.PointLights = pointLights
+	dragging := -1
+
+	// Reach versus Euclidean circle comparison around the hovered source
+	showReachCircle := false
+
+	// Target of a minimal source search, picked by a first <M>
+	var minSourceTarget *Point
+
+	// Arrows showing where each cell's light comes from
+	showFlow := false
+
+	// Preview of what the next left click would light up
+	showPreview := true
+
+	// Tiling mode: the hovered cell's source repeats across the grid, through the hovered cell
+	tiling := false
+	tileAt := func(anchor Point) (Stamp, []Point, []Point) {
+		source := int32(15)
+		if cell, exists := simulation.Layout.At(anchor); exists && cell.Source > 0 {
+			source = cell.Source
+		}
+		stamp := Stamp{Width: 1, Height: 1, Sources: []int32{source}}
+		spacing := Spacing{DX: int32(*tileDX), DY: int32(*tileDY), OffsetX: anchor.X, OffsetY: anchor.Y}
+		placed, skipped := simulation.Layout.Tile(stamp, simulation.Layout.WholeGrid(), spacing)
+		return stamp, placed, skipped
+	}
+
+	// Occlusion of the hovered blocker, and the ranking of all of them running in the background, if any
+	occlusion := &OcclusionCache{}
+	var occlusionJob *OcclusionJob
+
+	// GIF recording in progress, if any
+	var recording *GIFRecording
+	stopRecording := func() {
+		if path, err := recording.exportGIF(); err != nil {
+			log.Printf("GIF export failed: %v\n", err)
+		} else {
+			log.Printf("Exported %s (%d frames)\n", path, recording.Len())
+		}
+		recording = nil
+	}
+
+	// Right-click context menu
+	menu := &Menu{}
+	ignoreLeftUntilRelease := false
+
+	// Give it some space at the bottom for extra text
+	rl.InitWindow(int32Max(width*SquareSideLengthPx, MinWindowWidthPx), height*SquareSideLengthPx+footerHeightPx(), "Minecraft lighting automata demo (pixels)")
+
+	rl.SetTargetFPS(TargetFPS)
+
+	if *autosaveSeconds > 0 {
+		path := autosavePath()
+		if autosaved, savedAt, ok := recoverableAutosave(path, slots, width, height); ok {
+			if raylibPromptRestore(autosaved, savedAt) {
+				simulation.Layout = autosaved
+				log.Printf("Restored the autosave from %v\n", savedAt)
+			}
+		}
+		// Closing the window at the prompt keeps the autosave for next time.
+		if !rl.WindowShouldClose() {
+			defer simulation.startAutosave(path, time.Duration(*autosaveSeconds)*time.Second)()
+		}
+	}
 
 	for !rl.WindowShouldClose() {
 		// Update
@@ -234,43 +721,83 @@ func main() {
 		// Mouse ... Pressed = only once
 		// Mouse ... Down = as long as pressed
 
-		if rl.IsMouseButtonPressed(rl.MouseRightButton) {
-			// Right click to reset cell
+		// The context menu takes the mouse while it is open. A left click on it must not also paint the cell below
+		// once the menu is gone, so the left button is ignored until it is released.
+		menuWasOpen := menu.IsOpen()
+		menu.Update()
+		if menuWasOpen && rl.IsMouseButtonPressed(rl.MouseLeftButton) {
+			ignoreLeftUntilRelease = true
+		}
+		if rl.IsMouseButtonReleased(rl.MouseLeftButton) {
+			ignoreLeftUntilRelease = false
+		}
+		if menu.IsOpen() {
+			// Escape closes the menu rather than the window
+			rl.SetExitKey(0)
+		} else {
+			rl.SetExitKey(rl.KeyEscape)
+		}
+
+		if !menuWasOpen && rl.IsMouseButtonPressed(rl.MouseRightButton) {
+			// Right click for the cell's context menu, shift + right click to toggle a blocker
 
 			// Poll the cell location
 			guessX := rl.GetMouseX() / SquareSideLengthPx
 			guessY := rl.GetMouseY() / SquareSideLengthPx
 
 			// In range? Do it.
-			if 0 <= guessX && guessX < LayoutNSide &&
-				0 <= guessY && guessY < LayoutNSide {
-				// Cycle the light level.
-				cell, exists := testPattern[Point{X: guessX, Y: guessY}]
+			if 0 <= guessX && guessX < width &&
+				0 <= guessY && guessY < height {
+				point := Point{X: guessX, Y: guessY}
 
-				if !exists {
+				if !simulation.Layout.Contains(point) {
 					// No big deal if the guess fails. Just note it and then move on.
 					log.Printf("Guess failed mouse X, Y = (%d, %d) ==> gX, gY = (%d, %d)\n",
 						rl.GetMouseX(), rl.GetMouseY(), guessX, guessY)
+				} else if rl.IsKeyDown(rl.KeyLeftShift) || rl.IsKeyDown(rl.KeyRightShift) {
+					simulation.Layout.toggleBlocker(point)
+				} else {
+					items := simulation.Layout.cellMenuItems(point)
+					if showQA {
+						qaItems := qa.menuItems(simulation.Layout, point, func(err error) {
+							if err != nil {
+								log.Printf("Saving acknowledgements failed: %v\n", err)
+							}
+						})
+						if len(qaItems) > 0 {
+							items = append(append(items, MenuItem{Separator: true}), qaItems...)
+						}
+					}
+					menu.Open(items, rl.GetMouseX(), rl.GetMouseY())
 				}
+			}
+		}
 
-				if cell.Source == -1 {
-					cell.Source = 0
-				} else {
-					cell.Source = -1
+		if tiling && !menu.IsOpen() && !ignoreLeftUntilRelease && rl.IsMouseButtonPressed(rl.MouseLeftButton) {
+			// Commit the tiling previewed through the clicked cell
+			anchor := Point{X: rl.GetMouseX() / SquareSideLengthPx, Y: rl.GetMouseY() / SquareSideLengthPx}
+			if simulation.Layout.Contains(anchor) {
+				stamp, placed, skipped := tileAt(anchor)
+				for _, point := range placed {
+					simulation.Layout.PlaceStamp(stamp, point)
 				}
+				log.Printf("Tiled %d stamp(s), skipped %d on blockers\n", len(placed), len(skipped))
+				tiling = false
 			}
+			ignoreLeftUntilRelease = true
 		}
 
-		if rl.IsMouseButtonDown(rl.MouseLeftButton) {
+		if !tiling && !menu.IsOpen() && !ignoreLeftUntilRelease && rl.IsMouseButtonDown(rl.MouseLeftButton) {
 			// Poll the cell location
 			guessX := rl.GetMouseX() / SquareSideLengthPx
 			guessY := rl.GetMouseY() / SquareSideLengthPx
 
 			// In range? Do it.
-			if 0 <= guessX && guessX < LayoutNSide &&
-				0 <= guessY && guessY < LayoutNSide {
+			if 0 <= guessX && guessX < width &&
+				0 <= guessY && guessY < height {
 				// Cycle the light level.
-				cell, exists := testPattern[Point{X: guessX, Y: guessY}]
+				point := Point{X: guessX, Y: guessY}
+				cell, exists := simulation.Layout.At(point)
 
 				if !exists {
 					// No big deal if the guess fails. Just note it and then move on.
@@ -278,31 +805,466 @@ func main() {
 						rl.GetMouseX(), rl.GetMouseY(), guessX, guessY)
 				}
 
-				newSource := cycleLight(cell.Source)
+				newSource := simulation.NextSourceValue(cell.Source)
+
+				simulation.Layout.SetSource(point, newSource)
+			}
+		}
+
+		if rl.IsKeyPressed(rl.KeyW) {
+			// Export the walkability matrix
+			paths, err := simulation.Layout.exportWalkability("walkability.csv", int32(*walkThreshold),
+				*walkSourcesBlocked)
+			if err != nil {
+				log.Printf("Walkability export failed: %v\n", err)
+			} else {
+				log.Printf("Exported %s\n", strings.Join(paths, " and "))
+			}
+		}
+
+		if rl.IsFileDropped() {
+			// Dropping a PNG lighting plan on the window imports it
+			var count int32
+			files := rl.GetDroppedFiles(&count)
+			for _, path := range files {
+				if !strings.EqualFold(filepath.Ext(path), ".png") {
+					log.Printf("Ignoring dropped file %s: not a PNG\n", path)
+					continue
+				}
+				// The window is sized for the grid, so dropped plans never resize it
+				dropImport := pngImport
+				dropImport.Resize = false
+				imported, err := importPNGFile(path, width, height, dropImport)
+				if err != nil {
+					log.Printf("PNG import failed: %v\n", err)
+					continue
+				}
+				simulation.Layout = imported
+				pointLights.Forget()
+			}
+			rl.ClearDroppedFiles()
+		}
+
+		// Point lights live in continuous space, in cell units.
+		mouseCellX := float32(rl.GetMouseX()) / float32(SquareSideLengthPx)
+		mouseCellY := float32(rl.GetMouseY()) / float32(SquareSideLengthPx)
 
-				testPattern[Point{X: guessX, Y: guessY}].Source = newSource
+		if rl.IsMouseButtonPressed(rl.MouseMiddleButton) &&
+			0 <= mouseCellX && mouseCellX < float32(width) && 0 <= mouseCellY && mouseCellY < float32(height) {
+			// Grab the marker under the cursor, or drop a new point light there
+			dragging = pointLights.Nearest(mouseCellX, mouseCellY, PointLightGrabRadius)
+			if dragging < 0 {
+				pointLights.Lights = append(pointLights.Lights, PointLight{X: mouseCellX, Y: mouseCellY, Emission: 15})
+				dragging = len(pointLights.Lights) - 1
 			}
 		}
 
+		if dragging >= 0 && rl.IsMouseButtonDown(rl.MouseMiddleButton) {
+			// Keep the marker on the grid
+			pointLights.Lights[dragging].X = float32(math.Min(math.Max(float64(mouseCellX), 0), float64(width)-0.001))
+			pointLights.Lights[dragging].Y = float32(math.Min(math.Max(float64(mouseCellY), 0), float64(height)-0.001))
+		}
+
+		if rl.IsMouseButtonReleased(rl.MouseMiddleButton) {
+			dragging = -1
+		}
+
+		if rl.IsKeyPressed(rl.KeyX) && dragging < 0 {
+			// Delete the point light under the cursor
+			if i := pointLights.Nearest(mouseCellX, mouseCellY, PointLightGrabRadius); i >= 0 {
+				pointLights.Remove(i)
+			}
+		}
+
+		if rl.IsKeyPressed(rl.KeyS) {
+			// Toggle splitting point lights across the four nearest cells
+			pointLights.Split = !pointLights.Split
+		}
+
 		if rl.IsKeyPressed(rl.KeyR) {
 			// Reset everything
-			testPattern = makeEmptyLayout()
+			simulation.Layout = NewLayout(width, height)
+			*pointLights = PointLights{}
+			dragging = -1
+			slots.Current = -1
+		}
+
+		for slot := 0; slot < SaveSlotCount; slot++ {
+			if !rl.IsKeyPressed(rl.KeyZero + int32(slot)) {
+				continue
+			}
+			if rl.IsKeyDown(rl.KeyLeftShift) || rl.IsKeyDown(rl.KeyRightShift) {
+				if err := slots.Save(slot, pointLights.Unrasterized(simulation.Layout)); err != nil {
+					log.Printf("Saving slot %d failed: %v\n", slot, err)
+				} else {
+					log.Printf("Saved slot %d to %s\n", slot, slots.Path(slot))
+				}
+				continue
+			}
+			loaded, err := slots.Load(slot, width, height)
+			if errors.Is(err, os.ErrNotExist) {
+				log.Printf("Slot %d is empty\n", slot)
+			} else if err != nil {
+				log.Printf("Loading slot %d failed: %v\n", slot, err)
+			} else {
+				simulation.Layout = loaded
+				pointLights.Forget()
+				dragging = -1
+				log.Printf("Loaded slot %d\n", slot)
+			}
+		}
+
+		// Only does anything if a point light was added, moved or removed, or Split toggled
+		pointLights.Rasterize(simulation.Layout)
+
+		if rl.IsKeyPressed(rl.KeyD) {
+			// Cycle the distance overlays: off -> sources -> blockers -> off
+			switch overlay {
+			case OverlaySourceDistance:
+				overlay = OverlayBlockerDistance
+			case OverlayBlockerDistance:
+				overlay = OverlayNone
+			default:
+				overlay = OverlaySourceDistance
+			}
+		}
+
+		if rl.IsKeyPressed(rl.KeyA) {
+			if rl.IsKeyDown(rl.KeyLeftShift) || rl.IsKeyDown(rl.KeyRightShift) {
+				// Restart the age clock of every cell
+				simulation.Layout.ResetAges()
+			} else if overlay == OverlayAge {
+				overlay = OverlayNone
+			} else {
+				overlay = OverlayAge
+			}
+		}
+
+		if rl.IsKeyPressed(rl.KeyF) {
+			if rl.IsKeyDown(rl.KeyLeftShift) || rl.IsKeyDown(rl.KeyRightShift) {
+				// Export the flow field
+				if path, err := simulation.Layout.exportFlowField(); err != nil {
+					log.Printf("Flow field export failed: %v\n", err)
+				} else {
+					log.Printf("Exported %s\n", path)
+				}
+			} else {
+				showFlow = !showFlow
+			}
+		}
+
+		if rl.IsKeyPressed(rl.KeyB) {
+			// Toggle between stepping the cellular automaton and converging at once with a flood fill
+			simulation.Instant = !simulation.Instant
+		}
+
+		if rl.IsKeyPressed(rl.KeyI) {
+			// Switch between block light, both fields combined and sky light
+			simulation.View = simulation.View.next()
+			if simulation.View != ViewBlock {
+				simulation.Layout.PropagateSky()
+			}
+			log.Printf("Showing %v\n", simulation.View)
+		}
+
+		if rl.IsKeyPressed(rl.KeyEqual) || rl.IsKeyPressed(rl.KeyKpAdd) ||
+			rl.IsKeyPressed(rl.KeyMinus) || rl.IsKeyPressed(rl.KeyKpSubtract) {
+			// Move the time of day an hour, which only changes how the sky light is shown
+			if rl.IsKeyPressed(rl.KeyMinus) || rl.IsKeyPressed(rl.KeyKpSubtract) {
+				simulation.AdvanceTime(-DayStepTicks)
+			} else {
+				simulation.AdvanceTime(DayStepTicks)
+			}
+			log.Printf("Time %s\n", timeStatus(simulation.Time))
+		}
+		if *daySpeed != 0 {
+			simulation.AdvanceTime(int32(*daySpeed))
+		}
+
+		if rl.IsKeyDown(rl.KeyU) {
+			// Paint suppressed cells while held; shift + <U> pokes the hovered cell's patch awake instead
+			hovered := Point{X: rl.GetMouseX() / SquareSideLengthPx, Y: rl.GetMouseY() / SquareSideLengthPx}
+			if rl.IsKeyDown(rl.KeyLeftShift) || rl.IsKeyDown(rl.KeyRightShift) {
+				if rl.IsKeyPressed(rl.KeyU) {
+					simulation.Layout.Poke(hovered)
+				}
+			} else {
+				simulation.Layout.Suppress(hovered)
+			}
+		}
+
+		if rl.IsKeyDown(rl.KeyZ) {
+			// Paint the light cap over cells while held; shift + <Z> clears it
+			hovered := Point{X: rl.GetMouseX() / SquareSideLengthPx, Y: rl.GetMouseY() / SquareSideLengthPx}
+			if rl.IsKeyDown(rl.KeyLeftShift) || rl.IsKeyDown(rl.KeyRightShift) {
+				simulation.Layout.SetCap(hovered, 0)
+			} else {
+				simulation.Layout.SetCap(hovered, int32(*paintedCap))
+			}
+		}
+
+		if rl.IsKeyPressed(rl.KeySpace) {
+			// Run evolve until the grid settles
+			iterations, converged := simulation.Converge(ConvergeMaxIterations)
+			if converged {
+				log.Printf("Converged after %d iteration(s)\n", iterations)
+			} else {
+				log.Printf("Not converged after %d iterations\n", iterations)
+			}
+		}
+
+		if rl.IsKeyPressed(rl.KeyL) {
+			// Toggle tiling mode
+			tiling = !tiling
+		}
+
+		if rl.IsKeyPressed(rl.KeyT) {
+			// The placement preview relights a clone every frame; this turns it off for big, slow sessions
+			showPreview = !showPreview
+		}
+
+		if rl.IsKeyPressed(rl.KeyC) {
+			showReachCircle = !showReachCircle
+		}
+
+		if rl.IsKeyPressed(rl.KeyV) {
+			// Export the reach versus circle comparison of the hovered source as SVG
+			hovered := Point{X: rl.GetMouseX() / SquareSideLengthPx, Y: rl.GetMouseY() / SquareSideLengthPx}
+			if path, err := simulation.Layout.exportReachCircle(hovered); err != nil {
+				log.Printf("Reach comparison export failed: %v\n", err)
+			} else {
+				log.Printf("Exported %s\n", path)
+			}
+		}
+
+		if rl.IsKeyPressed(rl.KeyM) {
+			// First press picks the target cell, the second the spot to place a source at.
+			hovered := Point{X: rl.GetMouseX() / SquareSideLengthPx, Y: rl.GetMouseY() / SquareSideLengthPx}
+			if !simulation.Layout.Contains(hovered) {
+				minSourceTarget = nil
+			} else if minSourceTarget == nil {
+				minSourceTarget = &hovered
+				log.Printf("Target %v picked; press <M> over where the source would go\n", hovered)
+			} else {
+				source, err := simulation.Layout.MinSourceFor(hovered, *minSourceTarget, int32(*targetLevel))
+				if err != nil {
+					log.Printf("No source at %v can light %v to %d: %v\n", hovered, *minSourceTarget, *targetLevel, err)
+				} else {
+					log.Printf("A source at %v needs emission %d to light %v to %d\n",
+						hovered, source, *minSourceTarget, *targetLevel)
+				}
+				minSourceTarget = nil
+			}
+		}
+
+		if rl.IsKeyPressed(rl.KeyN) {
+			// Toggle the light level band overlay
+			if overlay == OverlayBands {
+				overlay = OverlayNone
+			} else {
+				overlay = OverlayBands
+			}
+		}
+
+		if rl.IsKeyPressed(rl.KeyQ) {
+			// Toggle the comparison with the expected levels
+			if qa == nil {
+				log.Printf("No expected levels loaded (see -expected)\n")
+			} else {
+				showQA = !showQA
+			}
+		}
+
+		if rl.IsKeyPressed(rl.KeyJ) && showQA {
+			// Move the cursor to the next open discrepancy
+			if discrepancy, ok := qa.Next(simulation.Layout); ok {
+				rl.SetMousePosition(
+					int(discrepancy.Point.X*SquareSideLengthPx+SquareSideLengthPx/2),
+					int(discrepancy.Point.Y*SquareSideLengthPx+SquareSideLengthPx/2))
+				log.Printf("(%d, %d): expected %d, got %d\n",
+					discrepancy.Point.X, discrepancy.Point.Y, discrepancy.Expected, discrepancy.Actual)
+			} else {
+				log.Printf("No open discrepancies\n")
+			}
+		}
+
+		if rl.IsKeyPressed(rl.KeyP) {
+			// Render the published grid to grid.png, with or without the numbers
+			numbers := !(rl.IsKeyDown(rl.KeyLeftShift) || rl.IsKeyDown(rl.KeyRightShift))
+			if path, err := exportGridPNG(simulation.Snapshot(), int32(*pngCellPx), numbers); err != nil {
+				log.Printf("PNG export failed: %v\n", err)
+			} else {
+				log.Printf("Exported %s\n", path)
+			}
+		}
+
+		if rl.IsKeyPressed(rl.KeyH) {
+			if occlusionJob != nil {
+				log.Printf("Occlusion ranking already running (%s)\n", occlusionJob.status())
+			} else {
+				occlusionJob = StartOcclusionJob(simulation.Layout, simulation.Layout.WholeGrid(), *occlusionWorkers)
+			}
+		}
+		if occlusionJob != nil {
+			if gains, finished, err := occlusionJob.Poll(simulation.Layout); finished {
+				occlusionJob = nil
+				path := ""
+				if err == nil {
+					path, err = exportOcclusionCSV(gains)
+				}
+				if err != nil {
+					log.Printf("Occlusion ranking failed: %v\n", err)
+				} else {
+					log.Printf("Exported %s (%d blockers)\n", path, len(gains))
+				}
+			}
+		}
+
+		if rl.IsKeyPressed(rl.KeyO) {
+			// Export setblock commands to rebuild the layout in game
+			if path, err := writeMCFunctionFile("layout.mcfunction", simulation.Layout, int32(*mcfunctionY)); err != nil {
+				log.Printf("mcfunction export failed: %v\n", err)
+			} else {
+				log.Printf("Exported %s\n", path)
+			}
+		}
+
+		if rl.IsKeyPressed(rl.KeyG) {
+			if recording == nil {
+				recording = NewGIFRecording(TargetFPS)
+				log.Printf("Recording, up to %d frames; press <G> again to stop\n", GIFMaxFrames)
+			} else {
+				stopRecording()
+			}
+		}
+
+		if rl.IsKeyPressed(rl.KeyK) {
+			if rl.IsKeyDown(rl.KeyLeftShift) || rl.IsKeyDown(rl.KeyRightShift) {
+				// Export sRGB and linear blending of every level side by side
+				if path, err := exportBlendingComparison(); err != nil {
+					log.Printf("Blending comparison export failed: %v\n", err)
+				} else {
+					log.Printf("Exported %s\n", path)
+				}
+			} else {
+				if liveBlending == BlendLinear {
+					liveBlending = BlendSRGB
+				} else {
+					liveBlending = BlendLinear
+				}
+				log.Printf("Blending in %v\n", liveBlending)
+			}
+		}
+
+		if rl.IsKeyPressed(rl.KeyE) {
+			// Export the overlay being shown, or the QA report
+			if showQA {
+				if path, err := qa.exportQAReport(simulation.Layout); err != nil {
+					log.Printf("QA report export failed: %v\n", err)
+				} else {
+					log.Printf("Exported %s\n", path)
+				}
+			} else if overlay == OverlayNone {
+				log.Printf("No overlay shown, nothing to export\n")
+			} else if paths, err := overlay.export(simulation.Layout); err != nil {
+				log.Printf("Overlay export failed: %v\n", err)
+			} else {
+				log.Printf("Exported the %v overlay to %s\n", overlay, strings.Join(paths, " and "))
+			}
 		}
 
 		// Drawing
-		rl.BeginDrawing()
 
-		rl.ClearBackground(rl.RayWhite)
+		// Make this frame's edits visible, then draw what is published.
+		simulation.Publish()
+		drawFrame := func() {
+			rl.ClearBackground(theme.Background)
+			raylibDrawSnapshot(simulation.Snapshot(), overlay.colors(simulation.Layout))
+			if showFlow {
+				raylibDrawFlowField(simulation.Layout.FlowField())
+			}
+			pointLights.raylibDraw()
+			if showQA {
+				raylibDrawDiscrepancies(qa.Discrepancies(simulation.Layout))
+			}
+			if minSourceTarget != nil {
+				raylibDrawMinSourceTarget(*minSourceTarget)
+			}
+			if !menu.IsOpen() {
+				hovered := Point{X: rl.GetMouseX() / SquareSideLengthPx, Y: rl.GetMouseY() / SquareSideLengthPx}
+				if cell, exists := simulation.Layout.At(hovered); exists && showPreview {
+					// Preview the source the next left click would place
+					next := simulation.NextSourceValue(cell.Source)
+					if next > 0 {
+						if changes, ok := simulation.Layout.PlacementPreview(hovered, next, PreviewBudget); ok {
+							simulation.Layout.raylibDrawPlacementPreview(changes)
+						}
+					}
+				}
+				if simulation.Layout.Contains(hovered) && tiling {
+					stamp, placed, skipped := tileAt(hovered)
+					raylibDrawTilePreview(stamp, placed, skipped)
+				}
+				if showReachCircle {
+					simulation.Layout.raylibDrawReachCircle(hovered)
+				}
+				simulation.Layout.raylibDrawReachBadge(hovered)
+				occlusion.raylibDrawOcclusionBadge(simulation.Layout, hovered)
+			}
+			menu.raylibDraw()
+
+			status := simulation.Layout.bandSummary(bands)
+			if showQA {
+				status = qaSummary(qa.Discrepancies(simulation.Layout))
+			}
+			if hovered := (Point{X: rl.GetMouseX() / SquareSideLengthPx, Y: rl.GetMouseY() / SquareSideLengthPx}); world != nil {
+				if simulation.Layout.Contains(hovered) {
+					status = world.worldStatus(hovered)
+				}
+			}
+			if slot := slots.status(); slot != "" {
+				status += " | " + slot
+			}
+			if occlusionJob != nil {
+				status += " | " + occlusionJob.status()
+			}
+			if simulation.View != ViewBlock {
+				status += " | " + timeStatus(simulation.Time)
+			}
+			raylibDrawFooter(status)
+		}
 
-		testPattern.raylibDraw()
+		if rl.IsKeyPressed(rl.KeyF12) {
+			// Save the frame as displayed, footer included
+			if path, err := raylibSaveScreenshot(drawFrame); err != nil {
+				log.Printf("Screenshot failed: %v\n", err)
+			} else {
+				log.Printf("Saved %s\n", path)
+			}
+		}
 
-		rl.DrawText("left-clk: increase; right: clear\n<R>: reset; credit @0wulfaz", 0, LayoutNSide*SquareSideLengthPx, 24, rl.Black)
+		rl.BeginDrawing()
+		drawFrame()
 
-		changed := testPattern.evolve()
+		changed := simulation.Evolve()
+		if reducedMotion {
+			// Snap to the steady state instead of animating the propagation
+			for passes := 1; changed > 0 && passes < ReducedMotionMaxPasses; passes++ {
+				changed = simulation.Evolve()
+			}
+		}
 		log.Printf("Number changed: %v\n", changed)
+		if recording != nil && !recording.Add(simulation.Snapshot()) {
+			log.Printf("Recording reached %d frames\n", GIFMaxFrames)
+			stopRecording()
+		}
 
 		rl.EndDrawing()
 	}
 
+	if recording != nil {
+		// Closing the window stops the recording like <G> does.
+		stopRecording()
+	}
 	rl.CloseWindow()
 }
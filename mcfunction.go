@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// MinecraftBlocks is the block each emission is rebuilt with in game, indexed by emission + 1: stone for blockers,
+// air for empty cells, then a block giving off each light level from 1 to 15 in Java edition. Edit it to taste;
+// levels with no convenient block use the light block.
+var MinecraftBlocks = [17]string{
+	"stone",
+	"air",
+	"small_amethyst_bud",
+	"medium_amethyst_bud",
+	"magma_block",
+	"large_amethyst_bud",
+	"amethyst_cluster",
+	"candle[candles=2,lit=true]",
+	"redstone_torch",
+	"light[level=8]",
+	"candle[candles=3,lit=true]",
+	"crying_obsidian",
+	"respawn_anchor[charges=3]",
+	"sea_pickle[pickles=3,waterlogged=true]",
+	"furnace[lit=true]",
+	"torch",
+	"glowstone",
+}
+
+// minecraftBlock returns the block for an emission, or "" if the emission is not -1 to 15.
+func minecraftBlock(emission int32) string {
+	if emission < -1 || emission > 15 {
+		return ""
+	}
+	return MinecraftBlocks[emission+1]
+}
+
+// maxFillBlocks is the most blocks a single fill command may change in game.
+const maxFillBlocks = 32768
+
+// WriteMCFunction writes the layout as a Minecraft function: fills clearing the grid's area to air, as few as the
+// maxFillBlocks limit allows, then one setblock per non-empty cell (see MinecraftBlocks). Grid x and y are world x
+// and z, relative to where the function runs, at height baseY (also relative).
+func (layout Layout) WriteMCFunction(w io.Writer, baseY int32) error {
+	buffered := bufio.NewWriter(w)
+	width, height := layout.Size()
+	// Whole rows at a time, or pieces of a row when a single one is too long.
+	fillWidth, fillRows := width, int32(maxFillBlocks)/width
+	if fillRows == 0 {
+		fillWidth, fillRows = maxFillBlocks, 1
+	}
+	for y0 := int32(0); y0 < height; y0 += fillRows {
+		y1 := y0 + fillRows - 1
+		if y1 >= height {
+			y1 = height - 1
+		}
+		for x0 := int32(0); x0 < width; x0 += fillWidth {
+			x1 := x0 + fillWidth - 1
+			if x1 >= width {
+				x1 = width - 1
+			}
+			fmt.Fprintf(buffered, "fill ~%d ~%d ~%d ~%d ~%d ~%d %s\n", x0, baseY, y0, x1, baseY, y1, minecraftBlock(0))
+		}
+	}
+	for y := int32(0); y < height; y++ {
+		for x := int32(0); x < width; x++ {
+			source := layout.Get(Point{X: x, Y: y}).Source
+			if source == 0 {
+				continue
+			}
+			block := minecraftBlock(source)
+			if block == "" {
+				return fmt.Errorf("%w: emission %d at (%d, %d) has no block", ErrOutOfRange, source, x, y)
+			}
+			fmt.Fprintf(buffered, "setblock ~%d ~%d ~%d %s\n", x, baseY, y, block)
+		}
+	}
+	return buffered.Flush()
+}
+
+// writeMCFunctionFile writes the layout as a Minecraft function (see Layout.WriteMCFunction) to the file named by
+// exportName for path, and returns the name.
+func writeMCFunctionFile(path string, layout Layout, baseY int32) (string, error) {
+	params := ExportParams{"y": fmt.Sprint(baseY)}
+	return writeExport(path, params, func(w io.Writer) error {
+		return layout.WriteMCFunction(w, baseY)
+	})
+}
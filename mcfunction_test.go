@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestWriteMCFunction(t *testing.T) {
+	layout := NewLayout(3, 2)
+	layout.SetSource(Point{X: 0, Y: 0}, 15)
+	layout.SetSource(Point{X: 2, Y: 1}, -1)
+
+	var output bytes.Buffer
+	if err := layout.WriteMCFunction(&output, 4); err != nil {
+		t.Fatal(err)
+	}
+	want := "fill ~0 ~4 ~0 ~2 ~4 ~1 air\n" +
+		"setblock ~0 ~4 ~0 glowstone\n" +
+		"setblock ~2 ~4 ~1 stone\n"
+	if output.String() != want {
+		t.Errorf("function is\n%swant\n%s", output.String(), want)
+	}
+}
+
+// Every fill stays within the game's limit, and together the fills clear each cell of the grid exactly once.
+func TestWriteMCFunctionFillChunks(t *testing.T) {
+	sizes := []struct{ width, height int32 }{
+		{1, 1}, {181, 181}, {200, 300}, {32768, 2}, {40000, 3}, {1, 70000},
+	}
+	for _, size := range sizes {
+		layout := NewLayout(size.width, size.height)
+		var output bytes.Buffer
+		if err := layout.WriteMCFunction(&output, 0); err != nil {
+			t.Fatal(err)
+		}
+
+		cleared := make([]int8, size.width*size.height)
+		scanner := bufio.NewScanner(&output)
+		for scanner.Scan() {
+			var x0, y0, x1, y1 int32
+			line := scanner.Text()
+			if _, err := fmt.Sscanf(line, "fill ~%d ~0 ~%d ~%d ~0 ~%d air", &x0, &y0, &x1, &y1); err != nil {
+				t.Fatalf("%dx%d: %q is not a fill: %v", size.width, size.height, line, err)
+			}
+			if blocks := (x1 - x0 + 1) * (y1 - y0 + 1); blocks > maxFillBlocks {
+				t.Fatalf("%dx%d: %q fills %d blocks", size.width, size.height, line, blocks)
+			}
+			for y := y0; y <= y1; y++ {
+				for x := x0; x <= x1; x++ {
+					cleared[y*size.width+x]++
+				}
+			}
+		}
+		for i, times := range cleared {
+			if times != 1 {
+				t.Fatalf("%dx%d: (%d, %d) is cleared %d times", size.width, size.height,
+					int32(i)%size.width, int32(i)/size.width, times)
+			}
+		}
+	}
+}
+
+func TestWriteMCFunctionUnknownEmission(t *testing.T) {
+	layout := NewLayout(2, 1)
+	layout.SetSource(Point{X: 1, Y: 0}, 16)
+	err := layout.WriteMCFunction(&bytes.Buffer{}, 0)
+	if err == nil || !strings.Contains(err.Error(), "(1, 0)") {
+		t.Errorf("got error %v, want one naming (1, 0)", err)
+	}
+}
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"github.com/gen2brain/raylib-go/raylib"
+)
+
+// MenuItem is an entry of a Menu. An item is either a separator, a submenu (if Submenu is not empty) or an action.
+type MenuItem struct {
+	Label     string
+	Separator bool
+	Disabled  bool
+	Submenu   []MenuItem
+	Action    func()
+}
+
+func (item MenuItem) selectable() bool {
+	return !item.Separator && !item.Disabled
+}
+
+// Menu is a popup menu with nested submenus, navigable by mouse or arrow keys.
+//
+// The navigation state is the path of highlighted items, one index per open level: path[0] is the highlighted item
+// of the top level, path[1] of the submenu opened from it, and so on. The last level is the one the arrow keys move
+// in.
+type Menu struct {
+	Items []MenuItem
+
+	// Top-left corner of the top level, in pixels.
+	X int32
+	Y int32
+
+	open bool
+	path []int
+}
+
+// IsOpen tells if the menu is shown.
+func (menu *Menu) IsOpen() bool {
+	return menu.open
+}
+
+// Open shows the menu with the given items at (x, y), highlighting the first selectable item.
+func (menu *Menu) Open(items []MenuItem, x int32, y int32) {
+	menu.Items = items
+	menu.X = x
+	menu.Y = y
+	menu.open = true
+	menu.path = []int{nextSelectable(items, -1, 1)}
+}
+
+// Close hides the menu.
+func (menu *Menu) Close() {
+	menu.open = false
+	menu.path = nil
+}
+
+// levelItems returns the items shown at the given level.
+func (menu *Menu) levelItems(level int) []MenuItem {
+	items := menu.Items
+	for _, index := range menu.path[:level] {
+		items = items[index].Submenu
+	}
+	return items
+}
+
+// Highlighted returns the highlighted item of the innermost level, if any.
+func (menu *Menu) Highlighted() (MenuItem, bool) {
+	if !menu.open {
+		return MenuItem{}, false
+	}
+	level := len(menu.path) - 1
+	index := menu.path[level]
+	if index < 0 {
+		return MenuItem{}, false
+	}
+	return menu.levelItems(level)[index], true
+}
+
+// nextSelectable returns the next selectable index after from, going in direction step and wrapping around, or -1
+// if there is none.
+func nextSelectable(items []MenuItem, from int, step int) int {
+	n := len(items)
+	for i := 1; i <= n; i++ {
+		index := ((from+step*i)%n + n) % n
+		if items[index].selectable() {
+			return index
+		}
+	}
+	return -1
+}
+
+// Move highlights the next (step = 1) or previous (step = -1) selectable item of the innermost level.
+func (menu *Menu) Move(step int) {
+	if !menu.open {
+		return
+	}
+	level := len(menu.path) - 1
+	items := menu.levelItems(level)
+	from := menu.path[level]
+	if from < 0 && step < 0 {
+		// Nothing highlighted yet: going up starts from the bottom.
+		from = len(items)
+	}
+	menu.path[level] = nextSelectable(items, from, step)
+}
+
+// Enter activates the highlighted item: a submenu is opened, an action is run and the menu is closed.
+func (menu *Menu) Enter() {
+	item, ok := menu.Highlighted()
+	if !ok || !item.selectable() {
+		return
+	}
+	if len(item.Submenu) > 0 {
+		menu.path = append(menu.path, nextSelectable(item.Submenu, -1, 1))
+		return
+	}
+	menu.Close()
+	if item.Action != nil {
+		item.Action()
+	}
+}
+
+// Back closes the innermost submenu, or the whole menu at the top level.
+func (menu *Menu) Back() {
+	if len(menu.path) <= 1 {
+		menu.Close()
+		return
+	}
+	menu.path = menu.path[:len(menu.path)-1]
+}
+
+// Hover highlights the item at index of the given level, closing any deeper submenus.
+// A submenu under the cursor is opened right away.
+func (menu *Menu) Hover(level int, index int) {
+	if !menu.open || level >= len(menu.path) {
+		return
+	}
+	item := menu.levelItems(level)[index]
+	if !item.selectable() {
+		return
+	}
+	menu.path = append(menu.path[:level], index)
+	if len(item.Submenu) > 0 {
+		menu.path = append(menu.path, -1)
+	}
+}
+
+// Layout of the menu boxes, in pixels.
+const (
+	MenuFontPx        = int32(16)
+	MenuItemHeightPx  = int32(22)
+	MenuSeparatorPx   = int32(7)
+	MenuPaddingPx     = int32(6)
+	menuSubmenuMarker = " >"
+)
+
+func menuItemHeight(item MenuItem) int32 {
+	if item.Separator {
+		return MenuSeparatorPx
+	}
+	return MenuItemHeightPx
+}
+
+// levelSize returns the size of the box of the given level, in pixels.
+func (menu *Menu) levelSize(level int) (width int32, height int32) {
+	for _, item := range menu.levelItems(level) {
+		label := item.Label
+		if len(item.Submenu) > 0 {
+			label += menuSubmenuMarker
+		}
+		if w := rl.MeasureText(label, MenuFontPx) + 2*MenuPaddingPx; w > width {
+			width = w
+		}
+		height += menuItemHeight(item)
+	}
+	return width, height
+}
+
+// levelRect returns the box of the given level. Submenus open to the right of the item they come from (or to the
+// left, if there is no room), and every box is kept on screen.
+func (menu *Menu) levelRect(level int) rl.Rectangle {
+	screenWidth := int32(rl.GetScreenWidth())
+	screenHeight := int32(rl.GetScreenHeight())
+
+	var rect rl.Rectangle
+	for l := 0; l <= level; l++ {
+		width, height := menu.levelSize(l)
+
+		x := menu.X
+		y := menu.Y
+		if l > 0 {
+			x = int32(rect.X + rect.Width)
+			if x+width > screenWidth {
+				x = int32(rect.X) - width
+			}
+			y = int32(rect.Y)
+			for _, item := range menu.levelItems(l - 1)[:menu.path[l-1]] {
+				y += menuItemHeight(item)
+			}
+		}
+
+		x = int32Max(0, x-int32Max(0, x+width-screenWidth))
+		y = int32Max(0, y-int32Max(0, y+height-screenHeight))
+		rect = rl.NewRectangle(float32(x), float32(y), float32(width), float32(height))
+	}
+	return rect
+}
+
+// itemAt returns the level and index of the item under (x, y), or ok = false if there is none.
+func (menu *Menu) itemAt(x int32, y int32) (level int, index int, ok bool) {
+	// Deeper levels are drawn on top.
+	for level = len(menu.path) - 1; level >= 0; level-- {
+		rect := menu.levelRect(level)
+		if !rl.CheckCollisionPointRec(rl.NewVector2(float32(x), float32(y)), rect) {
+			continue
+		}
+		top := int32(rect.Y)
+		for index, item := range menu.levelItems(level) {
+			top += menuItemHeight(item)
+			if y < top {
+				return level, index, true
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// Update handles the mouse and keyboard while the menu is open.
+func (menu *Menu) Update() {
+	if !menu.open {
+		return
+	}
+
+	switch {
+	case rl.IsKeyPressed(rl.KeyDown):
+		menu.Move(1)
+	case rl.IsKeyPressed(rl.KeyUp):
+		menu.Move(-1)
+	case rl.IsKeyPressed(rl.KeyRight), rl.IsKeyPressed(rl.KeyEnter):
+		menu.Enter()
+	case rl.IsKeyPressed(rl.KeyLeft), rl.IsKeyPressed(rl.KeyEscape):
+		menu.Back()
+	}
+	if !menu.open {
+		return
+	}
+
+	mouseX := rl.GetMouseX()
+	mouseY := rl.GetMouseY()
+	level, index, hit := menu.itemAt(mouseX, mouseY)
+	if hit && (rl.GetMouseDelta().X != 0 || rl.GetMouseDelta().Y != 0) {
+		menu.Hover(level, index)
+	}
+
+	if rl.IsMouseButtonPressed(rl.MouseLeftButton) || rl.IsMouseButtonPressed(rl.MouseRightButton) {
+		if !hit {
+			// Clicking anywhere else dismisses the menu.
+			menu.Close()
+			return
+		}
+		item := menu.levelItems(level)[index]
+		if !item.selectable() {
+			return
+		}
+		menu.Hover(level, index)
+		if len(item.Submenu) == 0 {
+			menu.Enter()
+		}
+	}
+}
+
+// raylibDraw draws every open level of the menu.
+func (menu *Menu) raylibDraw() {
+	if !menu.open {
+		return
+	}
+
+	for level := range menu.path {
+		rect := menu.levelRect(level)
+		x := int32(rect.X)
+		y := int32(rect.Y)
+		width := int32(rect.Width)
+
+		rl.DrawRectangle(x, y, width, int32(rect.Height), rl.RayWhite)
+
+		for index, item := range menu.levelItems(level) {
+			height := menuItemHeight(item)
+			if item.Separator {
+				rl.DrawLine(x+MenuPaddingPx, y+height/2, x+width-MenuPaddingPx, y+height/2, rl.Gray)
+				y += height
+				continue
+			}
+
+			if menu.path[level] == index {
+				rl.DrawRectangle(x, y, width, height, rl.SkyBlue)
+			}
+
+			textColor := rl.Black
+			if item.Disabled {
+				textColor = rl.Gray
+			}
+			label := item.Label
+			if len(item.Submenu) > 0 {
+				label += menuSubmenuMarker
+			}
+			rl.DrawText(label, x+MenuPaddingPx, y+(height-MenuFontPx)/2, MenuFontPx, textColor)
+			y += height
+		}
+
+		rl.DrawRectangleLines(int32(rect.X), int32(rect.Y), width, int32(rect.Height), rl.Black)
+	}
+}
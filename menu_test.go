@@ -0,0 +1,150 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// testMenu returns the items of a menu recording the actions run in ran:
+//
+//	Copy
+//	Paste (disabled)
+//	---
+//	Light > 1, 15, ---, Off (disabled)
+//	Delete
+func testMenu(ran *[]string) []MenuItem {
+	action := func(name string) func() {
+		return func() { *ran = append(*ran, name) }
+	}
+	return []MenuItem{
+		{Label: "Copy", Action: action("copy")},
+		{Label: "Paste", Disabled: true, Action: action("paste")},
+		{Separator: true},
+		{Label: "Light", Submenu: []MenuItem{
+			{Label: "1", Action: action("light 1")},
+			{Label: "15", Action: action("light 15")},
+			{Separator: true},
+			{Label: "Off", Disabled: true, Action: action("off")},
+		}},
+		{Label: "Delete", Action: action("delete")},
+	}
+}
+
+// assertPath fails the test unless the menu is open with the given path of highlighted items.
+func assertPath(t *testing.T, menu *Menu, want ...int) {
+	t.Helper()
+	if !menu.IsOpen() {
+		t.Fatalf("the menu is closed, want path %v", want)
+	}
+	if !reflect.DeepEqual(menu.path, want) {
+		t.Fatalf("path is %v, want %v", menu.path, want)
+	}
+}
+
+func TestMenuKeyboard(t *testing.T) {
+	var ran []string
+	var menu Menu
+	menu.Open(testMenu(&ran), 10, 20)
+	assertPath(t, &menu, 0)
+
+	// Disabled items and separators are skipped, both ways, and the ends wrap around.
+	menu.Move(1)
+	assertPath(t, &menu, 3)
+	menu.Move(1)
+	assertPath(t, &menu, 4)
+	menu.Move(1)
+	assertPath(t, &menu, 0)
+	menu.Move(-1)
+	assertPath(t, &menu, 4)
+
+	// Entering a submenu highlights its first item; the arrow keys then move in it only.
+	menu.Move(-1)
+	menu.Enter()
+	assertPath(t, &menu, 3, 0)
+	menu.Move(-1)
+	assertPath(t, &menu, 3, 1)
+	if item, ok := menu.Highlighted(); !ok || item.Label != "15" {
+		t.Fatalf("highlighted %q, %v; want 15", item.Label, ok)
+	}
+
+	menu.Back()
+	assertPath(t, &menu, 3)
+	menu.Enter()
+	menu.Move(1)
+	menu.Enter()
+	if menu.IsOpen() {
+		t.Error("the menu is still open after an action")
+	}
+	if want := []string{"light 15"}; !reflect.DeepEqual(ran, want) {
+		t.Errorf("ran %v, want %v", ran, want)
+	}
+}
+
+func TestMenuBackCloses(t *testing.T) {
+	var ran []string
+	var menu Menu
+	menu.Open(testMenu(&ran), 0, 0)
+	menu.Back()
+	if menu.IsOpen() {
+		t.Fatal("going back from the top level left the menu open")
+	}
+	if _, ok := menu.Highlighted(); ok {
+		t.Error("a closed menu has a highlighted item")
+	}
+
+	// Keys do nothing to a closed menu.
+	menu.Move(1)
+	menu.Enter()
+	if menu.IsOpen() || len(ran) != 0 {
+		t.Errorf("a closed menu opened or ran %v", ran)
+	}
+}
+
+func TestMenuHover(t *testing.T) {
+	var ran []string
+	var menu Menu
+	menu.Open(testMenu(&ran), 0, 0)
+
+	// Hovering a submenu opens it with nothing highlighted yet.
+	menu.Hover(0, 3)
+	assertPath(t, &menu, 3, -1)
+	if _, ok := menu.Highlighted(); ok {
+		t.Error("a freshly hovered submenu has a highlighted item")
+	}
+	menu.Enter()
+	assertPath(t, &menu, 3, -1)
+
+	// From nothing highlighted, down starts at the top and up at the bottom.
+	menu.Move(-1)
+	assertPath(t, &menu, 3, 1)
+	menu.Hover(0, 3)
+	menu.Move(1)
+	assertPath(t, &menu, 3, 0)
+
+	// Disabled items, separators and levels that are not open do not take the highlight.
+	menu.Hover(1, 3)
+	menu.Hover(1, 2)
+	menu.Hover(2, 0)
+	assertPath(t, &menu, 3, 0)
+
+	// Hovering a shallower level closes the submenus below it.
+	menu.Hover(0, 4)
+	assertPath(t, &menu, 4)
+	menu.Enter()
+	if want := []string{"delete"}; !reflect.DeepEqual(ran, want) {
+		t.Errorf("ran %v, want %v", ran, want)
+	}
+}
+
+func TestMenuNothingSelectable(t *testing.T) {
+	var menu Menu
+	menu.Open([]MenuItem{{Label: "Nothing", Disabled: true}, {Separator: true}}, 0, 0)
+	assertPath(t, &menu, -1)
+	if _, ok := menu.Highlighted(); ok {
+		t.Error("a menu with nothing selectable has a highlighted item")
+	}
+	menu.Move(1)
+	menu.Move(-1)
+	menu.Enter()
+	assertPath(t, &menu, -1)
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"github.com/gen2brain/raylib-go/raylib"
+	"time"
+)
+
+// MinSourceBudget is how long MinSourceFor may spend relighting before it gives up.
+const MinSourceBudget = 100 * time.Millisecond
+
+// connected tells if light can travel from a to b at all, around blockers. a itself may be a blocker, since
+// placing a source there replaces it.
+func (layout Layout) connected(a Point, b Point) bool {
+	seen := map[Point]bool{a: true}
+	queue := []Point{a}
+	for len(queue) > 0 {
+		point := queue[0]
+		queue = queue[1:]
+		if point == b {
+			return true
+		}
+		for _, neighbor := range point.Neighbors() {
+			// Off the grid, Get returns a blocker.
+			if layout.Get(neighbor).Source < 0 || seen[neighbor] {
+				continue
+			}
+			seen[neighbor] = true
+			queue = append(queue, neighbor)
+		}
+	}
+	return false
+}
+
+// MinSourceFor returns the smallest emission a source at `at` needs for the cell at target to reach at least
+// minLevel, with every other source as it is. It is 0 if target is bright enough without one.
+//
+// Since the target's level only grows with the emission, the answer is binary-searched, each candidate being
+// relit on a clone within the candidate's reach only.
+func (layout Layout) MinSourceFor(at Point, target Point, minLevel int32) (int32, error) {
+	if !layout.Contains(at) {
+		return 0, fmt.Errorf("%w: source position %v", ErrOutOfBounds, at)
+	}
+	if !layout.Contains(target) {
+		return 0, fmt.Errorf("%w: target %v", ErrOutOfBounds, target)
+	}
+	if layout.Get(target).Source < 0 {
+		return 0, fmt.Errorf("%w: target %v is a blocker and never lit", ErrOutOfRange, target)
+	}
+	if minLevel > 15 {
+		return 0, fmt.Errorf("%w: level %d is above the maximum of 15", ErrOutOfRange, minLevel)
+	}
+
+	deadline := time.Now().Add(MinSourceBudget)
+	errTimeout := fmt.Errorf("%w: relighting took longer than %v", ErrNotConverged, MinSourceBudget)
+
+	// Lit without the candidate position contributing anything.
+	base := Layout{layout.Clone()}
+	baseCell, _ := base.At(at)
+	baseCell.Source = 0
+	if !base.RelightAll(deadline) {
+		return 0, errTimeout
+	}
+	if base.Get(target).Level >= minLevel {
+		return 0, nil
+	}
+
+	levelWith := func(source int32) (int32, bool) {
+		clone := base.Clone()
+		cell, _ := clone.At(at)
+		cell.Source = source
+		ok := clone.RelightAround(at, source, deadline)
+		return clone.Get(target).Level, ok
+	}
+
+	best, ok := levelWith(15)
+	if !ok {
+		return 0, errTimeout
+	}
+	if best < minLevel {
+		if !base.connected(at, target) {
+			return 0, fmt.Errorf("%w: blockers cut %v off from %v", ErrOutOfRange, target, at)
+		}
+		return 0, fmt.Errorf("%w: %v is too far from %v for level %d: even emission 15 only gets it to level %d",
+			ErrOutOfRange, target, at, minLevel, best)
+	}
+
+	// Invariant: low does not light target enough, high does.
+	low, high := int32(0), int32(15)
+	for high-low > 1 {
+		middle := (low + high) / 2
+		level, ok := levelWith(middle)
+		if !ok {
+			return 0, errTimeout
+		}
+		if level >= minLevel {
+			high = middle
+		} else {
+			low = middle
+		}
+	}
+	return high, nil
+}
+
+// raylibDrawMinSourceTarget outlines the cell picked as the target of a minimal source search.
+func raylibDrawMinSourceTarget(target Point) {
+	x := target.X * SquareSideLengthPx
+	y := target.Y * SquareSideLengthPx
+	rl.DrawRectangleLines(x, y, SquareSideLengthPx, SquareSideLengthPx, rl.Red)
+	rl.DrawRectangleLines(x+1, y+1, SquareSideLengthPx-2, SquareSideLengthPx-2, rl.Red)
+}
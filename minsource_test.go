@@ -0,0 +1,147 @@
+package main
+
+import (
+	"errors"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestMinSourceForBoundaries(t *testing.T) {
+	layout := NewLayout(20, 3)
+	at := Point{X: 0, Y: 1}
+	tests := []struct {
+		target   Point
+		minLevel int32
+		want     int32
+	}{
+		// Light drops by one per cell, so the answer is the distance plus the level, up to 15.
+		{Point{X: 5, Y: 1}, 10, 15},
+		{Point{X: 5, Y: 1}, 9, 14},
+		{Point{X: 5, Y: 1}, 1, 6},
+		{Point{X: 0, Y: 1}, 15, 15},
+		{Point{X: 0, Y: 1}, 1, 1},
+		{Point{X: 14, Y: 1}, 1, 15},
+		{Point{X: 3, Y: 0}, 7, 11},
+		// Nothing to do.
+		{Point{X: 5, Y: 1}, 0, 0},
+	}
+	for _, test := range tests {
+		got, err := layout.MinSourceFor(at, test.target, test.minLevel)
+		if err != nil || got != test.want {
+			t.Errorf("MinSourceFor(%v, %v, %d) = %d, %v; want %d", at, test.target, test.minLevel, got, err, test.want)
+		}
+	}
+
+	// One cell further than emission 15 reaches.
+	_, err := layout.MinSourceFor(at, Point{X: 15, Y: 1}, 1)
+	if !errors.Is(err, ErrOutOfRange) || !strings.Contains(err.Error(), "too far") {
+		t.Errorf("a target out of reach gave %v, want a too far error", err)
+	}
+	if _, err := layout.MinSourceFor(at, Point{X: 5, Y: 1}, 11); err == nil {
+		t.Error("a level out of reach at that distance gave no error")
+	}
+}
+
+// Other sources count: only the light they leave missing has to come from the new one.
+func TestMinSourceForWithOtherSources(t *testing.T) {
+	layout := NewLayout(20, 1)
+	layout.SetSource(Point{X: 10, Y: 0}, 10)
+	layout.Converge(ConvergeMaxIterations)
+
+	at, target := Point{X: 0, Y: 0}, Point{X: 5, Y: 0}
+	if got, err := layout.MinSourceFor(at, target, 5); err != nil || got != 0 {
+		t.Errorf("target already at level 5: got %d, %v; want 0", got, err)
+	}
+	if got, err := layout.MinSourceFor(at, target, 8); err != nil || got != 13 {
+		t.Errorf("target at level 5, wanting 8: got %d, %v; want 13", got, err)
+	}
+
+	// A source already at the candidate position does not count towards the answer.
+	layout.SetSource(at, 15)
+	layout.Converge(ConvergeMaxIterations)
+	if got, err := layout.MinSourceFor(at, target, 8); err != nil || got != 13 {
+		t.Errorf("with a source in place: got %d, %v; want 13", got, err)
+	}
+}
+
+func TestMinSourceForBlockedPath(t *testing.T) {
+	layout := NewLayout(9, 5)
+	for y := int32(0); y < 5; y++ {
+		layout.SetSource(Point{X: 4, Y: y}, -1)
+	}
+	at, target := Point{X: 2, Y: 2}, Point{X: 6, Y: 2}
+	_, err := layout.MinSourceFor(at, target, 1)
+	if !errors.Is(err, ErrOutOfRange) || !strings.Contains(err.Error(), "cut") {
+		t.Errorf("a walled-off target gave %v, want a cut off error", err)
+	}
+
+	// With a gap at the bottom, light goes around: 2 down, 4 across and 2 up.
+	layout.SetSource(Point{X: 4, Y: 4}, 0)
+	if got, err := layout.MinSourceFor(at, target, 3); err != nil || got != 11 {
+		t.Errorf("through the gap: got %d, %v; want 11", got, err)
+	}
+
+	// A blocker at the candidate position is replaced by the source.
+	if got, err := layout.MinSourceFor(Point{X: 4, Y: 2}, target, 3); err != nil || got != 5 {
+		t.Errorf("from inside the wall: got %d, %v; want 5", got, err)
+	}
+}
+
+func TestMinSourceForInvalid(t *testing.T) {
+	layout := NewLayout(5, 5)
+	layout.SetSource(Point{X: 3, Y: 3}, -1)
+	if _, err := layout.MinSourceFor(Point{X: 5, Y: 0}, Point{X: 1, Y: 1}, 1); !errors.Is(err, ErrOutOfBounds) {
+		t.Errorf("source off the grid gave %v, want ErrOutOfBounds", err)
+	}
+	if _, err := layout.MinSourceFor(Point{X: 0, Y: 0}, Point{X: -1, Y: 1}, 1); !errors.Is(err, ErrOutOfBounds) {
+		t.Errorf("target off the grid gave %v, want ErrOutOfBounds", err)
+	}
+	if _, err := layout.MinSourceFor(Point{X: 0, Y: 0}, Point{X: 1, Y: 1}, 16); !errors.Is(err, ErrOutOfRange) {
+		t.Errorf("level 16 gave %v, want ErrOutOfRange", err)
+	}
+	if _, err := layout.MinSourceFor(Point{X: 0, Y: 0}, Point{X: 3, Y: 3}, 1); !errors.Is(err, ErrOutOfRange) {
+		t.Errorf("a blocker as the target gave %v, want ErrOutOfRange", err)
+	}
+}
+
+// The search gives what trying every emission in turn would.
+func TestMinSourceForMatchesLinearSearch(t *testing.T) {
+	rng := rand.New(rand.NewSource(243))
+	for round := 0; round < 100; round++ {
+		layout := NewLayout(12, 12)
+		for i := 0; i < 30; i++ {
+			layout.SetSource(Point{X: rng.Int31n(12), Y: rng.Int31n(12)}, -1)
+		}
+		for i := 0; i < 2; i++ {
+			layout.SetSource(Point{X: rng.Int31n(12), Y: rng.Int31n(12)}, 1+rng.Int31n(15))
+		}
+		layout.Converge(ConvergeMaxIterations)
+		at := Point{X: rng.Int31n(12), Y: rng.Int31n(12)}
+		target := Point{X: rng.Int31n(12), Y: rng.Int31n(12)}
+		if layout.Get(target).Source < 0 {
+			continue
+		}
+		minLevel := rng.Int31n(16)
+
+		want := int32(-1)
+		for source := int32(0); source <= 15 && want < 0; source++ {
+			tried := Layout{layout.Clone()}
+			tried.SetSource(at, source)
+			tried.Converge(ConvergeMaxIterations)
+			if tried.Get(target).Level >= minLevel {
+				want = source
+			}
+		}
+
+		got, err := layout.MinSourceFor(at, target, minLevel)
+		if want < 0 {
+			if err == nil {
+				t.Fatalf("round %d: %v from %v at level %d gave %d, want an error", round, target, at, minLevel, got)
+			}
+		} else if err != nil || got != want {
+			t.Fatalf("round %d: %v from %v at level %d gave %d, %v; want %d",
+				round, target, at, minLevel, got, err, want)
+		}
+	}
+}
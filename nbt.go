@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// NBT tag types, as numbered in the format.
+const (
+	nbtEnd = iota
+	nbtByte
+	nbtShort
+	nbtInt
+	nbtLong
+	nbtFloat
+	nbtDouble
+	nbtByteArray
+	nbtString
+	nbtList
+	nbtCompound
+	nbtIntArray
+	nbtLongArray
+)
+
+// NBTMaxDepth is how deeply lists and compounds may nest, as in game.
+const NBTMaxDepth = 512
+
+// nbtReader reads uncompressed, big-endian NBT (Minecraft's Named Binary Tag format) into plain Go values:
+// int8, int16, int32, int64, float32, float64, []byte, string, []interface{}, map[string]interface{}, []int32
+// and []int64 for the tag types in order.
+type nbtReader struct {
+	r *bufio.Reader
+}
+
+// readNBT reads the root tag, which must be a compound, returning its name and its value.
+func readNBT(r io.Reader) (string, map[string]interface{}, error) {
+	reader := nbtReader{r: bufio.NewReader(r)}
+	tagType, err := reader.r.ReadByte()
+	if err != nil {
+		return "", nil, reader.wrap(err)
+	}
+	if tagType != nbtCompound {
+		return "", nil, fmt.Errorf("%w: NBT root is tag type %d, not a compound", ErrMalformedInput, tagType)
+	}
+	name, err := reader.readString()
+	if err != nil {
+		return "", nil, err
+	}
+	value, err := reader.readPayload(nbtCompound, 0)
+	if err != nil {
+		return "", nil, err
+	}
+	return name, value.(map[string]interface{}), nil
+}
+
+// wrap turns running out of input into ErrMalformedInput, passing other errors (and nil) through.
+func (reader nbtReader) wrap(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return fmt.Errorf("%w: NBT data ends early", ErrMalformedInput)
+	}
+	return err
+}
+
+func (reader nbtReader) read(n int) ([]byte, error) {
+	buffer := make([]byte, n)
+	if _, err := io.ReadFull(reader.r, buffer); err != nil {
+		return nil, reader.wrap(err)
+	}
+	return buffer, nil
+}
+
+// readLength reads the int32 length of an array or list, which must not be negative.
+func (reader nbtReader) readLength() (int, error) {
+	buffer, err := reader.read(4)
+	if err != nil {
+		return 0, err
+	}
+	length := int32(binary.BigEndian.Uint32(buffer))
+	if length < 0 {
+		return 0, fmt.Errorf("%w: NBT length %d", ErrMalformedInput, length)
+	}
+	return int(length), nil
+}
+
+func (reader nbtReader) readString() (string, error) {
+	buffer, err := reader.read(2)
+	if err != nil {
+		return "", err
+	}
+	// Modified UTF-8, which matches UTF-8 for anything a block name holds.
+	text, err := reader.read(int(binary.BigEndian.Uint16(buffer)))
+	return string(text), err
+}
+
+// readPayload reads the value of a tag of the given type, nested depth lists and compounds deep. Arrays and lists
+// grow as their elements arrive, so a corrupt length runs out of input instead of allocating it all upfront.
+func (reader nbtReader) readPayload(tagType byte, depth int) (interface{}, error) {
+	if depth > NBTMaxDepth {
+		return nil, fmt.Errorf("%w: NBT nested deeper than %d", ErrMalformedInput, NBTMaxDepth)
+	}
+	switch tagType {
+	case nbtByte:
+		value, err := reader.r.ReadByte()
+		return int8(value), reader.wrap(err)
+	case nbtShort:
+		buffer, err := reader.read(2)
+		if err != nil {
+			return nil, err
+		}
+		return int16(binary.BigEndian.Uint16(buffer)), nil
+	case nbtInt, nbtFloat:
+		buffer, err := reader.read(4)
+		if err != nil {
+			return nil, err
+		}
+		if tagType == nbtFloat {
+			return math.Float32frombits(binary.BigEndian.Uint32(buffer)), nil
+		}
+		return int32(binary.BigEndian.Uint32(buffer)), nil
+	case nbtLong, nbtDouble:
+		buffer, err := reader.read(8)
+		if err != nil {
+			return nil, err
+		}
+		if tagType == nbtDouble {
+			return math.Float64frombits(binary.BigEndian.Uint64(buffer)), nil
+		}
+		return int64(binary.BigEndian.Uint64(buffer)), nil
+	case nbtByteArray:
+		length, err := reader.readLength()
+		if err != nil {
+			return nil, err
+		}
+		var array bytes.Buffer
+		if _, err := io.CopyN(&array, reader.r, int64(length)); err != nil {
+			return nil, reader.wrap(err)
+		}
+		return array.Bytes(), nil
+	case nbtString:
+		return reader.readString()
+	case nbtList:
+		elementType, err := reader.r.ReadByte()
+		if err != nil {
+			return nil, reader.wrap(err)
+		}
+		length, err := reader.readLength()
+		if err != nil {
+			return nil, err
+		}
+		list := []interface{}{}
+		for i := 0; i < length; i++ {
+			element, err := reader.readPayload(elementType, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, element)
+		}
+		return list, nil
+	case nbtCompound:
+		compound := map[string]interface{}{}
+		for {
+			childType, err := reader.r.ReadByte()
+			if err != nil {
+				return nil, reader.wrap(err)
+			}
+			if childType == nbtEnd {
+				return compound, nil
+			}
+			name, err := reader.readString()
+			if err != nil {
+				return nil, err
+			}
+			if compound[name], err = reader.readPayload(childType, depth+1); err != nil {
+				return nil, err
+			}
+		}
+	case nbtIntArray:
+		length, err := reader.readLength()
+		if err != nil {
+			return nil, err
+		}
+		array := []int32{}
+		for i := 0; i < length; i++ {
+			buffer, err := reader.read(4)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, int32(binary.BigEndian.Uint32(buffer)))
+		}
+		return array, nil
+	case nbtLongArray:
+		length, err := reader.readLength()
+		if err != nil {
+			return nil, err
+		}
+		array := []int64{}
+		for i := 0; i < length; i++ {
+			buffer, err := reader.read(8)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, int64(binary.BigEndian.Uint64(buffer)))
+		}
+		return array, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown NBT tag type %d", ErrMalformedInput, tagType)
+	}
+}
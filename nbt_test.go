@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadNBT(t *testing.T) {
+	data := []byte{
+		nbtCompound, 0, 4, 'r', 'o', 'o', 't',
+		nbtShort, 0, 1, 's', 0x01, 0x02,
+		nbtString, 0, 1, 'n', 0, 2, 'h', 'i',
+		nbtList, 0, 1, 'l', nbtInt, 0, 0, 0, 2, 0, 0, 0, 7, 0xff, 0xff, 0xff, 0xff,
+		nbtByteArray, 0, 1, 'b', 0, 0, 0, 3, 1, 2, 3,
+		nbtCompound, 0, 1, 'c',
+		nbtLongArray, 0, 1, 'a', 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 9,
+		nbtEnd,
+		nbtEnd,
+	}
+	name, root, err := readNBT(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"s": int16(0x0102),
+		"n": "hi",
+		"l": []interface{}{int32(7), int32(-1)},
+		"b": []byte{1, 2, 3},
+		"c": map[string]interface{}{"a": []int64{9}},
+	}
+	if name != "root" || !reflect.DeepEqual(root, want) {
+		t.Errorf("read %q: %#v, want \"root\": %#v", name, root, want)
+	}
+}
+
+// nested returns a root compound holding depth lists, each in the one before, around an empty compound: the
+// compound is nested depth+1 deep.
+func nested(depth int) []byte {
+	data := []byte{nbtCompound, 0, 0, nbtList, 0, 1, 'x'}
+	for i := 1; i < depth; i++ {
+		data = append(data, nbtList, 0, 0, 0, 1)
+	}
+	data = append(data, nbtCompound, 0, 0, 0, 1, nbtEnd)
+	for i := 0; i <= depth; i++ {
+		data = append(data, nbtEnd)
+	}
+	return data
+}
+
+func TestReadNBTMalformed(t *testing.T) {
+	// Right at the nesting limit, the structure is fine; one deeper is not.
+	if _, _, err := readNBT(bytes.NewReader(nested(NBTMaxDepth - 1))); err != nil {
+		t.Fatalf("nesting %d deep gave %v", NBTMaxDepth, err)
+	}
+	_, _, err := readNBT(bytes.NewReader(nested(NBTMaxDepth)))
+	if !errors.Is(err, ErrMalformedInput) || !strings.Contains(err.Error(), "deeper") {
+		t.Errorf("nesting %d deep gave %v, want ErrMalformedInput for the depth", NBTMaxDepth+1, err)
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", []byte{}},
+		{"truncated name", []byte{nbtCompound, 0, 4, 'r', 'o'}},
+		{"truncated int", []byte{nbtCompound, 0, 0, nbtInt, 0, 1, 'i', 0, 0}},
+		{"no end", []byte{nbtCompound, 0, 0, nbtByte, 0, 1, 'b', 1}},
+		{"truncated byte array", []byte{nbtCompound, 0, 0, nbtByteArray, 0, 1, 'b', 0, 0, 0, 9, 1, 2}},
+		{"negative byte array length", []byte{nbtCompound, 0, 0, nbtByteArray, 0, 1, 'b', 0xff, 0xff, 0xff, 0xfe}},
+		{"negative list length", []byte{nbtCompound, 0, 0, nbtList, 0, 1, 'l', nbtInt, 0x80, 0, 0, 0}},
+		{"negative int array length", []byte{nbtCompound, 0, 0, nbtIntArray, 0, 1, 'a', 0xff, 0xff, 0xff, 0xff}},
+		{"huge list length", []byte{nbtCompound, 0, 0, nbtList, 0, 1, 'l', nbtLong, 0x7f, 0xff, 0xff, 0xff, 0, 0}},
+		{"unknown tag", []byte{nbtCompound, 0, 0, 13, 0, 1, 'x', nbtEnd}},
+		{"list root", []byte{nbtList, 0, 0, nbtEnd, 0, 0, 0, 0}},
+	}
+	for _, test := range tests {
+		if _, _, err := readNBT(bytes.NewReader(test.data)); !errors.Is(err, ErrMalformedInput) {
+			t.Errorf("%s: got %v, want ErrMalformedInput", test.name, err)
+		}
+	}
+}
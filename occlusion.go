@@ -0,0 +1,255 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// OcclusionBudget is how long computing the occlusion of a single blocker may take.
+const OcclusionBudget = 100 * time.Millisecond
+
+// BlockerGain is how much light the grid would gain if the blocker at Point were removed: the sum, over every
+// cell, of how many levels brighter it would get once the light settled.
+type BlockerGain struct {
+	Point Point
+	Gain  int64
+}
+
+// occlusionBase is the layout lit to its steady state, which gains are measured against.
+func (layout Layout) occlusionBase() Layout {
+	base := Layout{layout.Clone()}
+	base.PropagateBFS()
+	return base
+}
+
+// blockerGain removes the blocker at p from scratch, a copy of base, relights around it and sums the gain. A removed
+// blocker lets in at most level 14, so nothing beyond 15 cells away can change. scratch is restored before returning.
+func blockerGain(base Layout, scratch Layout, p Point, deadline time.Time) (int64, error) {
+	width, _ := base.Size()
+	cells, baseCells := scratch.Cells(), base.Cells()
+	const radius = 15
+
+	cells[p.Y*width+p.X].Source = 0
+	ok := scratch.RelightAround(p, radius, deadline)
+
+	gain := int64(0)
+	for dy := int32(-radius); dy <= radius; dy++ {
+		for dx := -radius + int32Abs(dy); dx <= radius-int32Abs(dy); dx++ {
+			point := Point{X: p.X + dx, Y: p.Y + dy}
+			if !scratch.Contains(point) {
+				continue
+			}
+			i := point.Y*width + point.X
+			gain += int64(cells[i].Level - baseCells[i].Level)
+			cells[i] = baseCells[i]
+		}
+	}
+	if !ok {
+		return 0, fmt.Errorf("%w: relighting around %v took longer than %v", ErrNotConverged, p, OcclusionBudget)
+	}
+	return gain, nil
+}
+
+// OcclusionGain returns how much light the grid would gain without the blocker at p (see BlockerGain).
+func (layout Layout) OcclusionGain(p Point) (int64, error) {
+	cell, exists := layout.At(p)
+	if !exists {
+		return 0, fmt.Errorf("%w: %v", ErrOutOfBounds, p)
+	}
+	if cell.Source >= 0 {
+		return 0, fmt.Errorf("%w: %v is not a blocker", ErrOutOfRange, p)
+	}
+	base := layout.occlusionBase()
+	return blockerGain(base, Layout{base.Clone()}, p, time.Now().Add(OcclusionBudget))
+}
+
+// OcclusionRanking computes the gain of every blocker in region, most occluding first (then row by row), relighting
+// with at most workers at a time. progress, if not nil, is called from the workers after each blocker with the
+// number done so far and the total.
+func (layout Layout) OcclusionRanking(region Rect, workers int, progress func(done, total int)) ([]BlockerGain, error) {
+	if workers < 1 {
+		return nil, fmt.Errorf("%w: %d workers", ErrOutOfRange, workers)
+	}
+	blockers := []Point{}
+	for y := region.Y; y < region.Y+region.Height; y++ {
+		for x := region.X; x < region.X+region.Width; x++ {
+			if cell, exists := layout.At(Point{X: x, Y: y}); exists && cell.Source < 0 {
+				blockers = append(blockers, Point{X: x, Y: y})
+			}
+		}
+	}
+
+	base := layout.occlusionBase()
+	gains := make([]BlockerGain, len(blockers))
+	errs := make([]error, len(blockers))
+	var next, done int32
+	var wait sync.WaitGroup
+	for w := 0; w < workers && w < len(blockers); w++ {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			scratch := Layout{base.Clone()}
+			for {
+				i := int(atomic.AddInt32(&next, 1) - 1)
+				if i >= len(blockers) {
+					return
+				}
+				gain, err := blockerGain(base, scratch, blockers[i], time.Now().Add(OcclusionBudget))
+				gains[i], errs[i] = BlockerGain{Point: blockers[i], Gain: gain}, err
+				if progress != nil {
+					progress(int(atomic.AddInt32(&done, 1)), len(blockers))
+				}
+			}
+		}()
+	}
+	wait.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	// Blockers are listed row by row, which a stable sort keeps for equal gains.
+	sort.SliceStable(gains, func(i, j int) bool { return gains[i].Gain > gains[j].Gain })
+	return gains, nil
+}
+
+// WriteOcclusionCSV writes one "x,y,gain" row per blocker, after a header.
+func WriteOcclusionCSV(w io.Writer, gains []BlockerGain) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"x", "y", "gain"}); err != nil {
+		return err
+	}
+	for _, gain := range gains {
+		record := []string{
+			strconv.Itoa(int(gain.Point.X)),
+			strconv.Itoa(int(gain.Point.Y)),
+			strconv.FormatInt(gain.Gain, 10),
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// sameEmitters tells if two layouts have the same size, the same emission and light cap everywhere and the same
+// cells suppressed, at the same levels, so that occlusion results computed for one hold for the other.
+func sameEmitters(a Layout, b Layout) bool {
+	aCells, bCells := a.Cells(), b.Cells()
+	if a.Width != b.Width || len(aCells) != len(bCells) {
+		return false
+	}
+	for i := range aCells {
+		if aCells[i].Source != bCells[i].Source || aCells[i].Cap != bCells[i].Cap ||
+			aCells[i].Suppressed != bCells[i].Suppressed {
+			return false
+		}
+		if aCells[i].Suppressed && aCells[i].Level != bCells[i].Level {
+			return false
+		}
+	}
+	return true
+}
+
+// OcclusionCache remembers the gains of the blockers hovered so far, for the hover badge. It forgets them as soon as
+// an emission, a light cap or a suppressed cell anywhere changes.
+type OcclusionCache struct {
+	// Copy of the layout the gains hold for
+	layout Layout
+	base   Layout
+	gains  map[Point]int64
+}
+
+// Gain returns the gain of the blocker at p in layout, computing it if it is not known yet.
+func (cache *OcclusionCache) Gain(layout Layout, p Point) (int64, error) {
+	if cache.gains == nil || !sameEmitters(cache.layout, layout) {
+		cache.layout = Layout{layout.Clone()}
+		cache.base = layout.occlusionBase()
+		cache.gains = map[Point]int64{}
+	}
+	if gain, known := cache.gains[p]; known {
+		return gain, nil
+	}
+	if cell, exists := layout.At(p); !exists || cell.Source >= 0 {
+		return 0, fmt.Errorf("%w: %v is not a blocker", ErrOutOfRange, p)
+	}
+	gain, err := blockerGain(cache.base, Layout{cache.base.Clone()}, p, time.Now().Add(OcclusionBudget))
+	if err != nil {
+		return 0, err
+	}
+	cache.gains[p] = gain
+	return gain, nil
+}
+
+// OcclusionJob runs an occlusion ranking in the background, so that the window stays responsive.
+type OcclusionJob struct {
+	// The layout as it was when the job started, to tell if the results still hold
+	layout Layout
+
+	done  int32
+	total int32
+
+	result chan occlusionResult
+}
+
+type occlusionResult struct {
+	gains []BlockerGain
+	err   error
+}
+
+// StartOcclusionJob ranks the blockers of a copy of layout within region, on at most workers goroutines.
+func StartOcclusionJob(layout Layout, region Rect, workers int) *OcclusionJob {
+	job := &OcclusionJob{layout: Layout{layout.Clone()}, result: make(chan occlusionResult, 1)}
+	go func() {
+		gains, err := job.layout.OcclusionRanking(region, workers, func(done, total int) {
+			atomic.StoreInt32(&job.total, int32(total))
+			atomic.StoreInt32(&job.done, int32(done))
+		})
+		job.result <- occlusionResult{gains: gains, err: err}
+	}()
+	return job
+}
+
+// status describes the progress of the job for the footer.
+func (job *OcclusionJob) status() string {
+	return fmt.Sprintf("occlusion %d/%d", atomic.LoadInt32(&job.done), atomic.LoadInt32(&job.total))
+}
+
+// Poll returns the ranking once the job is done, and false until then. Results for a layout whose emissions, light
+// caps or suppressed cells have changed since the job started are stale and come back as an error.
+func (job *OcclusionJob) Poll(current Layout) ([]BlockerGain, bool, error) {
+	select {
+	case result := <-job.result:
+		if result.err == nil && !sameEmitters(job.layout, current) {
+			return nil, true, fmt.Errorf("%w: the layout changed while ranking; run it again", ErrCancelled)
+		}
+		return result.gains, true, result.err
+	default:
+		return nil, false, nil
+	}
+}
+
+// raylibDrawOcclusionBadge draws a "+N light if removed" badge next to the blocker at p, if there is one.
+func (cache *OcclusionCache) raylibDrawOcclusionBadge(layout Layout, p Point) {
+	if cell, exists := layout.At(p); !exists || cell.Source >= 0 {
+		return
+	}
+	gain, err := cache.Gain(layout, p)
+	if err != nil {
+		return
+	}
+	layout.raylibDrawBadge(p, fmt.Sprintf("+%d light if removed", gain))
+}
+
+// exportOcclusionCSV writes occlusion.csv to the working directory and returns its name.
+func exportOcclusionCSV(gains []BlockerGain) (string, error) {
+	return writeExport("occlusion.csv", nil, func(w io.Writer) error { return WriteOcclusionCSV(w, gains) })
+}
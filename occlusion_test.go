@@ -0,0 +1,109 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// wallLayout is a 9x5 grid lit by a source at (1, 2), with a wall of blockers down column 4.
+func wallLayout() Layout {
+	layout := NewLayout(9, 5)
+	layout.SetSource(Point{X: 1, Y: 2}, 15)
+	for y := int32(0); y < 5; y++ {
+		layout.SetSource(Point{X: 4, Y: y}, -1)
+	}
+	layout.Converge(ConvergeMaxIterations)
+	return layout
+}
+
+// pollOcclusionJob waits for the job to finish.
+func pollOcclusionJob(t *testing.T, job *OcclusionJob, current Layout) ([]BlockerGain, error) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		if gains, done, err := job.Poll(current); done {
+			return gains, err
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("the occlusion job did not finish")
+	return nil, nil
+}
+
+func TestOcclusionCacheFollowsSuppression(t *testing.T) {
+	layout := wallLayout()
+	blocker := Point{X: 4, Y: 2}
+	cache := &OcclusionCache{}
+	before, err := cache.Gain(layout, blocker)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A suppressed cell behind the wall stays dark, and holds back the light the blocker would let through.
+	layout.Suppress(Point{X: 5, Y: 2})
+	after, err := cache.Gain(layout, blocker)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := layout.OcclusionGain(blocker)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after != want || after == before {
+		t.Errorf("gain after suppressing is %d, want %d (it was %d before)", after, want, before)
+	}
+
+	layout.Poke(Point{X: 5, Y: 2})
+	if gain, err := cache.Gain(layout, blocker); err != nil || gain != before {
+		t.Errorf("gain after poking is %d, %v; want %d", gain, err, before)
+	}
+}
+
+func TestOcclusionCacheFollowsCaps(t *testing.T) {
+	layout := wallLayout()
+	blocker := Point{X: 4, Y: 2}
+	cache := &OcclusionCache{}
+	before, err := cache.Gain(layout, blocker)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A cap right behind the wall dims everything the blocker would let through.
+	layout.SetCap(Point{X: 5, Y: 2}, 4)
+	after, err := cache.Gain(layout, blocker)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := layout.OcclusionGain(blocker)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after != want || after >= before {
+		t.Errorf("gain after capping is %d, want %d (less than the %d before)", after, want, before)
+	}
+
+	job := StartOcclusionJob(layout, layout.WholeGrid(), 2)
+	layout.SetCap(Point{X: 5, Y: 2}, 0)
+	if _, err := pollOcclusionJob(t, job, layout); !errors.Is(err, ErrCancelled) {
+		t.Errorf("a ranking for the layout before its cap was cleared came back with %v, want ErrCancelled", err)
+	}
+}
+
+func TestOcclusionJobStaleAfterSuppression(t *testing.T) {
+	layout := wallLayout()
+	job := StartOcclusionJob(layout, layout.WholeGrid(), 2)
+	layout.Suppress(Point{X: 6, Y: 1})
+	if _, err := pollOcclusionJob(t, job, layout); !errors.Is(err, ErrCancelled) {
+		t.Errorf("a ranking for the layout before it was suppressed came back with %v, want ErrCancelled", err)
+	}
+
+	job = StartOcclusionJob(layout, layout.WholeGrid(), 2)
+	gains, err := pollOcclusionJob(t, job, layout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gains) != 5 {
+		t.Errorf("ranked %d blockers, want 5", len(gains))
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"github.com/gen2brain/raylib-go/raylib"
+)
+
+// Overlay selects what cells are shaded by, instead of their light level.
+type Overlay int
+
+const (
+	OverlayNone Overlay = iota
+	OverlaySourceDistance
+	OverlayBlockerDistance
+	OverlayAge
+	OverlayBands
+)
+
+func (overlay Overlay) String() string {
+	switch overlay {
+	case OverlayNone:
+		return "none"
+	case OverlaySourceDistance:
+		return "source distance"
+	case OverlayBlockerDistance:
+		return "blocker distance"
+	case OverlayAge:
+		return "age"
+	case OverlayBands:
+		return "bands"
+	default:
+		return "unknown"
+	}
+}
+
+// colors returns the shading of every cell (indexed [y][x]), or nil for OverlayNone.
+func (overlay Overlay) colors(layout Layout) [][]rl.Color {
+	var colorOf func(x int32, y int32) rl.Color
+
+	switch overlay {
+	case OverlaySourceDistance, OverlayBlockerDistance:
+		kind := FieldSource
+		if overlay == OverlayBlockerDistance {
+			kind = FieldBlocker
+		}
+		field := layout.DistanceField(kind)
+		max := maxDistance(field)
+		colorOf = func(x int32, y int32) rl.Color {
+			gray := distanceGray(field[y][x], max)
+			return rl.NewColor(gray, gray, gray, 255)
+		}
+	case OverlayAge:
+		ages := layout.AgeField()
+		colorOf = func(x int32, y int32) rl.Color {
+			return ageColor(ages[y][x])
+		}
+	case OverlayBands:
+		colorOf = func(x int32, y int32) rl.Color {
+			cell := layout.Get(Point{X: x, Y: y})
+			i := bandOf(bands, cell.Level)
+			if cell.Source < 0 || i < 0 {
+				return rl.Blank
+			}
+			return bands[i].Color
+		}
+	default:
+		return nil
+	}
+
+	width, height := layout.Size()
+	colors := make([][]rl.Color, height)
+	for y := int32(0); y < height; y++ {
+		colors[y] = make([]rl.Color, width)
+		for x := int32(0); x < width; x++ {
+			colors[y][x] = colorOf(x, y)
+		}
+	}
+	return colors
+}
+
+// export writes the overlay's field to the working directory and returns the names of the files.
+func (overlay Overlay) export(layout Layout) ([]string, error) {
+	switch overlay {
+	case OverlaySourceDistance:
+		return layout.exportDistanceField(FieldSource)
+	case OverlayBlockerDistance:
+		return layout.exportDistanceField(FieldBlocker)
+	case OverlayAge:
+		path, err := layout.exportAge()
+		return []string{path}, err
+	default:
+		return nil, fmt.Errorf("the %v overlay has nothing to export", overlay)
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"github.com/gen2brain/raylib-go/raylib"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+)
+
+// Colors of the cells: every square is drawn in cellBackground, then filled with a translucent color whose opacity
+// follows the light level.
+var (
+	cellBackground  = rl.Gray
+	sourceFillColor = rl.Orange
+	levelFillColor  = rl.Yellow
+)
+
+// withAlpha sets the opacity of a color, like raylib's ColorAlpha (alpha is clamped to [0, 1]).
+func withAlpha(c color.RGBA, alpha float32) color.RGBA {
+	if alpha < 0 {
+		alpha = 0
+	} else if alpha > 1 {
+		alpha = 1
+	}
+	c.A = uint8(255 * alpha)
+	return c
+}
+
+// levelScale maps a light level to 0..full: 0 and below give 0, 15 and above give full. Levels are scaled for
+// display here only, in 64 bits, so that out-of-range levels saturate instead of wrapping around.
+func levelScale(level int32, full int32) int32 {
+	if level < 0 {
+		level = 0
+	} else if level > 15 {
+		level = 15
+	}
+	return int32(int64(level) * int64(full) / 15)
+}
+
+// cellFillColor is the translucent color drawn over the background of a cell: orange for sources, yellow
+// otherwise, more opaque the brighter the cell. Blockers get no fill.
+func cellFillColor(cell Cell) color.RGBA {
+	// At most 15/16 opaque, so that the background always shows through a little.
+	alpha := float32(levelScale(cell.Level, 15)) / 16
+	switch {
+	case cell.Source > 0:
+		return withAlpha(sourceFillColor, alpha)
+	case cell.Source == 0:
+		return withAlpha(levelFillColor, alpha)
+	default:
+		return rl.Blank
+	}
+}
+
+// blendOver composites a translucent color over an opaque one, the way raylib's default blending does.
+func blendOver(src color.RGBA, dst color.RGBA) color.RGBA {
+	a := uint32(src.A)
+	mix := func(s uint8, d uint8) uint8 {
+		return uint8((uint32(s)*a + uint32(d)*(255-a) + 127) / 255)
+	}
+	return color.RGBA{R: mix(src.R, dst.R), G: mix(src.G, dst.G), B: mix(src.B, dst.B), A: 255}
+}
+
+// Blending is the color space translucent fills are composited in.
+type Blending int
+
+const (
+	// BlendLinear mixes colors in linear light, so that half-opaque yellow over gray looks half way between them
+	// rather than muddy. It is the zero value, and what exports use.
+	BlendLinear Blending = iota
+
+	// BlendSRGB mixes the sRGB-encoded values directly, as raylib and most image tools do.
+	BlendSRGB
+)
+
+func (blending Blending) String() string {
+	if blending == BlendSRGB {
+		return "sRGB"
+	}
+	return "linear"
+}
+
+// liveBlending is how the window and the terminal composite cells. It starts as raylib blends, in sRGB.
+var liveBlending = BlendSRGB
+
+// srgbToLinearTable maps an sRGB-encoded channel to linear light in [0, 1].
+var srgbToLinearTable = func() (table [256]float64) {
+	for i := range table {
+		c := float64(i) / 255
+		if c <= 0.04045 {
+			table[i] = c / 12.92
+		} else {
+			table[i] = math.Pow((c+0.055)/1.055, 2.4)
+		}
+	}
+	return table
+}()
+
+// linearToSRGB encodes a linear light value in [0, 1] as an sRGB channel.
+func linearToSRGB(l float64) uint8 {
+	var c float64
+	if l <= 0.0031308 {
+		c = l * 12.92
+	} else {
+		c = 1.055*math.Pow(l, 1/2.4) - 0.055
+	}
+	return uint8(math.Round(255 * math.Max(0, math.Min(1, c))))
+}
+
+// blendOverLinear composites a translucent color over an opaque one in linear light. Alpha is coverage, so it
+// weighs the linear values, not the encoded ones.
+func blendOverLinear(src color.RGBA, dst color.RGBA) color.RGBA {
+	a := float64(src.A) / 255
+	mix := func(s uint8, d uint8) uint8 {
+		return linearToSRGB(srgbToLinearTable[s]*a + srgbToLinearTable[d]*(1-a))
+	}
+	return color.RGBA{R: mix(src.R, dst.R), G: mix(src.G, dst.G), B: mix(src.B, dst.B), A: 255}
+}
+
+// CellColor is the opaque color a cell is shown in (without its numbers).
+func CellColor(cell Cell, blending Blending) color.RGBA {
+	if blending == BlendSRGB {
+		return blendOver(cellFillColor(cell), cellBackground)
+	}
+	return blendOverLinear(cellFillColor(cell), cellBackground)
+}
+
+// BlendingSwatchPx is the side of one swatch in the blending comparison image.
+const BlendingSwatchPx = 32
+
+// BlendingComparison shows every light level of an empty cell and of a source, blended in sRGB and, right below,
+// in linear light: one swatch per level, level 0 on the left.
+func BlendingComparison() image.Image {
+	rows := []struct {
+		cell     Cell
+		blending Blending
+	}{
+		{Cell{Source: 0}, BlendSRGB},
+		{Cell{Source: 0}, BlendLinear},
+		{Cell{Source: 15}, BlendSRGB},
+		{Cell{Source: 15}, BlendLinear},
+	}
+	img := image.NewRGBA(image.Rect(0, 0, 16*BlendingSwatchPx, len(rows)*BlendingSwatchPx))
+	for row, swatch := range rows {
+		for level := int32(0); level < 16; level++ {
+			cell := swatch.cell
+			cell.Level = level
+			c := CellColor(cell, swatch.blending)
+			for y := row * BlendingSwatchPx; y < (row+1)*BlendingSwatchPx; y++ {
+				for x := int(level) * BlendingSwatchPx; x < int(level+1)*BlendingSwatchPx; x++ {
+					img.SetRGBA(x, y, c)
+				}
+			}
+		}
+	}
+	return img
+}
+
+// WriteBlendingComparisonPNG writes BlendingComparison as a PNG.
+func WriteBlendingComparisonPNG(w io.Writer) error {
+	return png.Encode(w, BlendingComparison())
+}
+
+// exportBlendingComparison writes blending.png to the working directory and returns its name.
+func exportBlendingComparison() (string, error) {
+	return writeExport("blending.png", nil, WriteBlendingComparisonPNG)
+}
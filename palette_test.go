@@ -0,0 +1,48 @@
+package main
+
+import (
+	"image/color"
+	"testing"
+)
+
+// The colors cells are shown in, pinned so that palette or blending edits cannot change them unnoticed. They are
+// raylib's gray background under its yellow (empty cells) or orange (sources) fill, at 1/16 opacity per level.
+func TestCellColor(t *testing.T) {
+	tests := []struct {
+		source int32
+		level  int32
+		srgb   color.RGBA
+		linear color.RGBA
+	}{
+		{0, 0, color.RGBA{130, 130, 130, 255}, color.RGBA{130, 130, 130, 255}},
+		{0, 1, color.RGBA{137, 137, 122, 255}, color.RGBA{141, 141, 126, 255}},
+		{0, 7, color.RGBA{184, 182, 73, 255}, color.RGBA{196, 194, 99, 255}},
+		{0, 8, color.RGBA{191, 189, 65, 255}, color.RGBA{204, 201, 94, 255}},
+		{0, 15, color.RGBA{245, 242, 8, 255}, color.RGBA{248, 244, 31, 255}},
+		{15, 1, color.RGBA{137, 132, 122, 255}, color.RGBA{142, 132, 126, 255}},
+		{15, 8, color.RGBA{192, 145, 65, 255}, color.RGBA{205, 146, 94, 255}},
+		{15, 15, color.RGBA{247, 159, 8, 255}, color.RGBA{249, 159, 31, 255}},
+		// Out-of-range levels saturate.
+		{0, 40, color.RGBA{245, 242, 8, 255}, color.RGBA{248, 244, 31, 255}},
+		{0, -3, color.RGBA{130, 130, 130, 255}, color.RGBA{130, 130, 130, 255}},
+		// Blockers get no fill, whatever their level.
+		{-1, 15, color.RGBA{130, 130, 130, 255}, color.RGBA{130, 130, 130, 255}},
+	}
+	for _, test := range tests {
+		cell := Cell{Source: test.source, Level: test.level}
+		if got := CellColor(cell, BlendSRGB); got != test.srgb {
+			t.Errorf("source %d, level %d in sRGB: got %v, want %v", test.source, test.level, got, test.srgb)
+		}
+		if got := CellColor(cell, BlendLinear); got != test.linear {
+			t.Errorf("source %d, level %d in linear light: got %v, want %v", test.source, test.level, got, test.linear)
+		}
+	}
+}
+
+func TestSRGBRoundTrip(t *testing.T) {
+	for i := 0; i < 256; i++ {
+		if got := linearToSRGB(srgbToLinearTable[i]); got != uint8(i) {
+			t.Errorf("%d encodes back as %d", i, got)
+		}
+	}
+}
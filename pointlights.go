@@ -0,0 +1,215 @@
+package main
+
+import (
+	"github.com/gen2brain/raylib-go/raylib"
+	"math"
+)
+
+// PointLight is a light source at an arbitrary (sub-cell) position, in cell units: cell (x, y) covers
+// [x, x+1) x [y, y+1).
+type PointLight struct {
+	X        float32
+	Y        float32
+	Emission int32
+}
+
+// rasterized remembers what a rasterization wrote into a cell, so that it can be taken back out.
+type rasterized struct {
+	// The cell's own emission before the point lights were applied.
+	base int32
+	// The emission the rasterization wrote.
+	written int32
+}
+
+// PointLights is a list of point lights kept alongside a Layout.
+// Before each evolve, Rasterize writes their emission into the grid's sources.
+type PointLights struct {
+	Lights []PointLight
+
+	// If set, a light's emission is split across the four nearest cells in proportion to how close it is to each
+	// of their centers. Otherwise, all of it goes to the containing cell.
+	Split bool
+
+	applied map[Point]rasterized
+
+	// Lights and Split as they were rasterized, and whether applied is in the layout at all
+	rasterizedLights []PointLight
+	rasterizedSplit  bool
+	rasterized       bool
+}
+
+// contributions computes the emission each cell receives from the point lights.
+// Overlapping lights do not add up (they don't in Minecraft either): a cell takes the strongest one, capped at 15.
+func (lights *PointLights) contributions() map[Point]int32 {
+	result := map[Point]int32{}
+
+	add := func(p Point, emission int32) {
+		if emission > 15 {
+			emission = 15
+		}
+		if emission > result[p] {
+			result[p] = emission
+		}
+	}
+
+	for _, light := range lights.Lights {
+		if !lights.Split {
+			add(Point{X: int32(math.Floor(float64(light.X))), Y: int32(math.Floor(float64(light.Y)))}, light.Emission)
+			continue
+		}
+
+		// Bilinear weights between the centers of the four nearest cells.
+		fx := float64(light.X) - 0.5
+		fy := float64(light.Y) - 0.5
+		x0 := math.Floor(fx)
+		y0 := math.Floor(fy)
+		tx := fx - x0
+		ty := fy - y0
+
+		corners := []struct {
+			dx     int32
+			dy     int32
+			weight float64
+		}{
+			{0, 0, (1 - tx) * (1 - ty)},
+			{1, 0, tx * (1 - ty)},
+			{0, 1, (1 - tx) * ty},
+			{1, 1, tx * ty},
+		}
+		for _, corner := range corners {
+			emission := int32(math.Round(float64(light.Emission) * corner.weight))
+			if emission <= 0 {
+				continue
+			}
+			add(Point{X: int32(x0) + corner.dx, Y: int32(y0) + corner.dy}, emission)
+		}
+	}
+
+	return result
+}
+
+// Unrasterize takes the previous rasterization back out of the layout.
+// A cell whose source was edited since is left as it is.
+func (lights *PointLights) Unrasterize(layout Layout) {
+	for point, entry := range lights.applied {
+		if cell, exists := layout.At(point); exists && cell.Source == entry.written {
+			layout.SetSource(point, entry.base)
+		}
+	}
+	lights.Forget()
+}
+
+// Forget drops the record of the last rasterization without touching any layout, for when the layout it was
+// written into has been replaced.
+func (lights *PointLights) Forget() {
+	lights.applied = nil
+	lights.rasterized = false
+}
+
+// Rasterize writes the point lights into the layout's sources, replacing the previous rasterization. It does nothing
+// if neither the lights nor Split changed since, and otherwise only changes the cells whose emission differs, so
+// that cells it does not touch are not marked dirty.
+// A cell keeps its own emission if it is brighter; blockers and cells outside the grid are skipped.
+func (lights *PointLights) Rasterize(layout Layout) {
+	unchanged := lights.Split == lights.rasterizedSplit && samePointLights(lights.Lights, lights.rasterizedLights)
+	if lights.rasterized && unchanged {
+		return
+	}
+	contributions := lights.contributions()
+
+	applied := map[Point]rasterized{}
+	for point, entry := range lights.applied {
+		cell, exists := layout.At(point)
+		if !exists || cell.Source != entry.written {
+			// Edited since: the cell is the user's again.
+			continue
+		}
+		if emission := contributions[point]; emission > entry.base {
+			applied[point] = rasterized{base: entry.base, written: emission}
+			layout.SetSource(point, emission)
+		} else {
+			layout.SetSource(point, entry.base)
+		}
+	}
+	for point, emission := range contributions {
+		if _, done := applied[point]; done {
+			continue
+		}
+		cell, exists := layout.At(point)
+		if !exists || cell.Source < 0 || cell.Source >= emission {
+			continue
+		}
+		applied[point] = rasterized{base: cell.Source, written: emission}
+		layout.SetSource(point, emission)
+	}
+
+	lights.applied = applied
+	lights.rasterizedLights = append(lights.rasterizedLights[:0], lights.Lights...)
+	lights.rasterizedSplit = lights.Split
+	lights.rasterized = true
+}
+
+// samePointLights tells if two lists hold the same lights in the same order.
+func samePointLights(a []PointLight, b []PointLight) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Bases returns, for each cell the point lights are rasterized into, the emission it has without them. Cells whose
+// source was edited since are left out: their emission is their own.
+func (lights *PointLights) Bases(layout Layout) map[Point]int32 {
+	bases := map[Point]int32{}
+	for point, entry := range lights.applied {
+		if cell, exists := layout.At(point); exists && cell.Source == entry.written {
+			bases[point] = entry.base
+		}
+	}
+	return bases
+}
+
+// Unrasterized returns a copy of the layout with the point lights taken back out of its sources, as it is saved:
+// point lights are not part of the grid, and would stay behind as plain sources when it is loaded again.
+func (lights *PointLights) Unrasterized(layout Layout) Layout {
+	unrasterized := Layout{layout.Clone()}
+	for point, base := range lights.Bases(layout) {
+		unrasterized.SetSource(point, base)
+	}
+	return unrasterized
+}
+
+// Nearest returns the index of the light closest to (x, y) within radius (in cells), or -1 if there is none.
+func (lights *PointLights) Nearest(x float32, y float32, radius float32) int {
+	nearest := -1
+	best := radius * radius
+	for i, light := range lights.Lights {
+		dx := light.X - x
+		dy := light.Y - y
+		if distance := dx*dx + dy*dy; distance <= best {
+			nearest = i
+			best = distance
+		}
+	}
+	return nearest
+}
+
+// Remove deletes the light at index i.
+func (lights *PointLights) Remove(i int) {
+	lights.Lights = append(lights.Lights[:i], lights.Lights[i+1:]...)
+}
+
+// raylibDraw draws a marker at each point light.
+func (lights *PointLights) raylibDraw() {
+	for _, light := range lights.Lights {
+		x := int32(light.X * float32(SquareSideLengthPx))
+		y := int32(light.Y * float32(SquareSideLengthPx))
+		rl.DrawCircle(x, y, 4, rl.Red)
+		rl.DrawCircleLines(x, y, 6, rl.Black)
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"testing"
+)
+
+// sources returns the emission of every cell, indexed y*width+x.
+func sources(layout Layout) []int32 {
+	result := []int32{}
+	for _, cell := range layout.Cells() {
+		result = append(result, cell.Source)
+	}
+	return result
+}
+
+// assertSources fails the test if the emissions of the layout are not those of want.
+func assertSources(t *testing.T, layout Layout, want Layout) {
+	t.Helper()
+	got, wanted := sources(layout), sources(want)
+	for i := range wanted {
+		if got[i] != wanted[i] {
+			width, _ := layout.Size()
+			t.Fatalf("source at (%d, %d) is %d, want %d", int32(i)%width, int32(i)/width, got[i], wanted[i])
+		}
+	}
+}
+
+// Moving a marker takes its light out of the cell it left: the grid ends up as if the light had always been where
+// it was moved to.
+func TestPointLightMove(t *testing.T) {
+	layout := NewLayout(8, 8)
+	lights := &PointLights{Lights: []PointLight{{X: 2.5, Y: 2.5, Emission: 12}}}
+	lights.Rasterize(layout)
+	layout.Converge(ConvergeMaxIterations)
+
+	moves := []PointLight{{X: 2.9, Y: 2.1, Emission: 12}, {X: 5.5, Y: 6.5, Emission: 12}, {X: 0, Y: 7.99, Emission: 9}}
+	for _, to := range moves {
+		lights.Lights[0] = to
+		lights.Rasterize(layout)
+		layout.Converge(ConvergeMaxIterations)
+
+		want := NewLayout(8, 8)
+		want.SetSource(Point{X: int32(to.X), Y: int32(to.Y)}, to.Emission)
+		want.Converge(ConvergeMaxIterations)
+		assertSources(t, layout, want)
+		if got, wanted := layout.Get(Point{X: 2, Y: 2}).Level, want.Get(Point{X: 2, Y: 2}).Level; got != wanted {
+			t.Errorf("after moving to (%v, %v), level at (2, 2) is %d, want %d", to.X, to.Y, got, wanted)
+		}
+	}
+}
+
+// A light over a cell with its own, dimmer emission gives the cell its emission back when it moves off.
+func TestPointLightMoveRestoresBase(t *testing.T) {
+	layout := NewLayout(8, 8)
+	layout.SetSource(Point{X: 2, Y: 2}, 5)
+	layout.SetSource(Point{X: 4, Y: 4}, 14)
+	lights := &PointLights{Lights: []PointLight{{X: 2.5, Y: 2.5, Emission: 12}}}
+	lights.Rasterize(layout)
+	if source := layout.Get(Point{X: 2, Y: 2}).Source; source != 12 {
+		t.Fatalf("source under the light is %d, want 12", source)
+	}
+
+	// Over a brighter source, the light changes nothing.
+	lights.Lights[0].X, lights.Lights[0].Y = 4.5, 4.5
+	lights.Rasterize(layout)
+	if source := layout.Get(Point{X: 2, Y: 2}).Source; source != 5 {
+		t.Errorf("source left behind is %d, want 5", source)
+	}
+	if source := layout.Get(Point{X: 4, Y: 4}).Source; source != 14 {
+		t.Errorf("source under the light is %d, want its own 14", source)
+	}
+
+	// A blocker is never lit.
+	layout.SetSource(Point{X: 6, Y: 6}, -1)
+	lights.Lights[0].X, lights.Lights[0].Y = 6.5, 6.5
+	lights.Rasterize(layout)
+	if source := layout.Get(Point{X: 6, Y: 6}).Source; source != -1 {
+		t.Errorf("source of a blocker under the light is %d, want -1", source)
+	}
+}
+
+// A cell edited while a light is over it belongs to the user: moving the light off leaves the edit.
+func TestPointLightMoveKeepsEdits(t *testing.T) {
+	layout := NewLayout(8, 8)
+	lights := &PointLights{Lights: []PointLight{{X: 2.5, Y: 2.5, Emission: 12}}}
+	lights.Rasterize(layout)
+	layout.SetSource(Point{X: 2, Y: 2}, 3)
+
+	lights.Lights[0].X = 6.5
+	lights.Rasterize(layout)
+	if source := layout.Get(Point{X: 2, Y: 2}).Source; source != 3 {
+		t.Errorf("edited source is %d after the light moved off, want 3", source)
+	}
+	if source := layout.Get(Point{X: 6, Y: 2}).Source; source != 12 {
+		t.Errorf("source under the light is %d, want 12", source)
+	}
+}
+
+// Split across four cells, the light moves off all of them; switching Split off goes back to one cell.
+func TestPointLightSplit(t *testing.T) {
+	layout := NewLayout(8, 8)
+	lights := &PointLights{Lights: []PointLight{{X: 3, Y: 3, Emission: 15}}, Split: true}
+	lights.Rasterize(layout)
+	for _, p := range []Point{{X: 2, Y: 2}, {X: 3, Y: 2}, {X: 2, Y: 3}, {X: 3, Y: 3}} {
+		if source := layout.Get(p).Source; source != 4 {
+			t.Errorf("split source at %v is %d, want 4", p, source)
+		}
+	}
+
+	lights.Split = false
+	lights.Rasterize(layout)
+	want := NewLayout(8, 8)
+	want.SetSource(Point{X: 3, Y: 3}, 15)
+	assertSources(t, layout, want)
+
+	lights.Split = true
+	lights.Lights[0] = PointLight{X: 6.5, Y: 1.5, Emission: 15}
+	lights.Rasterize(layout)
+	want = NewLayout(8, 8)
+	want.SetSource(Point{X: 6, Y: 1}, 15)
+	assertSources(t, layout, want)
+}
+
+// Rasterizing lights that have not changed touches nothing, so a settled grid stays settled.
+func TestPointLightRasterizeUnchanged(t *testing.T) {
+	layout := NewLayout(16, 16)
+	lights := &PointLights{Lights: []PointLight{{X: 2.5, Y: 2.5, Emission: 12}, {X: 9.2, Y: 11.7, Emission: 7}}}
+	lights.Rasterize(layout)
+	layout.Converge(ConvergeMaxIterations)
+
+	for frame := 0; frame < 3; frame++ {
+		lights.Rasterize(layout)
+		if !layout.Settled() {
+			t.Fatalf("frame %d: rasterizing unchanged lights left dirty cells", frame)
+		}
+	}
+
+	// Moving a light does rasterize it again.
+	lights.Lights[1].X = 10.2
+	lights.Rasterize(layout)
+	if layout.Settled() {
+		t.Fatal("moving a light left the grid settled")
+	}
+	layout.Converge(ConvergeMaxIterations)
+	if source := layout.Get(Point{X: 2, Y: 2}).Source; source != 12 {
+		t.Errorf("the light that did not move has source %d, want 12", source)
+	}
+
+	// Removing every light takes them all out.
+	lights.Lights = nil
+	lights.Rasterize(layout)
+	assertSources(t, layout, NewLayout(16, 16))
+}
+
+// After Forget, for a replaced layout, the lights go into the new one from scratch.
+func TestPointLightForget(t *testing.T) {
+	lights := &PointLights{Lights: []PointLight{{X: 1.5, Y: 1.5, Emission: 10}}}
+	lights.Rasterize(NewLayout(4, 4))
+
+	replaced := NewLayout(4, 4)
+	lights.Forget()
+	lights.Rasterize(replaced)
+	if source := replaced.Get(Point{X: 1, Y: 1}).Source; source != 10 {
+		t.Errorf("source in the replaced layout is %d, want 10", source)
+	}
+}
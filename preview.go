@@ -0,0 +1,53 @@
+package main
+
+import (
+	"github.com/gen2brain/raylib-go/raylib"
+	"time"
+)
+
+// PreviewBudget is how long the placement preview may take per frame before it gives up.
+const PreviewBudget = 3 * time.Millisecond
+
+// PlacementPreview returns the cells whose light level would change if the cell at p emitted source, with the
+// level each would get. It works on a clone, relighting only the region the new source can reach.
+// It returns false if p is not in the grid or the relight did not fit in the budget.
+func (layout Layout) PlacementPreview(p Point, source int32, budget time.Duration) (map[Point]int32, bool) {
+	deadline := time.Now().Add(budget)
+
+	cell, exists := layout.At(p)
+	if !exists {
+		return nil, false
+	}
+
+	clone := layout.Clone()
+	clone.Cells()[p.Y*clone.Width+p.X].Source = source
+	// A source reaches emission-1 cells away; one more ring lets the relight see its boundary.
+	radius := int32Max(source, cell.Source)
+	if !clone.RelightAround(p, radius, deadline) {
+		return nil, false
+	}
+
+	changes := map[Point]int32{}
+	for i, after := range clone.Cells() {
+		if before := layout.Cells()[i]; after.Level != before.Level {
+			changes[Point{X: int32(i) % layout.Width, Y: int32(i) / layout.Width}] = after.Level
+		}
+	}
+	return changes, true
+}
+
+// raylibDrawPlacementPreview tints the cells a placement would change in green, stronger for bigger gains, and
+// shows their new levels.
+func (layout Layout) raylibDrawPlacementPreview(changes map[Point]int32) {
+	for point, level := range changes {
+		gain := level - layout.Get(point).Level
+		alpha := float32(0.25)
+		if gain > 0 {
+			alpha += 0.5 * float32(gain) / 15
+		}
+
+		x := point.X * SquareSideLengthPx
+		y := point.Y * SquareSideLengthPx
+		rl.DrawRectangle(x, y, SquareSideLengthPx, SquareSideLengthPx, rl.ColorAlpha(rl.Green, alpha))
+	}
+}
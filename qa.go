@@ -0,0 +1,306 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gen2brain/raylib-go/raylib"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ReadGridCSV reads a per-cell grid of integers, one row per grid row (the format writeGridCSV writes).
+func ReadGridCSV(r io.Reader) ([][]int32, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, csvInputError(err)
+	}
+
+	grid := make([][]int32, len(records))
+	for y, record := range records {
+		grid[y] = make([]int32, len(record))
+		for x, field := range record {
+			value, err := strconv.ParseInt(strings.TrimSpace(field), 10, 32)
+			if err != nil {
+				return nil, &InputError{Line: y + 1, Column: x + 1, Msg: fmt.Sprintf("%q is not a number", field)}
+			}
+			grid[y][x] = int32(value)
+		}
+	}
+	return grid, nil
+}
+
+// csvInputError turns a CSV syntax error into an InputError at the same position. Other errors (reading the
+// underlying file) are returned as is.
+func csvInputError(err error) error {
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return &InputError{Line: parseErr.Line, Column: parseErr.Column, Msg: parseErr.Err.Error()}
+	}
+	return err
+}
+
+// QAReasons are the reasons a discrepancy can be acknowledged with from the cell menu.
+var QAReasons = []string{"measurement error", "known game difference", "accepted"}
+
+// Discrepancy is a cell whose simulated light level differs from the expected one.
+type Discrepancy struct {
+	Point    Point
+	Expected int32
+	Actual   int32
+
+	// Why it was signed off; empty while the discrepancy is open.
+	Reason string
+}
+
+// Delta is the simulated level minus the expected one.
+func (discrepancy Discrepancy) Delta() int32 {
+	return discrepancy.Actual - discrepancy.Expected
+}
+
+// QA compares the simulation with expected light levels, measured in game.
+// Acknowledgements are kept in a sidecar file next to the expected grid, so that sign-off survives restarts.
+type QA struct {
+	// Indexed Expected[y][x]
+	Expected [][]int32
+
+	Acknowledged map[Point]string
+	SidecarPath  string
+
+	// Index of the discrepancy the last jump went to
+	cursor int
+}
+
+// LoadQA reads the expected grid at path, as CSV or, for a .json file, as an array of rows, and the
+// acknowledgements sidecar next to it (path + ".ack.csv") if there is one.
+func LoadQA(path string, width int32, height int32) (*QA, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	qa := &QA{Acknowledged: map[Point]string{}, SidecarPath: path + ".ack.csv"}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.NewDecoder(file).Decode(&qa.Expected)
+	} else {
+		qa.Expected, err = ReadGridCSV(file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	if int32(len(qa.Expected)) != height {
+		return nil, fmt.Errorf("%w: %s: %d rows, the grid has %d", ErrMalformedInput, path, len(qa.Expected), height)
+	}
+	for y, row := range qa.Expected {
+		if int32(len(row)) != width {
+			return nil, fmt.Errorf("%w: %s: row %d has %d cells, the grid has %d",
+				ErrMalformedInput, path, y+1, len(row), width)
+		}
+	}
+
+	sidecar, err := os.Open(qa.SidecarPath)
+	if os.IsNotExist(err) {
+		return qa, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer sidecar.Close()
+
+	reader := csv.NewReader(sidecar)
+	reader.FieldsPerRecord = 3
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", qa.SidecarPath, csvInputError(err))
+	}
+	for _, record := range records {
+		x, errX := strconv.Atoi(record[0])
+		y, errY := strconv.Atoi(record[1])
+		if errX != nil || errY != nil {
+			return nil, fmt.Errorf("%w: %s: bad cell %q, %q", ErrMalformedInput, qa.SidecarPath, record[0], record[1])
+		}
+		qa.Acknowledged[Point{X: int32(x), Y: int32(y)}] = record[2]
+	}
+	return qa, nil
+}
+
+// saveAcknowledgements writes the sidecar file, one "x,y,reason" line per acknowledged cell.
+func (qa *QA) saveAcknowledgements() error {
+	points := make([]Point, 0, len(qa.Acknowledged))
+	for point := range qa.Acknowledged {
+		points = append(points, point)
+	}
+	sortPoints(points)
+
+	file, err := os.Create(qa.SidecarPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	for _, point := range points {
+		record := []string{strconv.Itoa(int(point.X)), strconv.Itoa(int(point.Y)), qa.Acknowledged[point]}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// sortPoints orders points row by row.
+func sortPoints(points []Point) {
+	sort.Slice(points, func(i, j int) bool {
+		if points[i].Y != points[j].Y {
+			return points[i].Y < points[j].Y
+		}
+		return points[i].X < points[j].X
+	})
+}
+
+// Acknowledge signs off the discrepancy at p with a reason (or reopens it, if reason is empty) and saves the
+// sidecar file.
+func (qa *QA) Acknowledge(p Point, reason string) error {
+	if reason == "" {
+		delete(qa.Acknowledged, p)
+	} else {
+		qa.Acknowledged[p] = reason
+	}
+	return qa.saveAcknowledgements()
+}
+
+// Discrepancies lists, row by row, the cells of the layout whose light level differs from the expected one.
+func (qa *QA) Discrepancies(layout Layout) []Discrepancy {
+	discrepancies := []Discrepancy{}
+	for y, row := range qa.Expected {
+		for x, expected := range row {
+			point := Point{X: int32(x), Y: int32(y)}
+			cell, exists := layout.At(point)
+			if !exists || cell.Level == expected {
+				continue
+			}
+			discrepancies = append(discrepancies, Discrepancy{
+				Point:    point,
+				Expected: expected,
+				Actual:   cell.Level,
+				Reason:   qa.Acknowledged[point],
+			})
+		}
+	}
+	return discrepancies
+}
+
+// Next returns the open discrepancy after the one the previous call returned, wrapping around.
+func (qa *QA) Next(layout Layout) (Discrepancy, bool) {
+	open := []Discrepancy{}
+	for _, discrepancy := range qa.Discrepancies(layout) {
+		if discrepancy.Reason == "" {
+			open = append(open, discrepancy)
+		}
+	}
+	if len(open) == 0 {
+		return Discrepancy{}, false
+	}
+	qa.cursor = (qa.cursor + 1) % len(open)
+	return open[qa.cursor], true
+}
+
+// qaSummary counts open and acknowledged discrepancies.
+func qaSummary(discrepancies []Discrepancy) string {
+	acknowledged := 0
+	for _, discrepancy := range discrepancies {
+		if discrepancy.Reason != "" {
+			acknowledged++
+		}
+	}
+	return fmt.Sprintf("QA: %d open, %d acknowledged", len(discrepancies)-acknowledged, acknowledged)
+}
+
+// WriteQAReport writes the discrepancies as CSV with a header, followed by the open/acknowledged totals.
+func WriteQAReport(w io.Writer, discrepancies []Discrepancy) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"x", "y", "expected", "actual", "delta", "status", "reason"}); err != nil {
+		return err
+	}
+	for _, discrepancy := range discrepancies {
+		status := "open"
+		if discrepancy.Reason != "" {
+			status = "acknowledged"
+		}
+		record := []string{
+			strconv.Itoa(int(discrepancy.Point.X)),
+			strconv.Itoa(int(discrepancy.Point.Y)),
+			strconv.Itoa(int(discrepancy.Expected)),
+			strconv.Itoa(int(discrepancy.Actual)),
+			strconv.Itoa(int(discrepancy.Delta())),
+			status,
+			discrepancy.Reason,
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "# %s\n", qaSummary(discrepancies))
+	return err
+}
+
+// exportQAReport writes qa-report.csv to the working directory and returns its name.
+func (qa *QA) exportQAReport(layout Layout) (string, error) {
+	discrepancies := qa.Discrepancies(layout)
+	return writeExport("qa-report.csv", nil, func(w io.Writer) error {
+		return WriteQAReport(w, discrepancies)
+	})
+}
+
+// menuItems returns the cell menu entries to sign off or reopen the discrepancy at p, if there is one.
+func (qa *QA) menuItems(layout Layout, p Point, onError func(error)) []MenuItem {
+	for _, discrepancy := range qa.Discrepancies(layout) {
+		if discrepancy.Point != p {
+			continue
+		}
+		if discrepancy.Reason != "" {
+			return []MenuItem{{Label: "Reopen discrepancy", Action: func() { onError(qa.Acknowledge(p, "")) }}}
+		}
+		reasons := make([]MenuItem, len(QAReasons))
+		for i, reason := range QAReasons {
+			reason := reason
+			reasons[i] = MenuItem{Label: reason, Action: func() { onError(qa.Acknowledge(p, reason)) }}
+		}
+		return []MenuItem{{Label: "Acknowledge", Submenu: reasons}}
+	}
+	return nil
+}
+
+// QADeltaFontPx is the size of the signed delta drawn in the corner of discrepant cells.
+const QADeltaFontPx = int32(10)
+
+// raylibDrawDiscrepancies outlines open discrepancies in red and acknowledged ones in gray, with the signed delta
+// in the top-right corner.
+func raylibDrawDiscrepancies(discrepancies []Discrepancy) {
+	for _, discrepancy := range discrepancies {
+		x := discrepancy.Point.X * SquareSideLengthPx
+		y := discrepancy.Point.Y * SquareSideLengthPx
+		color := rl.Red
+		if discrepancy.Reason != "" {
+			color = rl.Gray
+		}
+		rl.DrawRectangleLines(x, y, SquareSideLengthPx, SquareSideLengthPx, color)
+		rl.DrawRectangleLines(x+1, y+1, SquareSideLengthPx-2, SquareSideLengthPx-2, color)
+
+		text := fmt.Sprintf("%+d", discrepancy.Delta())
+		width := rl.MeasureText(text, QADeltaFontPx)
+		rl.DrawText(text, x+SquareSideLengthPx-width-2, y+1, QADeltaFontPx, color)
+	}
+}
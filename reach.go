@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"github.com/gen2brain/raylib-go/raylib"
+)
+
+// TheoreticalReach returns how far a source with the given emission reaches on an open, unbounded grid:
+// the largest Manhattan distance it lights (at level 1 or more) and the number of cells within it.
+func TheoreticalReach(emission int32) (radius, cells int32) {
+	if emission <= 0 {
+		return 0, 0
+	}
+	radius = emission - 1
+	return radius, 2*radius*(radius+1) + 1
+}
+
+// sourceLevels floods light from the source at p on its own, around blockers and clamped by caps as the
+// simulation does, and returns the level it delivers to every cell it lights (at least 1). Past a capped cell, the
+// brightest path may be a detour rather than the shortest one.
+func (layout Layout) sourceLevels(p Point) (map[Point]int32, error) {
+	source, exists := layout.At(p)
+	if !exists {
+		return nil, fmt.Errorf("%w: %v", ErrOutOfBounds, p)
+	}
+	if source.Source <= 0 {
+		return nil, fmt.Errorf("%w: cell at %v is not a light source (emission %d)", ErrOutOfRange, p, source.Source)
+	}
+
+	levels := map[Point]int32{p: source.Capped(source.Source)}
+	queue := []Point{p}
+	for len(queue) > 0 {
+		point := queue[0]
+		queue = queue[1:]
+
+		// The next cell would receive nothing.
+		level := levels[point] - 1
+		if level <= 0 {
+			continue
+		}
+
+		for _, neighbor := range point.Neighbors() {
+			// Off the grid, Get returns a blocker.
+			cell := layout.Get(neighbor)
+			if cell.Source < 0 {
+				continue
+			}
+			if next := cell.Capped(level); next > levels[neighbor] {
+				levels[neighbor] = next
+				queue = append(queue, neighbor)
+			}
+		}
+	}
+
+	return levels, nil
+}
+
+// SourceReach measures how far the source at p actually reaches given the blockers and caps around it: the largest
+// Manhattan distance of a cell it owns and the number of cells it owns.
+//
+// A cell is owned by the source if its light level is exactly what this source delivers along its brightest path,
+// around blockers and through caps (a cell tied between several sources is owned by all of them).
+// The light levels are expected to have converged.
+func (layout Layout) SourceReach(p Point) (radius, cells int32, err error) {
+	levels, err := layout.sourceLevels(p)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	for point, level := range levels {
+		if layout.Get(point).Level == level {
+			cells++
+			radius = int32Max(radius, int32Abs(point.X-p.X)+int32Abs(point.Y-p.Y))
+		}
+	}
+	return radius, cells, nil
+}
+
+// ReachArea returns the cells the source at p lights on its own: its Manhattan diamond, cut by blockers and shrunk
+// behind caps. Unlike SourceReach, this does not depend on the light levels, so it is up to date right after an edit.
+func (layout Layout) ReachArea(p Point) (map[Point]bool, error) {
+	levels, err := layout.sourceLevels(p)
+	if err != nil {
+		return nil, err
+	}
+
+	area := make(map[Point]bool, len(levels))
+	for point := range levels {
+		area[point] = true
+	}
+	return area, nil
+}
+
+func int32Abs(a int32) int32 {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
+
+// ReachBadgeFontPx is the font size of the hover badges on sources and blockers.
+const ReachBadgeFontPx = int32(16)
+
+// raylibDrawReachBadge draws a "reach 9/14" badge next to the source at p, if there is one.
+func (layout Layout) raylibDrawReachBadge(p Point) {
+	radius, _, err := layout.SourceReach(p)
+	if err != nil {
+		return
+	}
+	theoretical, _ := TheoreticalReach(layout.Get(p).Source)
+
+	layout.raylibDrawBadge(p, fmt.Sprintf("reach %d/%d", radius, theoretical))
+}
+
+// raylibDrawBadge draws a text badge next to the cell at p.
+func (layout Layout) raylibDrawBadge(p Point, text string) {
+	width := rl.MeasureText(text, ReachBadgeFontPx) + 4
+	height := ReachBadgeFontPx + 4
+
+	// Below and to the right of the cell, unless that runs off the grid.
+	x := (p.X + 1) * SquareSideLengthPx
+	y := (p.Y + 1) * SquareSideLengthPx
+	gridWidth, gridHeight := layout.Size()
+	if x+width > gridWidth*SquareSideLengthPx {
+		x = p.X*SquareSideLengthPx - width
+	}
+	if y+height > gridHeight*SquareSideLengthPx {
+		y = p.Y*SquareSideLengthPx - height
+	}
+
+	rl.DrawRectangle(x, y, width, height, rl.RayWhite)
+	rl.DrawRectangleLines(x, y, width, height, rl.Black)
+	rl.DrawText(text, x+2, y+2, ReachBadgeFontPx, rl.Black)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestSourceReachOpen(t *testing.T) {
+	layout := NewLayout(15, 15)
+	layout.SetSource(Point{X: 7, Y: 7}, 6)
+	layout.Converge(ConvergeMaxIterations)
+
+	radius, cells, err := layout.SourceReach(Point{X: 7, Y: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wantRadius, wantCells := TheoreticalReach(6); radius != wantRadius || cells != wantCells {
+		t.Errorf("reach %d over %d cells, want %d over %d", radius, cells, wantRadius, wantCells)
+	}
+}
+
+// A cap on the path dims everything past it: the source still owns those cells at the capped level.
+func TestSourceReachCapped(t *testing.T) {
+	// 10 at the left end, capped to 4 two cells on, and a 6 further right that outshines it past x=4.
+	layout := NewLayout(12, 1)
+	layout.SetSource(Point{X: 0, Y: 0}, 10)
+	layout.SetCap(Point{X: 2, Y: 0}, 4)
+	layout.SetSource(Point{X: 9, Y: 0}, 6)
+	layout.Converge(ConvergeMaxIterations)
+
+	radius, cells, err := layout.SourceReach(Point{X: 0, Y: 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if radius != 4 || cells != 5 {
+		t.Errorf("reach %d over %d cells, want 4 over 5", radius, cells)
+	}
+
+	area, err := layout.ReachArea(Point{X: 0, Y: 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for x := int32(0); x < 12; x++ {
+		if want := x <= 5; area[Point{X: x, Y: 0}] != want {
+			t.Errorf("cell %d in the reach area: %v, want %v", x, area[Point{X: x, Y: 0}], want)
+		}
+	}
+}
+
+// Past a tightly capped cell, the brightest path is a detour around it, longer than the shortest one.
+func TestSourceReachCapDetour(t *testing.T) {
+	layout := NewLayout(5, 3)
+	layout.SetSource(Point{X: 0, Y: 1}, 10)
+	layout.SetCap(Point{X: 1, Y: 1}, 1)
+	layout.Converge(ConvergeMaxIterations)
+
+	levels, err := layout.sourceLevels(Point{X: 0, Y: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if level := levels[Point{X: 2, Y: 1}]; level != 6 {
+		t.Errorf("the detour delivers %d to (2, 1), want 6", level)
+	}
+	for i, cell := range layout.Cells() {
+		p := Point{X: int32(i) % 5, Y: int32(i) / 5}
+		if levels[p] != cell.Level {
+			t.Errorf("%v: the source alone delivers %d, the simulation %d", p, levels[p], cell.Level)
+		}
+	}
+
+	radius, cells, err := layout.SourceReach(Point{X: 0, Y: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if radius != 5 || cells != 15 {
+		t.Errorf("reach %d over %d cells, want 5 over 15", radius, cells)
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"github.com/gen2brain/raylib-go/raylib"
+	"io"
+)
+
+// Colors of the reach-versus-circle comparison.
+var (
+	reachOutlineColor = rl.Orange
+	circleColor       = rl.Blue
+)
+
+// NominalRadius is the radius, in cells, of the circle a source with this emission is naively expected to light:
+// it reaches emission-1 cells away, and the diamond's edge is half a cell beyond that cell's center.
+func NominalRadius(emission int32) float32 {
+	return float32(emission) - 0.5
+}
+
+// raylibDrawReachCircle draws, around the source at p, the outline of the cells it actually lights next to the
+// Euclidean circle of the same nominal radius, with a legend.
+func (layout Layout) raylibDrawReachCircle(p Point) {
+	area, err := layout.ReachArea(p)
+	if err != nil {
+		return
+	}
+
+	for _, segment := range Contour(area) {
+		rl.DrawLineEx(
+			rl.NewVector2(float32(segment.From.X*SquareSideLengthPx), float32(segment.From.Y*SquareSideLengthPx)),
+			rl.NewVector2(float32(segment.To.X*SquareSideLengthPx), float32(segment.To.Y*SquareSideLengthPx)),
+			3, reachOutlineColor)
+	}
+
+	centerX := p.X*SquareSideLengthPx + SquareSideLengthPx/2
+	centerY := p.Y*SquareSideLengthPx + SquareSideLengthPx/2
+	radius := NominalRadius(layout.Get(p).Source) * float32(SquareSideLengthPx)
+	rl.DrawCircleLines(centerX, centerY, radius, circleColor)
+	rl.DrawCircleLines(centerX, centerY, radius-1, circleColor)
+
+	// Legend, in the top-left corner
+	const fontPx = 12
+	lines := []struct {
+		text  string
+		color rl.Color
+	}{
+		{"actual reach: a diamond, cut by blockers and caps", reachOutlineColor},
+		{"Euclidean circle of the same radius", circleColor},
+	}
+	width := int32(0)
+	for _, line := range lines {
+		width = int32Max(width, rl.MeasureText(line.text, fontPx))
+	}
+	rl.DrawRectangle(0, 0, width+20, int32(len(lines))*(fontPx+4)+4, rl.RayWhite)
+	for i, line := range lines {
+		y := int32(i)*(fontPx+4) + 4
+		rl.DrawRectangle(4, y+2, 10, 4, line.color)
+		rl.DrawText(line.text, 18, y, fontPx, rl.Black)
+	}
+}
+
+// WriteReachCircleSVG writes the same comparison as an SVG, in cell units.
+func (layout Layout) WriteReachCircleSVG(w io.Writer, p Point) error {
+	area, err := layout.ReachArea(p)
+	if err != nil {
+		return err
+	}
+
+	svgColor := func(c rl.Color) string {
+		return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+	}
+
+	width, height := layout.Size()
+	out := &errWriter{w: w}
+	out.printf("<svg xmlns=\"http://www.w3.org/2000/svg\" viewBox=\"0 0 %d %d\" width=\"%d\" height=\"%d\">\n",
+		width, height, width*SquareSideLengthPx, height*SquareSideLengthPx)
+	out.printf("<rect width=\"%d\" height=\"%d\" fill=\"white\" stroke=\"black\" stroke-width=\"0.05\"/>\n",
+		width, height)
+
+	for y := int32(0); y < height; y++ {
+		for x := int32(0); x < width; x++ {
+			if layout.Get(Point{X: x, Y: y}).Source < 0 {
+				out.printf("<rect x=\"%d\" y=\"%d\" width=\"1\" height=\"1\" fill=\"gray\"/>\n", x, y)
+			}
+		}
+	}
+	out.printf("<rect x=\"%d\" y=\"%d\" width=\"1\" height=\"1\" fill=\"%s\"/>\n", p.X, p.Y, svgColor(rl.Orange))
+
+	out.printf("<g stroke=\"%s\" stroke-width=\"0.12\" stroke-linecap=\"round\">\n", svgColor(reachOutlineColor))
+	for _, segment := range Contour(area) {
+		out.printf("<line x1=\"%d\" y1=\"%d\" x2=\"%d\" y2=\"%d\"/>\n",
+			segment.From.X, segment.From.Y, segment.To.X, segment.To.Y)
+	}
+	out.printf("</g>\n")
+
+	out.printf("<circle cx=\"%g\" cy=\"%g\" r=\"%g\" fill=\"none\" stroke=\"%s\" stroke-width=\"0.08\"/>\n",
+		float32(p.X)+0.5, float32(p.Y)+0.5, NominalRadius(layout.Get(p).Source), svgColor(circleColor))
+	out.printf("</svg>\n")
+	return out.err
+}
+
+// exportReachCircle writes reach-<x>-<y>.svg to the working directory and returns its name.
+func (layout Layout) exportReachCircle(p Point) (string, error) {
+	return writeExport(fmt.Sprintf("reach-%d-%d.svg", p.X, p.Y), nil, func(w io.Writer) error {
+		return layout.WriteReachCircleSVG(w, p)
+	})
+}
+
+// errWriter keeps the first write error, so a sequence of writes can be checked once at the end.
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (ew *errWriter) printf(format string, args ...interface{}) {
+	if ew.err != nil {
+		return
+	}
+	_, ew.err = fmt.Fprintf(ew.w, format, args...)
+}
@@ -0,0 +1,416 @@
+package main
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// blockEmissions is the light level vanilla Java edition blocks give off, by block name without the "minecraft:"
+// namespace. Blocks that only glow in some states are adjusted by blockEmission.
+var blockEmissions = map[string]int32{
+	"beacon":                15,
+	"campfire":              15,
+	"conduit":               15,
+	"end_gateway":           15,
+	"end_portal":            15,
+	"fire":                  15,
+	"glowstone":             15,
+	"jack_o_lantern":        15,
+	"lantern":               15,
+	"lava":                  15,
+	"lava_cauldron":         15,
+	"ochre_froglight":       15,
+	"pearlescent_froglight": 15,
+	"redstone_lamp":         15,
+	"sea_lantern":           15,
+	"shroomlight":           15,
+	"verdant_froglight":     15,
+	"end_rod":               14,
+	"torch":                 14,
+	"wall_torch":            14,
+	"blast_furnace":         13,
+	"furnace":               13,
+	"smoker":                13,
+	"nether_portal":         11,
+	"crying_obsidian":       10,
+	"soul_campfire":         10,
+	"soul_fire":             10,
+	"soul_lantern":          10,
+	"soul_torch":            10,
+	"soul_wall_torch":       10,
+	"enchanting_table":      7,
+	"ender_chest":           7,
+	"glow_lichen":           7,
+	"redstone_torch":        7,
+	"redstone_wall_torch":   7,
+	"sculk_catalyst":        6,
+	"amethyst_cluster":      5,
+	"large_amethyst_bud":    4,
+	"magma_block":           3,
+	"medium_amethyst_bud":   2,
+	"brewing_stand":         1,
+	"brown_mushroom":        1,
+	"dragon_egg":            1,
+	"end_portal_frame":      1,
+	"sculk_sensor":          1,
+	"small_amethyst_bud":    1,
+}
+
+// litOnly are the emitters that are dark unless their lit property is true. Other blocks with a lit property
+// (campfires, redstone torches) are dark only when it is false.
+var litOnly = map[string]bool{
+	"blast_furnace": true,
+	"furnace":       true,
+	"redstone_lamp": true,
+	"smoker":        true,
+}
+
+// transparentBlocks let light through without giving any off, like empty cells. Names ending in one of
+// transparentSuffixes are too.
+var transparentBlocks = map[string]bool{
+	"air":                true,
+	"allium":             true,
+	"azure_bluet":        true,
+	"barrier":            true,
+	"blue_orchid":        true,
+	"bubble_column":      true,
+	"cauldron":           true,
+	"cave_air":           true,
+	"chain":              true,
+	"cobweb":             true,
+	"cornflower":         true,
+	"dandelion":          true,
+	"dead_bush":          true,
+	"fern":               true,
+	"flower_pot":         true,
+	"glass":              true,
+	"glass_pane":         true,
+	"grass":              true,
+	"iron_bars":          true,
+	"kelp":               true,
+	"kelp_plant":         true,
+	"ladder":             true,
+	"large_fern":         true,
+	"lever":              true,
+	"lilac":              true,
+	"lily_of_the_valley": true,
+	"lily_pad":           true,
+	"oxeye_daisy":        true,
+	"peony":              true,
+	"poppy":              true,
+	"rail":               true,
+	"redstone_wire":      true,
+	"rose_bush":          true,
+	"scaffolding":        true,
+	"seagrass":           true,
+	"short_grass":        true,
+	"snow":               true,
+	"structure_void":     true,
+	"sugar_cane":         true,
+	"sunflower":          true,
+	"tall_grass":         true,
+	"tall_seagrass":      true,
+	"tripwire":           true,
+	"tripwire_hook":      true,
+	"vine":               true,
+	"void_air":           true,
+	"water":              true,
+	"water_cauldron":     true,
+}
+
+var transparentSuffixes = []string{
+	"_button", "_carpet", "_door", "_fence", "_fence_gate", "_glass", "_glass_pane", "_leaves", "_pressure_plate",
+	"_rail", "_sapling", "_sign", "_trapdoor", "_tulip", "_wall_sign", "_hanging_sign", "_banner", "_wall_banner",
+	"_head", "_skull", "_bed",
+}
+
+// opaqueBlocks block light, as blockers do. Names ending in one of opaqueSuffixes do too. Slabs and stairs count as
+// opaque: a slice through one mostly cuts solid block.
+var opaqueBlocks = map[string]bool{
+	"andesite":          true,
+	"barrel":            true,
+	"basalt":            true,
+	"bedrock":           true,
+	"blackstone":        true,
+	"bookshelf":         true,
+	"bricks":            true,
+	"calcite":           true,
+	"chest":             true,
+	"clay":              true,
+	"coarse_dirt":       true,
+	"cobbled_deepslate": true,
+	"cobblestone":       true,
+	"crafting_table":    true,
+	"deepslate":         true,
+	"diorite":           true,
+	"dirt":              true,
+	"dirt_path":         true,
+	"end_stone":         true,
+	"farmland":          true,
+	"granite":           true,
+	"grass_block":       true,
+	"gravel":            true,
+	"ice":               true,
+	"mossy_cobblestone": true,
+	"mud":               true,
+	"mycelium":          true,
+	"netherrack":        true,
+	"obsidian":          true,
+	"podzol":            true,
+	"prismarine":        true,
+	"purpur_pillar":     true,
+	"quartz_pillar":     true,
+	"red_sand":          true,
+	"red_sandstone":     true,
+	"rooted_dirt":       true,
+	"sand":              true,
+	"sandstone":         true,
+	"snow_block":        true,
+	"soul_sand":         true,
+	"soul_soil":         true,
+	"sponge":            true,
+	"stone":             true,
+	"terracotta":        true,
+	"tinted_glass":      true,
+	"tuff":              true,
+}
+
+var opaqueSuffixes = []string{
+	"_block", "_bricks", "_concrete", "_concrete_powder", "_log", "_ore", "_planks", "_slab", "_stairs", "_stem",
+	"_terracotta", "_wood", "_wool", "_hyphae", "_tiles", "_wall", "_stone", "_sandstone", "_deepslate",
+	"_andesite", "_diorite", "_granite", "_blackstone", "_basalt", "_prismarine", "_quartz", "_glazed_terracotta",
+}
+
+// blockEmission maps a block state such as "minecraft:furnace[facing=north,lit=true]" to an emission: its light
+// level for light sources, 0 for blocks light passes through and -1 for blocks that stop it. known is false for
+// blocks in none of the tables, which are taken as opaque.
+func blockEmission(state string) (emission int32, known bool) {
+	name, properties := state, map[string]string{}
+	if open := strings.IndexByte(state, '['); open >= 0 && strings.HasSuffix(state, "]") {
+		name = state[:open]
+		for _, property := range strings.Split(state[open+1:len(state)-1], ",") {
+			if equals := strings.IndexByte(property, '='); equals >= 0 {
+				properties[strings.TrimSpace(property[:equals])] = strings.TrimSpace(property[equals+1:])
+			}
+		}
+	}
+	if !strings.HasPrefix(name, "minecraft:") {
+		// Mods' blocks, or names without a namespace, which schematics do not write
+		return -1, false
+	}
+	name = strings.TrimPrefix(name, "minecraft:")
+
+	lit := properties["lit"]
+	switch {
+	case name == "light":
+		level, err := strconv.Atoi(properties["level"])
+		if err != nil || level < 0 || level > 15 {
+			level = 15
+		}
+		return int32(level), true
+	case name == "candle" || strings.HasSuffix(name, "_candle"):
+		if lit != "true" {
+			return 0, true
+		}
+		candles, err := strconv.Atoi(properties["candles"])
+		if err != nil || candles < 1 || candles > 4 {
+			candles = 1
+		}
+		return int32(3 * candles), true
+	case name == "candle_cake" || strings.HasSuffix(name, "_candle_cake"):
+		if lit != "true" {
+			return 0, true
+		}
+		return 3, true
+	case name == "sea_pickle":
+		if properties["waterlogged"] == "false" {
+			return 0, true
+		}
+		pickles, err := strconv.Atoi(properties["pickles"])
+		if err != nil || pickles < 1 || pickles > 4 {
+			pickles = 1
+		}
+		return int32(3 + 3*pickles), true
+	case name == "respawn_anchor":
+		charges, err := strconv.Atoi(properties["charges"])
+		if err != nil || charges < 0 || charges > 4 {
+			charges = 0
+		}
+		return int32([]int{0, 3, 7, 11, 15}[charges]), true
+	}
+
+	if level, emits := blockEmissions[name]; emits {
+		if lit == "false" || (litOnly[name] && lit != "true") {
+			if litOnly[name] {
+				// A cold furnace or lamp is a solid block.
+				return -1, true
+			}
+			return 0, true
+		}
+		return level, true
+	}
+	if transparentBlocks[name] {
+		return 0, true
+	}
+	if opaqueBlocks[name] {
+		return -1, true
+	}
+	for _, suffix := range transparentSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return 0, true
+		}
+	}
+	for _, suffix := range opaqueSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return -1, true
+		}
+	}
+	return -1, false
+}
+
+// ImportSchematic builds a layout from the horizontal slice at height y (0 being the bottom of the schematic) of a
+// Sponge schematic (.schem, versions 1 to 3, gzipped NBT). Grid x and y are the schematic's x and z; each block
+// becomes a cell through blockEmission. It also returns the number of cells per block state that blockEmission
+// does not know, which are blockers.
+func ImportSchematic(r io.Reader, y int32) (Layout, map[string]int, error) {
+	unzipped, err := gzip.NewReader(r)
+	if err != nil {
+		return Layout{}, nil, fmt.Errorf("%w: not gzipped: %v", ErrMalformedInput, err)
+	}
+	_, root, err := readNBT(unzipped)
+	if err != nil {
+		return Layout{}, nil, err
+	}
+	// Version 3 wraps everything in a Schematic compound; earlier versions have it at the root.
+	schematic := root
+	if inner, ok := root["Schematic"].(map[string]interface{}); ok {
+		schematic = inner
+	}
+
+	size := [3]int32{}
+	for i, name := range []string{"Width", "Height", "Length"} {
+		value, ok := schematic[name].(int16)
+		if !ok {
+			return Layout{}, nil, fmt.Errorf("%w: schematic has no %s", ErrMalformedInput, name)
+		}
+		// Unsigned in the format
+		size[i] = int32(uint16(value))
+	}
+	width, height, length := size[0], size[1], size[2]
+	if width == 0 || length == 0 {
+		return Layout{}, nil, fmt.Errorf("%w: schematic is %dx%d, it has no blocks", ErrMalformedInput, width, length)
+	}
+	if y < 0 || y >= height {
+		return Layout{}, nil, fmt.Errorf("%w: height %d is outside the schematic's 0-%d", ErrOutOfRange, y, height-1)
+	}
+
+	blocks := schematic
+	if inner, ok := schematic["Blocks"].(map[string]interface{}); ok {
+		blocks = inner
+	}
+	palette, ok := blocks["Palette"].(map[string]interface{})
+	if !ok {
+		return Layout{}, nil, fmt.Errorf("%w: schematic has no block palette", ErrMalformedInput)
+	}
+	data, ok := blocks["BlockData"].([]byte)
+	if !ok {
+		if data, ok = blocks["Data"].([]byte); !ok {
+			return Layout{}, nil, fmt.Errorf("%w: schematic has no block data", ErrMalformedInput)
+		}
+	}
+
+	states := map[int32]string{}
+	for state, value := range palette {
+		index, ok := value.(int32)
+		if !ok {
+			return Layout{}, nil, fmt.Errorf("%w: palette entry %q is not an int", ErrMalformedInput, state)
+		}
+		states[index] = state
+	}
+
+	// Block data is one varint palette index per block, x fastest, then z, then y. Every varint takes at least a
+	// byte, so the data bounds the size before anything is allocated for it.
+	count := int64(width) * int64(height) * int64(length)
+	if count > int64(len(data)) {
+		return Layout{}, nil, fmt.Errorf("%w: block data has at most %d blocks, expected %d",
+			ErrMalformedInput, len(data), count)
+	}
+	indices, err := decodeVarints(data, int(count))
+	if err != nil {
+		return Layout{}, nil, err
+	}
+	layout := NewLayout(width, length)
+	unknown := map[string]int{}
+	slice := indices[int(y)*int(width)*int(length):]
+	for z := int32(0); z < length; z++ {
+		for x := int32(0); x < width; x++ {
+			index := slice[z*width+x]
+			state, ok := states[index]
+			if !ok {
+				return Layout{}, nil, fmt.Errorf("%w: block (%d, %d, %d) has palette index %d, which is not in the palette",
+					ErrMalformedInput, x, y, z, index)
+			}
+			emission, known := blockEmission(state)
+			if !known {
+				unknown[state]++
+			}
+			layout.SetSource(Point{X: x, Y: z}, emission)
+		}
+	}
+	return layout, unknown, nil
+}
+
+// decodeVarints decodes exactly count unsigned LEB128 varints of at most 32 bits from data.
+func decodeVarints(data []byte, count int) ([]int32, error) {
+	values := make([]int32, 0, count)
+	value, shift := uint32(0), uint(0)
+	for i, b := range data {
+		if shift >= 32 {
+			return nil, fmt.Errorf("%w: block data varint at byte %d is too long", ErrMalformedInput, i)
+		}
+		value |= uint32(b&0x7f) << shift
+		if b&0x80 != 0 {
+			shift += 7
+			continue
+		}
+		if len(values) == count {
+			return nil, fmt.Errorf("%w: block data has more than %d blocks", ErrMalformedInput, count)
+		}
+		values = append(values, int32(value))
+		value, shift = 0, 0
+	}
+	if shift != 0 || len(values) != count {
+		return nil, fmt.Errorf("%w: block data has %d blocks, expected %d", ErrMalformedInput, len(values), count)
+	}
+	return values, nil
+}
+
+// importSchematicFile imports a slice of the schematic file at path (see ImportSchematic), logging the blocks it
+// did not know.
+func importSchematicFile(path string, y int32) (Layout, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Layout{}, err
+	}
+	defer file.Close()
+
+	layout, unknown, err := ImportSchematic(file, y)
+	if err != nil {
+		return Layout{}, fmt.Errorf("%s: %w", path, err)
+	}
+	states := make([]string, 0, len(unknown))
+	for state := range unknown {
+		states = append(states, state)
+	}
+	sort.Strings(states)
+	for _, state := range states {
+		log.Printf("%s: %d block(s) of unknown %s taken as opaque\n", path, unknown[state], state)
+	}
+	return layout, nil
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+)
+
+// The fixtures hold the same 4x2x3 (width x height x length) schematic, in the version 2 layout (everything at
+// the root) and the version 3 one (in a Schematic compound, with the blocks in a Blocks compound). Their palette
+// has 301 entries, so that glowstone (128), stone (129) and mod:strange_block (300) take two-byte varints.
+// The bottom layer is air but for glowstone at x 3, z 2; the top one is
+//
+//	glowstone  air                air    stone
+//	air        mod:strange_block  air    air
+//	torch      air                stone  air
+var schematicFixtures = []string{"testdata/slice-v2.schem", "testdata/slice-v3.schem"}
+
+func TestImportSchematic(t *testing.T) {
+	for _, path := range schematicFixtures {
+		for _, slice := range []struct {
+			y       int32
+			want    [][]int32
+			unknown map[string]int
+		}{
+			{0, [][]int32{{0, 0, 0, 0}, {0, 0, 0, 0}, {0, 0, 0, 15}}, map[string]int{}},
+			{1, [][]int32{{15, 0, 0, -1}, {0, -1, 0, 0}, {14, 0, -1, 0}}, map[string]int{"mod:strange_block": 1}},
+		} {
+			file, err := os.Open(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			layout, unknown, err := ImportSchematic(file, slice.y)
+			file.Close()
+			if err != nil {
+				t.Fatalf("%s at height %d: %v", path, slice.y, err)
+			}
+			if got := layout.Emissions(); !reflect.DeepEqual(got, slice.want) {
+				t.Errorf("%s at height %d is %v, want %v", path, slice.y, got, slice.want)
+			}
+			if !reflect.DeepEqual(unknown, slice.unknown) {
+				t.Errorf("%s at height %d has unknown blocks %v, want %v", path, slice.y, unknown, slice.unknown)
+			}
+		}
+	}
+}
+
+func TestImportSchematicInvalid(t *testing.T) {
+	fixture, err := os.ReadFile(schematicFixtures[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, y := range []int32{-1, 2} {
+		if _, _, err := ImportSchematic(bytes.NewReader(fixture), y); !errors.Is(err, ErrOutOfRange) {
+			t.Errorf("height %d gave %v, want ErrOutOfRange", y, err)
+		}
+	}
+
+	if _, _, err := ImportSchematic(bytes.NewReader([]byte("not gzipped")), 0); !errors.Is(err, ErrMalformedInput) {
+		t.Errorf("plain text gave %v, want ErrMalformedInput", err)
+	}
+
+	// Gzipped, but not NBT with a compound at the root.
+	var zipped bytes.Buffer
+	writer := gzip.NewWriter(&zipped)
+	writer.Write([]byte{nbtString, 0, 0, 0, 0})
+	writer.Close()
+	if _, _, err := ImportSchematic(&zipped, 0); !errors.Is(err, ErrMalformedInput) {
+		t.Errorf("a string root gave %v, want ErrMalformedInput", err)
+	}
+}
+
+func TestDecodeVarints(t *testing.T) {
+	values, err := decodeVarints([]byte{0x00, 0x7f, 0x80, 0x01, 0xac, 0x02, 0xff, 0xff, 0xff, 0xff, 0x07}, 5)
+	if want := []int32{0, 127, 128, 300, 1<<31 - 1}; err != nil || !reflect.DeepEqual(values, want) {
+		t.Errorf("decoded %v, %v; want %v", values, err, want)
+	}
+	for _, data := range [][]byte{
+		{0x01, 0x02},             // too few
+		{0x01, 0x02, 0x03, 0x04}, // too many
+		{0x01, 0x02, 0x83},       // cut off mid-varint
+		{0x80, 0x80, 0x80, 0x80, 0x80, 0x01, 0x02}, // longer than 32 bits
+	} {
+		if _, err := decodeVarints(data, 3); !errors.Is(err, ErrMalformedInput) {
+			t.Errorf("decoding % x gave %v, want ErrMalformedInput", data, err)
+		}
+	}
+}
+
+func TestBlockEmission(t *testing.T) {
+	tests := []struct {
+		state    string
+		emission int32
+		known    bool
+	}{
+		{"minecraft:air", 0, true},
+		{"minecraft:glowstone", 15, true},
+		{"minecraft:stone", -1, true},
+		{"minecraft:torch", 14, true},
+		{"minecraft:furnace[facing=north,lit=true]", 13, true},
+		{"minecraft:furnace[facing=north,lit=false]", -1, true},
+		{"minecraft:campfire[lit=false]", 0, true},
+		{"minecraft:light[level=9]", 9, true},
+		{"minecraft:candle[candles=3,lit=true]", 9, true},
+		{"minecraft:sea_pickle[pickles=2,waterlogged=true]", 9, true},
+		{"minecraft:respawn_anchor[charges=2]", 7, true},
+		{"minecraft:oak_planks", -1, true},
+		{"minecraft:red_carpet", 0, true},
+		{"minecraft:something_new", -1, false},
+		{"mod:strange_block", -1, false},
+	}
+	for _, test := range tests {
+		if emission, known := blockEmission(test.state); emission != test.emission || known != test.known {
+			t.Errorf("blockEmission(%q) = %d, %v; want %d, %v", test.state, emission, known, test.emission, test.known)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"github.com/gen2brain/raylib-go/raylib"
+	"image/png"
+	"os"
+	"strconv"
+	"time"
+)
+
+// createScreenshotFile creates screenshot-YYYYMMDD-HHMMSS.png in the working directory, or, if a screenshot was
+// already taken that second, the same name with -2, -3... appended. It never overwrites a file.
+func createScreenshotFile(now time.Time) (*os.File, error) {
+	base := "screenshot-" + now.Format("20060102-150405")
+	for n := 1; ; n++ {
+		name := base + ".png"
+		if n > 1 {
+			name = base + "-" + strconv.Itoa(n) + ".png"
+		}
+		file, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if !os.IsExist(err) {
+			return file, err
+		}
+	}
+}
+
+// raylibSaveScreenshot draws a frame with drawFrame into an offscreen texture the size of the window and saves it
+// as a PNG (see createScreenshotFile), returning its name. Call it outside BeginDrawing/EndDrawing.
+func raylibSaveScreenshot(drawFrame func()) (string, error) {
+	target := rl.LoadRenderTexture(int32(rl.GetScreenWidth()), int32(rl.GetScreenHeight()))
+	defer rl.UnloadRenderTexture(target)
+	rl.BeginTextureMode(target)
+	drawFrame()
+	rl.EndTextureMode()
+
+	image := rl.LoadImageFromTexture(target.Texture)
+	defer rl.UnloadImage(image)
+	// Textures are stored bottom-up.
+	rl.ImageFlipVertical(image)
+
+	file, err := createScreenshotFile(time.Now())
+	if err != nil {
+		return "", err
+	}
+	if err := png.Encode(file, image.ToImage()); err != nil {
+		file.Close()
+		return "", err
+	}
+	return file.Name(), file.Close()
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"log"
+	"mclighting000/lighting"
+	"sync/atomic"
+)
+
+// Simulation runs a layout and publishes an immutable Snapshot of it after every evolve pass.
+//
+// The layout's cells are the simulation's private working buffer; other goroutines only ever see published
+// snapshots, which are swapped in atomically, so the evolve hot loop needs no locking on their account.
+type Simulation struct {
+	Layout Layout
+
+	// Range of emission values that source cycling goes through: 0 to MaxSource, then -1 (a blocker) if
+	// BlockerInCycle is set, then back to 0.
+	MaxSource      int32
+	BlockerInCycle bool
+
+	// Phosphorescence: if above 0, a cell whose level drops keeps glowing in published snapshots, fading by at
+	// most DecayPerTick levels per evolve pass. The layout itself always holds the true levels, which stats and
+	// overlays keep using.
+	DecayPerTick int32
+
+	// If set, Evolve steps deterministically instead of running the concurrent evolve: in alternating sweeps
+	// (see Layout.Sweep) if Sweep is set, else in double-buffered generations (see Layout.Step).
+	Deterministic bool
+	Sweep         bool
+
+	// If set, Evolve jumps straight to the converged field (see Layout.PropagateBFS) instead of stepping towards it.
+	// This takes precedence over Deterministic.
+	Instant bool
+
+	// If set, every pass is recorded to it (see Trace).
+	Trace *Trace
+
+	// Point lights rasterized into the layout, if any. They are taken back out of what is saved (see Saveable).
+	PointLights *PointLights
+
+	// Light field published snapshots show. Unless it is ViewBlock, sky light is recomputed after every pass (see
+	// Layout.PropagateSky). The layout's levels are always block light, which stats and overlays keep using.
+	View LightView
+
+	// Time of day in ticks since sunrise, which dims the sky light shown (see SkyDarkening). It only applies to
+	// published snapshots: the layout's sky levels are always those of the day, so changing it takes no relighting.
+	Time int32
+
+	// Back buffer of deterministic steps
+	back Layout
+
+	// Number of sweeps run, which picks the order of the next one
+	sweeps int
+
+	// Displayed levels when decaying, indexed y*width+x
+	afterglow []int32
+
+	// Holds a publication
+	published atomic.Value
+}
+
+// publication is what Publish makes visible to other goroutines, swapped in as a whole.
+type publication struct {
+	snapshot *Snapshot
+
+	// The layout's own levels, block light without afterglow or the view applied. It is snapshot itself when there
+	// is nothing to apply.
+	block *Snapshot
+
+	// Emission of the cells point lights are rasterized into, without them (see PointLights.Bases)
+	bases map[Point]int32
+}
+
+// NewSimulation wraps a layout and publishes its initial state.
+func NewSimulation(layout Layout) *Simulation {
+	simulation := &Simulation{Layout: layout, MaxSource: 15, BlockerInCycle: true, Sweep: true, Time: Noon}
+	simulation.Publish()
+	return simulation
+}
+
+// Publish makes the current state of the cells visible to readers. Call it after editing the layout.
+// It must not be called while evolve is running.
+func (simulation *Simulation) Publish() {
+	block := simulation.Layout.Snapshot()
+	snapshot := block
+	afterglow := simulation.DecayPerTick > 0 && len(simulation.afterglow) == len(simulation.Layout.Cells())
+	if afterglow || simulation.View != ViewBlock {
+		darkening := SkyDarkening(simulation.Time)
+		cells := snapshot.Cells()
+		for i := range cells {
+			cell := &cells[i]
+			if afterglow && cell.Source >= 0 && simulation.afterglow[i] > cell.Level {
+				cell.Level = simulation.afterglow[i]
+			}
+			cell.Level = simulation.View.level(*cell, darkening)
+		}
+		snapshot = lighting.NewSnapshot(simulation.Layout.Width, simulation.Layout.Height, cells)
+	}
+	var bases map[Point]int32
+	if simulation.PointLights != nil {
+		bases = simulation.PointLights.Bases(simulation.Layout)
+	}
+	simulation.published.Store(publication{snapshot: snapshot, block: block, bases: bases})
+}
+
+// decay moves the displayed levels one pass towards the true ones: rises show at once, drops by at most
+// DecayPerTick.
+func (simulation *Simulation) decay() {
+	if simulation.DecayPerTick <= 0 {
+		simulation.afterglow = nil
+		return
+	}
+	width, height := simulation.Layout.Size()
+	if len(simulation.afterglow) != int(width*height) {
+		simulation.afterglow = make([]int32, width*height)
+	}
+	for i, cell := range simulation.Layout.Cells() {
+		simulation.afterglow[i] = int32Max(cell.Level, simulation.afterglow[i]-simulation.DecayPerTick)
+	}
+}
+
+// step runs one deterministic pass: a sweep in place, or a step through the back buffer whose result is copied back,
+// so that the layout keeps its cells (menus and other tools may hold pointers into them).
+func (simulation *Simulation) step() int {
+	if simulation.Sweep {
+		simulation.sweeps++
+		return simulation.Layout.Sweep(simulation.sweeps - 1)
+	}
+	if simulation.back.Width != simulation.Layout.Width || simulation.back.Height != simulation.Layout.Height {
+		simulation.back = NewLayout(simulation.Layout.Width, simulation.Layout.Height)
+	}
+	changed := simulation.Layout.StepInto(simulation.back.Layout)
+	simulation.Layout.CopyFrom(simulation.back.Layout)
+	return changed
+}
+
+// Evolve runs one evolve pass (or deterministic step, or full propagation) and publishes the result.
+// See Layout.Evolve.
+func (simulation *Simulation) Evolve() int {
+	var changed int
+	if simulation.Instant {
+		changed = simulation.Layout.PropagateBFS()
+	} else if simulation.Deterministic {
+		changed = simulation.step()
+	} else {
+		changed = simulation.Layout.Evolve()
+	}
+	if simulation.View != ViewBlock {
+		changed += simulation.Layout.PropagateSky()
+	}
+	simulation.decay()
+	if simulation.Trace != nil && !simulation.Trace.Done {
+		if err := simulation.Trace.Record(simulation.Layout, changed); err != nil {
+			log.Printf("Trace stopped: %v\n", err)
+			simulation.Trace.Close()
+		} else if simulation.Trace.Done {
+			log.Printf("Trace: levels settled, recording stopped\n")
+		}
+	}
+	simulation.Publish()
+	return changed
+}
+
+// Converge runs Evolve until a pass changes nothing, for at most maxIterations passes, and returns the number of
+// passes that changed something and whether the levels settled. Like Evolve, it publishes after every pass.
+func (simulation *Simulation) Converge(maxIterations int) (iterations int, converged bool) {
+	for iterations < maxIterations {
+		if simulation.Evolve() == 0 {
+			return iterations, true
+		}
+		iterations++
+	}
+	return iterations, false
+}
+
+// Snapshot returns the latest published snapshot. It is safe to call from any goroutine.
+func (simulation *Simulation) Snapshot() *Snapshot {
+	return simulation.published.Load().(publication).snapshot
+}
+
+// blockSnapshot returns the latest published block light, as the layout held it (see publication). It is safe to
+// call from any goroutine.
+func (simulation *Simulation) blockSnapshot() *Snapshot {
+	return simulation.published.Load().(publication).block
+}
+
+// Saveable returns the latest published snapshot with the point lights taken back out of its sources, as it is
+// saved: point lights are not part of the grid. It is safe to call from any goroutine.
+func (simulation *Simulation) Saveable() *Snapshot {
+	published := simulation.published.Load().(publication)
+	if len(published.bases) == 0 {
+		return published.snapshot
+	}
+	width, height := published.snapshot.Size()
+	cells := published.snapshot.Cells()
+	for point, base := range published.bases {
+		cells[point.Y*width+point.X].Source = base
+	}
+	return lighting.NewSnapshot(width, height, cells)
+}
+
+// NextSourceValue returns the emission that follows current when cycling a cell's source.
+// With the defaults (MaxSource 15, BlockerInCycle), this is 0, 1, ..., 15, -1, 0, ...
+func (simulation *Simulation) NextSourceValue(current int32) int32 {
+	next := current + 1
+	if next > simulation.MaxSource {
+		if simulation.BlockerInCycle && current >= 0 {
+			return -1
+		}
+		return 0
+	}
+	if next < 0 {
+		return 0
+	}
+	return next
+}
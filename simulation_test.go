@@ -0,0 +1,237 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// Renderers read published snapshots while the simulation evolves and is edited. Run with -race: it must report
+// nothing, and a published snapshot must stay as it was published.
+func TestSimulationConcurrentReads(t *testing.T) {
+	layout := NewLayout(256, 256)
+	simulation := NewSimulation(layout)
+
+	stop := make(chan struct{})
+	var readers sync.WaitGroup
+	for r := 0; r < 4; r++ {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				snapshot := simulation.Snapshot()
+				cells := snapshot.Cells()
+				again := snapshot.Cells()
+				for i := range cells {
+					if cells[i] != again[i] {
+						t.Errorf("published snapshot changed at cell %d", i)
+						return
+					}
+				}
+			}
+		}()
+	}
+
+	for pass := 0; pass < 60; pass++ {
+		if pass%10 == 0 {
+			simulation.Layout.SetSource(Point{X: int32(pass * 4), Y: int32(pass * 3)}, 15)
+		}
+		simulation.Evolve()
+	}
+	close(stop)
+	readers.Wait()
+
+	if level := simulation.Snapshot().Cells()[0].Level; level != 15 {
+		t.Errorf("published level of the first source is %d, want 15", level)
+	}
+}
+
+func BenchmarkPublish(b *testing.B) {
+	layout := NewLayout(256, 256)
+	layout.SetSource(Point{X: 128, Y: 128}, 15)
+	simulation := NewSimulation(layout)
+	simulation.Layout.Converge(ConvergeMaxIterations)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		simulation.Publish()
+	}
+}
+
+// Evolving a settled grid is the common case for the interactive view: the pass itself is free, so this is the
+// overhead publishing adds to a frame.
+func BenchmarkEvolveSettled(b *testing.B) {
+	layout := NewLayout(256, 256)
+	layout.SetSource(Point{X: 128, Y: 128}, 15)
+	simulation := NewSimulation(layout)
+	simulation.Layout.Converge(ConvergeMaxIterations)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		simulation.Evolve()
+	}
+}
+
+func TestNextSourceValue(t *testing.T) {
+	tests := []struct {
+		name           string
+		maxSource      int32
+		blockerInCycle bool
+		from           int32
+		want           []int32
+	}{
+		{"defaults", 15, true, -1, []int32{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, -1, 0}},
+		{"extended range", 20, true, 14, []int32{15, 16, 17, 18, 19, 20, -1, 0, 1}},
+		{"no blocker in cycle", 15, false, 13, []int32{14, 15, 0, 1}},
+		{"no blocker in cycle, from a blocker", 15, false, -1, []int32{0, 1}},
+		{"short range", 3, true, 0, []int32{1, 2, 3, -1, 0}},
+		// Emissions above the range, loaded from a file or left by a wider range, go on as from the highest one.
+		{"above the range", 7, true, 12, []int32{-1, 0}},
+		{"above the range, no blocker in cycle", 7, false, 12, []int32{0, 1}},
+	}
+	for _, test := range tests {
+		simulation := NewSimulation(NewLayout(1, 1))
+		simulation.MaxSource = test.maxSource
+		simulation.BlockerInCycle = test.blockerInCycle
+
+		current := test.from
+		for i, want := range test.want {
+			next := simulation.NextSourceValue(current)
+			if next != want {
+				t.Errorf("%s: step %d from %d gave %d, want %d", test.name, i+1, current, next, want)
+				break
+			}
+			current = next
+		}
+	}
+}
+
+// With the defaults, cycling goes as it did before it was configurable: up by one from -1, with 15 wrapping to -1.
+func TestNextSourceValueLegacy(t *testing.T) {
+	legacy := func(level int32) int32 {
+		level++
+		if level >= 16 {
+			level = -1
+		}
+		return level
+	}
+	simulation := NewSimulation(NewLayout(1, 1))
+	for current := int32(-1); current <= 15; current++ {
+		if got, want := simulation.NextSourceValue(current), legacy(current); got != want {
+			t.Errorf("NextSourceValue(%d) = %d, want %d", current, got, want)
+		}
+	}
+}
+
+// Converging the simulation goes through Evolve, so it honors the mode it runs in, records the trace and publishes
+// the settled levels.
+func TestSimulationConverge(t *testing.T) {
+	layout := NewLayout(12, 3)
+	layout.SetSource(Point{X: 0, Y: 1}, 10)
+	want := Layout{layout.Clone()}
+	want.Converge(ConvergeMaxIterations)
+
+	modes := []struct {
+		name       string
+		configure  func(*Simulation)
+		iterations int
+	}{
+		{"evolve", func(*Simulation) {}, -1},
+		{"sweeps", func(simulation *Simulation) { simulation.Deterministic = true }, -1},
+		{"steps", func(simulation *Simulation) { simulation.Deterministic, simulation.Sweep = true, false }, 10},
+		{"instant", func(simulation *Simulation) { simulation.Instant = true }, 1},
+	}
+	for _, mode := range modes {
+		simulation := NewSimulation(Layout{layout.Clone()})
+		mode.configure(simulation)
+		trace, err := NewTrace(filepath.Join(t.TempDir(), "trace.csv"), true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		simulation.Trace = trace
+
+		iterations, converged := simulation.Converge(ConvergeMaxIterations)
+		if !converged || (mode.iterations >= 0 && iterations != mode.iterations) {
+			t.Errorf("%s: converged %v after %d passes, want true after %d", mode.name, converged, iterations,
+				mode.iterations)
+		}
+		if !trace.Done || trace.step != iterations+1 {
+			t.Errorf("%s: trace recorded %d passes (done %v), want %d", mode.name, trace.step, trace.Done, iterations+1)
+		}
+		for i, cell := range simulation.Snapshot().Cells() {
+			if cell.Level != want.Cells()[i].Level {
+				t.Fatalf("%s: published level of cell %d is %d, want %d",
+					mode.name, i, cell.Level, want.Cells()[i].Level)
+			}
+		}
+	}
+
+	// Steps take 10 passes to settle.
+	simulation := NewSimulation(Layout{layout.Clone()})
+	simulation.Deterministic, simulation.Sweep = true, false
+	if iterations, converged := simulation.Converge(3); converged || iterations != 3 {
+		t.Errorf("3 passes: converged %v after %d, want false after 3", converged, iterations)
+	}
+}
+
+// Dropping levels fade by DecayPerTick per pass in what is published, never below the block light, and rises show
+// at once. Blockers do not glow. The layout keeps the true levels throughout.
+func TestAfterglowSchedule(t *testing.T) {
+	layout := NewLayout(8, 1)
+	layout.SetSource(Point{X: 0, Y: 0}, 10)
+	simulation := NewSimulation(layout)
+	simulation.Instant = true
+	simulation.DecayPerTick = 3
+
+	published := func() []int32 {
+		var levels []int32
+		for _, cell := range simulation.Snapshot().Cells() {
+			levels = append(levels, cell.Level)
+		}
+		return levels
+	}
+	block := func() []int32 {
+		var levels []int32
+		for _, cell := range simulation.Layout.Cells() {
+			levels = append(levels, cell.Level)
+		}
+		return levels
+	}
+
+	simulation.Evolve()
+	if want := []int32{10, 9, 8, 7, 6, 5, 4, 3}; !reflect.DeepEqual(published(), want) {
+		t.Fatalf("published %v before the edit, want %v", published(), want)
+	}
+
+	// The torch goes, a dimmer one lights the other end, and a blocker goes in on the way.
+	simulation.Layout.SetSource(Point{X: 0, Y: 0}, 0)
+	simulation.Layout.SetSource(Point{X: 7, Y: 0}, 4)
+	simulation.Layout.SetSource(Point{X: 2, Y: 0}, -1)
+	schedule := [][]int32{
+		{7, 6, 0, 4, 3, 2, 3, 4},
+		{4, 3, 0, 1, 1, 2, 3, 4},
+		{1, 0, 0, 0, 1, 2, 3, 4},
+		{0, 0, 0, 0, 1, 2, 3, 4},
+		{0, 0, 0, 0, 1, 2, 3, 4},
+	}
+	for pass, want := range schedule {
+		simulation.Evolve()
+		if !reflect.DeepEqual(published(), want) {
+			t.Errorf("pass %d published %v, want %v", pass+1, published(), want)
+		}
+		if want := []int32{0, 0, 0, 0, 1, 2, 3, 4}; !reflect.DeepEqual(block(), want) {
+			t.Errorf("pass %d left block light %v, want %v", pass+1, block(), want)
+		}
+	}
+
+	simulation.Layout.SetSource(Point{X: 2, Y: 0}, 0)
+	simulation.Layout.SetSource(Point{X: 0, Y: 0}, 15)
+	simulation.Evolve()
+	if want := []int32{15, 14, 13, 12, 11, 10, 9, 8}; !reflect.DeepEqual(published(), want) {
+		t.Errorf("published %v after relighting, want %v", published(), want)
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+// LightView is which light field the grid shows: Minecraft keeps block light and sky light apart, and renders the
+// brighter of the two.
+type LightView int
+
+const (
+	// ViewBlock shows block light only. Sky light is not computed.
+	ViewBlock LightView = iota
+	// ViewCombined shows the brighter of block and sky light, as Minecraft renders it.
+	ViewCombined
+	// ViewSky shows sky light only.
+	ViewSky
+)
+
+func (view LightView) String() string {
+	switch view {
+	case ViewCombined:
+		return "block and sky light"
+	case ViewSky:
+		return "sky light"
+	default:
+		return "block light"
+	}
+}
+
+// next is the view <I> switches to: block, combined, sky, then block again.
+func (view LightView) next() LightView {
+	return (view + 1) % 3
+}
+
+// level is the light level the view shows a cell with, its sky light dimmed by darkening levels (see SkyDarkening).
+func (view LightView) level(cell Cell, darkening int32) int32 {
+	switch view {
+	case ViewCombined:
+		return int32Max(cell.Level, darkenedSky(cell, darkening))
+	case ViewSky:
+		return darkenedSky(cell, darkening)
+	default:
+		return cell.Level
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// SaveSlotCount is the number of quick-save slots, one per digit key.
+const SaveSlotCount = 10
+
+// SaveSlots keeps quick saves of the grid's emissions, one file per slot in Dir, in the ASCII format (see
+// ParseASCII) so that slots can be edited by hand and passed to -layout.
+type SaveSlots struct {
+	Dir string
+
+	// Slot last saved to or loaded from, -1 if none
+	Current int
+}
+
+// NewSaveSlots returns slots kept in dir, none of them current.
+func NewSaveSlots(dir string) *SaveSlots {
+	return &SaveSlots{Dir: dir, Current: -1}
+}
+
+// Path is the file slot n is kept in.
+func (slots *SaveSlots) Path(n int) string {
+	return filepath.Join(slots.Dir, "slot-"+strconv.Itoa(n)+".txt")
+}
+
+// Save writes the emissions of the layout to slot n, replacing what was there, and makes it current.
+func (slots *SaveSlots) Save(n int, layout Layout) error {
+	if n < 0 || n >= SaveSlotCount {
+		return fmt.Errorf("%w: slot %d", ErrOutOfRange, n)
+	}
+	if err := os.MkdirAll(slots.Dir, 0o755); err != nil {
+		return err
+	}
+	if err := saveLayoutFile(slots.Path(n), layout); err != nil {
+		return err
+	}
+	slots.Current = n
+	return nil
+}
+
+// Load reads slot n and makes it current. The layout comes back unlit, as LayoutFromEmissions returns it.
+// An empty slot gives an error that matches os.ErrNotExist, and a slot saved with another grid size an
+// ErrMalformedInput one.
+func (slots *SaveSlots) Load(n int, width int32, height int32) (Layout, error) {
+	if n < 0 || n >= SaveSlotCount {
+		return Layout{}, fmt.Errorf("%w: slot %d", ErrOutOfRange, n)
+	}
+	layout, err := loadLayoutFile(slots.Path(n))
+	if err != nil {
+		return Layout{}, err
+	}
+	if loadedWidth, loadedHeight := layout.Size(); loadedWidth != width || loadedHeight != height {
+		return Layout{}, fmt.Errorf("%w: slot %d holds a %dx%d grid, this one is %dx%d",
+			ErrMalformedInput, n, loadedWidth, loadedHeight, width, height)
+	}
+	slots.Current = n
+	return layout, nil
+}
+
+// status describes the current slot for the footer, or is empty if there is none.
+func (slots *SaveSlots) status() string {
+	if slots.Current < 0 {
+		return ""
+	}
+	return fmt.Sprintf("slot %d", slots.Current)
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestSaveSlotsRoundTrip(t *testing.T) {
+	slots := NewSaveSlots(t.TempDir())
+	layout := NewLayout(6, 4)
+	layout.SetSource(Point{X: 1, Y: 2}, 15)
+	layout.SetSource(Point{X: 4, Y: 0}, -1)
+	layout.SetCap(Point{X: 3, Y: 3}, 6)
+	if err := slots.Save(3, layout); err != nil {
+		t.Fatal(err)
+	}
+	if slots.Current != 3 {
+		t.Errorf("current slot is %d, want 3", slots.Current)
+	}
+
+	loaded, err := slots.Load(3, 6, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertSources(t, loaded, layout)
+	if levelCap := loaded.Get(Point{X: 3, Y: 3}).Cap; levelCap != 6 {
+		t.Errorf("loaded cap is %d, want 6", levelCap)
+	}
+
+	if _, err := slots.Load(4, 6, 4); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("loading an empty slot gave %v, want an os.ErrNotExist error", err)
+	}
+	if _, err := slots.Load(3, 7, 4); !errors.Is(err, ErrMalformedInput) {
+		t.Errorf("loading a slot of another size gave %v, want an ErrMalformedInput error", err)
+	}
+	if err := slots.Save(SaveSlotCount, layout); !errors.Is(err, ErrOutOfRange) {
+		t.Errorf("saving to slot %d gave %v, want an ErrOutOfRange error", SaveSlotCount, err)
+	}
+}
+
+// Point lights are not part of the grid: a slot saved with one over a cell holds the cell's own emission, so that
+// loading it and moving the light leaves nothing behind.
+func TestSaveSlotsWithoutPointLights(t *testing.T) {
+	slots := NewSaveSlots(t.TempDir())
+	layout := NewLayout(6, 4)
+	layout.SetSource(Point{X: 4, Y: 1}, 3)
+	lights := &PointLights{Lights: []PointLight{{X: 1.5, Y: 1.5, Emission: 12}, {X: 4.5, Y: 1.5, Emission: 9}}}
+	lights.Rasterize(layout)
+
+	if err := slots.Save(0, lights.Unrasterized(layout)); err != nil {
+		t.Fatal(err)
+	}
+	if source := layout.Get(Point{X: 1, Y: 1}).Source; source != 12 {
+		t.Errorf("saving changed the live source under the light to %d", source)
+	}
+
+	loaded, err := slots.Load(0, 6, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := NewLayout(6, 4)
+	want.SetSource(Point{X: 4, Y: 1}, 3)
+	assertSources(t, loaded, want)
+
+	lights.Forget()
+	lights.Rasterize(loaded)
+	lights.Lights[0].X = 2.5
+	lights.Rasterize(loaded)
+	want.SetSource(Point{X: 2, Y: 1}, 12)
+	want.SetSource(Point{X: 4, Y: 1}, 9)
+	assertSources(t, loaded, want)
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"github.com/gen2brain/raylib-go/raylib"
+)
+
+// HatchSpacingPx is the distance between the stripes drawn over suppressed cells.
+const HatchSpacingPx = int32(6)
+
+// raylibDrawHatching strikes the square at (x, y) (in pixels) through with diagonal stripes, marking a cell whose
+// light updates are suppressed.
+func raylibDrawHatching(x int32, y int32) {
+	color := rl.ColorAlpha(rl.DarkPurple, 0.6)
+	side := SquareSideLengthPx
+	for offset := HatchSpacingPx; offset < 2*side; offset += HatchSpacingPx {
+		// Each stripe runs from the left or top edge to the bottom or right edge.
+		startX, startY := x, y+offset
+		if offset > side {
+			startX, startY = x+offset-side, y+side
+		}
+		endX, endY := x+offset, y
+		if offset > side {
+			endX, endY = x+side, y+offset-side
+		}
+		rl.DrawLine(startX, startY, endX, endY, color)
+	}
+}
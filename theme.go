@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"github.com/gen2brain/raylib-go/raylib"
+)
+
+// Theme holds the colors of everything around the grid.
+type Theme struct {
+	Name       string
+	Background rl.Color
+	Text       rl.Color
+}
+
+var (
+	LightTheme = Theme{Name: "light", Background: rl.RayWhite, Text: rl.Black}
+	DarkTheme  = Theme{Name: "dark", Background: rl.NewColor(28, 28, 32, 255), Text: rl.LightGray}
+)
+
+// Theme in use
+var theme = LightTheme
+
+// ReducedMotionMaxPasses caps how many evolve passes a frame may run to snap to the steady state when reduced
+// motion is on.
+const ReducedMotionMaxPasses = 256
+
+// chooseTheme picks the theme from the -theme setting: "light", "dark", or "auto" to follow the OS preference
+// (light if it cannot be detected).
+func chooseTheme(setting string) (Theme, error) {
+	switch setting {
+	case "light":
+		return LightTheme, nil
+	case "dark":
+		return DarkTheme, nil
+	case "auto":
+		if dark, ok := detectDarkMode(); ok && dark {
+			return DarkTheme, nil
+		}
+		return LightTheme, nil
+	default:
+		return Theme{}, fmt.Errorf("%w: unknown theme %q (want auto, light or dark)", ErrMalformedInput, setting)
+	}
+}
+
+// chooseReducedMotion resolves the -reduced-motion setting: "on", "off", or "auto" to follow the OS preference
+// (off if it cannot be detected).
+func chooseReducedMotion(setting string) (bool, error) {
+	switch setting {
+	case "on":
+		return true, nil
+	case "off":
+		return false, nil
+	case "auto":
+		reduced, ok := detectReducedMotion()
+		return ok && reduced, nil
+	default:
+		return false, fmt.Errorf("%w: unknown reduced motion setting %q (want auto, on or off)", ErrMalformedInput, setting)
+	}
+}
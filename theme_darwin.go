@@ -0,0 +1,25 @@
+package main
+
+import (
+	"os/exec"
+	"strings"
+)
+
+// detectDarkMode reads the global AppleInterfaceStyle default, which is "Dark" in dark mode and unset otherwise.
+func detectDarkMode() (dark bool, ok bool) {
+	out, err := exec.Command("defaults", "read", "-g", "AppleInterfaceStyle").Output()
+	if err != nil {
+		// Unset means light mode.
+		return false, true
+	}
+	return strings.TrimSpace(string(out)) == "Dark", true
+}
+
+// detectReducedMotion reads the accessibility "Reduce motion" setting.
+func detectReducedMotion() (reduced bool, ok bool) {
+	out, err := exec.Command("defaults", "read", "com.apple.universalaccess", "reduceMotion").Output()
+	if err != nil {
+		return false, false
+	}
+	return strings.TrimSpace(string(out)) == "1", true
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// gsettings reads a GNOME desktop setting, if gsettings is available.
+func gsettings(schema string, key string) (string, bool) {
+	out, err := exec.Command("gsettings", "get", schema, key).Output()
+	if err != nil {
+		return "", false
+	}
+	return strings.Trim(strings.TrimSpace(string(out)), "'"), true
+}
+
+// detectDarkMode looks at GTK_THEME (e.g. "Adwaita:dark"), then at the GNOME color scheme.
+func detectDarkMode() (dark bool, ok bool) {
+	if gtkTheme := os.Getenv("GTK_THEME"); gtkTheme != "" {
+		return strings.HasSuffix(strings.ToLower(gtkTheme), ":dark"), true
+	}
+	if scheme, ok := gsettings("org.gnome.desktop.interface", "color-scheme"); ok {
+		return scheme == "prefer-dark", true
+	}
+	return false, false
+}
+
+// detectReducedMotion reads GNOME's "enable animations" setting.
+func detectReducedMotion() (reduced bool, ok bool) {
+	if enabled, ok := gsettings("org.gnome.desktop.interface", "enable-animations"); ok {
+		return enabled == "false", true
+	}
+	return false, false
+}
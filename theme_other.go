@@ -0,0 +1,14 @@
+//go:build !darwin && !linux && !windows
+// +build !darwin,!linux,!windows
+
+package main
+
+// detectDarkMode cannot tell on this platform.
+func detectDarkMode() (dark bool, ok bool) {
+	return false, false
+}
+
+// detectReducedMotion cannot tell on this platform.
+func detectReducedMotion() (reduced bool, ok bool) {
+	return false, false
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"os/exec"
+	"strings"
+)
+
+// regQuery reads a registry value's data (the last field of reg's output), if it exists.
+func regQuery(key string, value string) (string, bool) {
+	out, err := exec.Command("reg", "query", key, "/v", value).Output()
+	if err != nil {
+		return "", false
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 3 && strings.EqualFold(fields[0], value) {
+			return fields[len(fields)-1], true
+		}
+	}
+	return "", false
+}
+
+// detectDarkMode reads AppsUseLightTheme, which is 0 when apps should use dark mode.
+func detectDarkMode() (dark bool, ok bool) {
+	data, ok := regQuery(`HKCU\Software\Microsoft\Windows\CurrentVersion\Themes\Personalize`, "AppsUseLightTheme")
+	if !ok {
+		return false, false
+	}
+	return data == "0x0", true
+}
+
+// detectReducedMotion reads MinAnimate, which is "0" when "Show animations in Windows" is off.
+func detectReducedMotion() (reduced bool, ok bool) {
+	data, ok := regQuery(`HKCU\Control Panel\Desktop\WindowMetrics`, "MinAnimate")
+	if !ok {
+		return false, false
+	}
+	return data == "0", true
+}
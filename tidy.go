@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Columns selects the optional columns of a tidy CSV export. x and y are always written.
+type Columns uint
+
+const (
+	ColumnSource Columns = 1 << iota
+	ColumnLevel
+	ColumnOpacity
+	ColumnMedium
+	ColumnOwner
+	ColumnRoom
+	ColumnBand
+	ColumnCap
+
+	AllColumns = ColumnSource | ColumnLevel | ColumnOpacity | ColumnMedium | ColumnOwner | ColumnRoom | ColumnBand |
+		ColumnCap
+)
+
+// columnNames are the names ParseColumns takes, in the order columns are written.
+var columnNames = []struct {
+	column Columns
+	name   string
+}{
+	{ColumnSource, "source"},
+	{ColumnLevel, "level"},
+	{ColumnOpacity, "opacity"},
+	{ColumnMedium, "medium"},
+	{ColumnOwner, "owner"},
+	{ColumnRoom, "room"},
+	{ColumnBand, "band"},
+	{ColumnCap, "cap"},
+}
+
+// ParseColumns reads a comma-separated list of column names, such as "source,level,band", or "all".
+func ParseColumns(spec string) (Columns, error) {
+	if strings.TrimSpace(spec) == "all" {
+		return AllColumns, nil
+	}
+	var columns Columns
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		found := false
+		for _, named := range columnNames {
+			if named.name == part {
+				columns |= named.column
+				found = true
+			}
+		}
+		if !found {
+			return 0, fmt.Errorf("%w: unknown column %q", ErrMalformedInput, part)
+		}
+	}
+	return columns, nil
+}
+
+// Owner is the source a cell's light comes from, and how many steps away it is.
+type Owner struct {
+	Source   Point
+	Distance int32
+}
+
+// OwnerField returns, per cell, the source that lights it the most once the light has settled, indexed as
+// field[y][x]. Dark cells and blockers have none. On a tie, the source first in row order wins.
+//
+// It floods from every source at once, brightest first, so each cell is claimed by the first source to reach it
+// with its final level. Caps clamp the levels on the way, as in propagation.
+func (layout Layout) OwnerField() [][]*Owner {
+	width, height := layout.Size()
+	field := make([][]*Owner, height)
+	levels := make([][]int32, height)
+	// Cells claimed at each level, the frontier to flood from when that level comes up.
+	frontier := make([][]Point, 16)
+	for y := int32(0); y < height; y++ {
+		field[y] = make([]*Owner, width)
+		levels[y] = make([]int32, width)
+		for x := int32(0); x < width; x++ {
+			if cell := layout.Get(Point{X: x, Y: y}); cell.Source > 0 && cell.Source <= 15 {
+				level := cell.Capped(cell.Source)
+				field[y][x] = &Owner{Source: Point{X: x, Y: y}}
+				levels[y][x] = level
+				frontier[level] = append(frontier[level], Point{X: x, Y: y})
+			}
+		}
+	}
+
+	for level := int32(15); level > 0; level-- {
+		for _, point := range frontier[level] {
+			// Skip cells that something brighter has claimed since.
+			if levels[point.Y][point.X] != level || level == 1 {
+				continue
+			}
+			owner := field[point.Y][point.X]
+			for _, neighbor := range point.Neighbors() {
+				// Off the grid, Get returns a blocker.
+				cell := layout.Get(neighbor)
+				next := cell.Capped(level - 1)
+				if cell.Source < 0 || levels[neighbor.Y][neighbor.X] >= next {
+					continue
+				}
+				field[neighbor.Y][neighbor.X] = &Owner{Source: owner.Source, Distance: owner.Distance + 1}
+				levels[neighbor.Y][neighbor.X] = next
+				frontier[next] = append(frontier[next], neighbor)
+			}
+		}
+	}
+	return field
+}
+
+// WriteTidyCSV writes one row per cell, row by row, with a header: x and y, then the included columns.
+// Columns with no data for a cell are left empty rather than zero, so that analysis tools read them as missing:
+// owner_x, owner_y and owner_distance for unlit cells, band for levels outside every band (see -bands), cap for
+// cells with no light cap.
+// This tree has no opacity, medium or room data, so those columns are always empty.
+func (layout Layout) WriteTidyCSV(w io.Writer, include Columns) error {
+	header := []string{"x", "y"}
+	for _, named := range columnNames {
+		if include&named.column == 0 {
+			continue
+		}
+		if named.column == ColumnOwner {
+			header = append(header, "owner_x", "owner_y", "owner_distance")
+		} else {
+			header = append(header, named.name)
+		}
+	}
+
+	var owners [][]*Owner
+	if include&ColumnOwner != 0 {
+		owners = layout.OwnerField()
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	width, height := layout.Size()
+	for y := int32(0); y < height; y++ {
+		for x := int32(0); x < width; x++ {
+			cell := layout.Get(Point{X: x, Y: y})
+			record := []string{strconv.Itoa(int(x)), strconv.Itoa(int(y))}
+			if include&ColumnSource != 0 {
+				record = append(record, strconv.Itoa(int(cell.Source)))
+			}
+			if include&ColumnLevel != 0 {
+				record = append(record, strconv.Itoa(int(cell.Level)))
+			}
+			if include&ColumnOpacity != 0 {
+				record = append(record, "")
+			}
+			if include&ColumnMedium != 0 {
+				record = append(record, "")
+			}
+			if include&ColumnOwner != 0 {
+				if owner := owners[y][x]; owner != nil {
+					record = append(record, strconv.Itoa(int(owner.Source.X)), strconv.Itoa(int(owner.Source.Y)),
+						strconv.Itoa(int(owner.Distance)))
+				} else {
+					record = append(record, "", "", "")
+				}
+			}
+			if include&ColumnRoom != 0 {
+				record = append(record, "")
+			}
+			if include&ColumnBand != 0 {
+				band := ""
+				if i := bandOf(bands, cell.Level); i >= 0 && cell.Source >= 0 {
+					band = bands[i].Name
+				}
+				record = append(record, band)
+			}
+			if include&ColumnCap != 0 {
+				levelCap := ""
+				if cell.Cap > 0 {
+					levelCap = strconv.Itoa(int(cell.Cap))
+				}
+				record = append(record, levelCap)
+			}
+			if err := writer.Write(record); err != nil {
+				return err
+			}
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
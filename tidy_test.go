@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// tidyTestLayout is a converged 4x2 layout: a source lighting around a blocker, through a cell capped at 5.
+func tidyTestLayout() Layout {
+	layout := NewLayout(4, 2)
+	layout.SetSource(Point{X: 0, Y: 0}, 12)
+	layout.SetSource(Point{X: 2, Y: 0}, -1)
+	layout.SetCap(Point{X: 3, Y: 1}, 5)
+	layout.Converge(ConvergeMaxIterations)
+	return layout
+}
+
+func TestWriteTidyCSV(t *testing.T) {
+	tests := []struct {
+		columns Columns
+		want    string
+	}{
+		{AllColumns, "" +
+			"x,y,source,level,opacity,medium,owner_x,owner_y,owner_distance,room,band,cap\n" +
+			"0,0,12,12,,,0,0,0,,bright,\n" +
+			"1,0,0,11,,,0,0,1,,safe,\n" +
+			"2,0,-1,0,,,,,,,,\n" +
+			"3,0,0,4,,,0,0,5,,dim,\n" +
+			"0,1,0,11,,,0,0,1,,safe,\n" +
+			"1,1,0,10,,,0,0,2,,safe,\n" +
+			"2,1,0,9,,,0,0,3,,safe,\n" +
+			"3,1,0,5,,,0,0,4,,dim,5\n"},
+		{ColumnLevel | ColumnCap, "" +
+			"x,y,level,cap\n" +
+			"0,0,12,\n" +
+			"1,0,11,\n" +
+			"2,0,0,\n" +
+			"3,0,4,\n" +
+			"0,1,11,\n" +
+			"1,1,10,\n" +
+			"2,1,9,\n" +
+			"3,1,5,5\n"},
+		{0, "x,y\n0,0\n1,0\n2,0\n3,0\n0,1\n1,1\n2,1\n3,1\n"},
+	}
+	layout := tidyTestLayout()
+	for _, test := range tests {
+		var output bytes.Buffer
+		if err := layout.WriteTidyCSV(&output, test.columns); err != nil {
+			t.Fatal(err)
+		}
+		if output.String() != test.want {
+			t.Errorf("columns %b:\n%swant\n%s", test.columns, output.String(), test.want)
+		}
+	}
+}
+
+// Levels no band covers get an empty band, not the first band or a zero.
+func TestWriteTidyCSVOutsideBands(t *testing.T) {
+	previous := bands
+	bands = []Band{{Name: "lit", Min: 1, Max: 15}}
+	defer func() { bands = previous }()
+
+	layout := NewLayout(3, 1)
+	layout.SetSource(Point{X: 0, Y: 0}, 2)
+	layout.Converge(ConvergeMaxIterations)
+
+	var output bytes.Buffer
+	if err := layout.WriteTidyCSV(&output, ColumnLevel|ColumnBand|ColumnOwner); err != nil {
+		t.Fatal(err)
+	}
+	want := "x,y,level,owner_x,owner_y,owner_distance,band\n" +
+		"0,0,2,0,0,0,lit\n" +
+		"1,0,1,0,0,1,lit\n" +
+		"2,0,0,,,,\n"
+	if output.String() != want {
+		t.Errorf("got\n%swant\n%s", output.String(), want)
+	}
+}
+
+func TestParseColumns(t *testing.T) {
+	tests := []struct {
+		spec string
+		want Columns
+	}{
+		{"all", AllColumns},
+		{" all ", AllColumns},
+		{"level", ColumnLevel},
+		{"source, level,band", ColumnSource | ColumnLevel | ColumnBand},
+		{"band,source", ColumnSource | ColumnBand},
+		{"owner,cap", ColumnOwner | ColumnCap},
+	}
+	for _, test := range tests {
+		if got, err := ParseColumns(test.spec); err != nil || got != test.want {
+			t.Errorf("ParseColumns(%q) = %b, %v; want %b", test.spec, got, err, test.want)
+		}
+	}
+	for _, spec := range []string{"", "level,", "x", "levels", "all,level"} {
+		if _, err := ParseColumns(spec); !errors.Is(err, ErrMalformedInput) {
+			t.Errorf("ParseColumns(%q) gave %v, want ErrMalformedInput", spec, err)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"github.com/gen2brain/raylib-go/raylib"
+)
+
+// Stamp is a small pattern of emissions, placed by its top-left corner.
+type Stamp struct {
+	Width  int32
+	Height int32
+
+	// Row-major, Width*Height entries. 0 leaves the cell under it alone.
+	Sources []int32
+}
+
+// At returns the emission of the stamp at (dx, dy) from its top-left corner.
+func (stamp Stamp) At(dx int32, dy int32) int32 {
+	return stamp.Sources[dy*stamp.Width+dx]
+}
+
+// Rect is a rectangle of cells.
+type Rect struct {
+	X      int32
+	Y      int32
+	Width  int32
+	Height int32
+}
+
+// WholeGrid returns the Rect covering every cell of the layout.
+func (layout Layout) WholeGrid() Rect {
+	width, height := layout.Size()
+	return Rect{X: 0, Y: 0, Width: width, Height: height}
+}
+
+// Spacing says where repeated stamps go: every DX cells horizontally and DY vertically, shifted by the offsets.
+type Spacing struct {
+	DX      int32
+	DY      int32
+	OffsetX int32
+	OffsetY int32
+}
+
+// mod is the modulo that stays non-negative for negative a.
+func mod(a int32, b int32) int32 {
+	return (a%b + b) % b
+}
+
+// firstOnLattice returns the first position at or after start that is offset modulo step.
+func firstOnLattice(start int32, step int32, offset int32) int32 {
+	return start + mod(offset-start, step)
+}
+
+// Tile enumerates where a stamp repeats across the region with the given spacing. Anchors sit on the lattice of
+// positions (OffsetX + i*DX, OffsetY + j*DY), in grid coordinates; a placement counts only if the whole stamp fits
+// in the region. Placements where any emitting cell of the stamp would land on a blocker are skipped.
+//
+// It returns the anchors to place and the anchors skipped because of blockers. Nothing is written to the layout.
+// Non-positive spacing, an empty stamp or an empty region yields no placements at all.
+func (layout Layout) Tile(stamp Stamp, region Rect, spacing Spacing) ([]Point, []Point) {
+	placed := []Point{}
+	skipped := []Point{}
+	if spacing.DX <= 0 || spacing.DY <= 0 || stamp.Width <= 0 || stamp.Height <= 0 {
+		return placed, skipped
+	}
+
+	for y := firstOnLattice(region.Y, spacing.DY, spacing.OffsetY); y+stamp.Height <= region.Y+region.Height; y += spacing.DY {
+		for x := firstOnLattice(region.X, spacing.DX, spacing.OffsetX); x+stamp.Width <= region.X+region.Width; x += spacing.DX {
+			anchor := Point{X: x, Y: y}
+			if layout.stampCollides(stamp, anchor) {
+				skipped = append(skipped, anchor)
+			} else {
+				placed = append(placed, anchor)
+			}
+		}
+	}
+	return placed, skipped
+}
+
+// stampCollides tells if any emitting cell of the stamp at anchor would land on a blocker or off the grid.
+func (layout Layout) stampCollides(stamp Stamp, anchor Point) bool {
+	for dy := int32(0); dy < stamp.Height; dy++ {
+		for dx := int32(0); dx < stamp.Width; dx++ {
+			if stamp.At(dx, dy) == 0 {
+				continue
+			}
+			// Off the grid, Get returns a blocker.
+			if layout.Get(Point{X: anchor.X + dx, Y: anchor.Y + dy}).Source < 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// PlaceStamp writes the stamp's emissions into the layout with its top-left corner at anchor.
+func (layout Layout) PlaceStamp(stamp Stamp, anchor Point) {
+	for dy := int32(0); dy < stamp.Height; dy++ {
+		for dx := int32(0); dx < stamp.Width; dx++ {
+			source := stamp.At(dx, dy)
+			if source == 0 {
+				continue
+			}
+			layout.SetSource(Point{X: anchor.X + dx, Y: anchor.Y + dy}, source)
+		}
+	}
+}
+
+// raylibDrawTilePreview outlines where a stamp would be placed (green) and where it would be skipped (red).
+func raylibDrawTilePreview(stamp Stamp, placed []Point, skipped []Point) {
+	outline := func(anchors []Point, color rl.Color) {
+		for _, anchor := range anchors {
+			rl.DrawRectangleLinesEx(rl.NewRectangle(
+				float32(anchor.X*SquareSideLengthPx), float32(anchor.Y*SquareSideLengthPx),
+				float32(stamp.Width*SquareSideLengthPx), float32(stamp.Height*SquareSideLengthPx)), 3, color)
+		}
+	}
+	outline(placed, rl.DarkGreen)
+	outline(skipped, rl.Red)
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+var torch = Stamp{Width: 1, Height: 1, Sources: []int32{14}}
+
+// corridor is a 3x2 stamp with a torch in the top middle and nothing else.
+var corridor = Stamp{Width: 3, Height: 2, Sources: []int32{0, 14, 0, 0, 0, 0}}
+
+func TestTile(t *testing.T) {
+	tests := []struct {
+		name    string
+		stamp   Stamp
+		region  Rect
+		spacing Spacing
+		want    []Point
+	}{
+		{"offsets", torch, Rect{0, 0, 10, 6}, Spacing{DX: 4, DY: 3, OffsetX: 1, OffsetY: 2},
+			[]Point{{X: 1, Y: 2}, {X: 5, Y: 2}, {X: 9, Y: 2}, {X: 1, Y: 5}, {X: 5, Y: 5}, {X: 9, Y: 5}}},
+		{"offsets past the spacing", torch, Rect{0, 0, 10, 6}, Spacing{DX: 4, DY: 3, OffsetX: 9, OffsetY: -1},
+			[]Point{{X: 1, Y: 2}, {X: 5, Y: 2}, {X: 9, Y: 2}, {X: 1, Y: 5}, {X: 5, Y: 5}, {X: 9, Y: 5}}},
+		{"stamp ending on the edge", corridor, Rect{0, 0, 10, 2}, Spacing{DX: 7, DY: 2},
+			[]Point{{X: 0, Y: 0}, {X: 7, Y: 0}}},
+		{"stamp past the edge", corridor, Rect{0, 0, 10, 3}, Spacing{DX: 4, DY: 2},
+			[]Point{{X: 0, Y: 0}, {X: 4, Y: 0}}},
+		// The lattice is in grid coordinates, not the region's.
+		{"region off the origin", torch, Rect{3, 1, 5, 3}, Spacing{DX: 2, DY: 2},
+			[]Point{{X: 4, Y: 2}, {X: 6, Y: 2}}},
+	}
+	layout := NewLayout(10, 6)
+	for _, test := range tests {
+		placed, skipped := layout.Tile(test.stamp, test.region, test.spacing)
+		if !reflect.DeepEqual(placed, test.want) || len(skipped) != 0 {
+			t.Errorf("%s: placed %v and skipped %v, want %v placed", test.name, placed, skipped, test.want)
+		}
+	}
+}
+
+func TestTileEmpty(t *testing.T) {
+	layout := NewLayout(10, 6)
+	tests := []struct {
+		name    string
+		stamp   Stamp
+		region  Rect
+		spacing Spacing
+	}{
+		{"no horizontal spacing", torch, layout.WholeGrid(), Spacing{DX: 0, DY: 2}},
+		{"negative spacing", torch, layout.WholeGrid(), Spacing{DX: 2, DY: -2}},
+		{"empty stamp", Stamp{}, layout.WholeGrid(), Spacing{DX: 2, DY: 2}},
+		{"empty region", torch, Rect{2, 2, 0, 3}, Spacing{DX: 1, DY: 1}},
+		{"stamp larger than the region", corridor, Rect{0, 0, 2, 6}, Spacing{DX: 1, DY: 1}},
+		{"no lattice position in the region", torch, Rect{1, 1, 2, 2}, Spacing{DX: 5, DY: 5}},
+	}
+	for _, test := range tests {
+		placed, skipped := layout.Tile(test.stamp, test.region, test.spacing)
+		// Empty, not nil, for the preview and the skip count.
+		if placed == nil || skipped == nil || len(placed) != 0 || len(skipped) != 0 {
+			t.Errorf("%s: placed %#v and skipped %#v, want both empty", test.name, placed, skipped)
+		}
+	}
+}
+
+func TestTileSkipsBlockers(t *testing.T) {
+	layout := NewLayout(9, 2)
+	// Under the torch of the second placement, and under an empty cell of the third.
+	layout.SetSource(Point{X: 4, Y: 0}, -1)
+	layout.SetSource(Point{X: 8, Y: 1}, -1)
+
+	placed, skipped := layout.Tile(corridor, layout.WholeGrid(), Spacing{DX: 3, DY: 2})
+	if want := []Point{{X: 0, Y: 0}, {X: 6, Y: 0}}; !reflect.DeepEqual(placed, want) {
+		t.Errorf("placed %v, want %v", placed, want)
+	}
+	if want := []Point{{X: 3, Y: 0}}; !reflect.DeepEqual(skipped, want) {
+		t.Errorf("skipped %v, want %v", skipped, want)
+	}
+
+	for _, anchor := range placed {
+		layout.PlaceStamp(corridor, anchor)
+	}
+	for x := int32(0); x < 9; x++ {
+		want := int32(0)
+		switch x {
+		case 1, 7:
+			want = 14
+		case 4:
+			want = -1
+		}
+		if source := layout.Get(Point{X: x, Y: 0}).Source; source != want {
+			t.Errorf("source at (%d, 0) is %d, want %d", x, source, want)
+		}
+	}
+	if source := layout.Get(Point{X: 8, Y: 1}).Source; source != -1 {
+		t.Errorf("the stamp's empty cell overwrote the blocker at (8, 1) with %d", source)
+	}
+}
+
+// Off the grid counts as a blocker: a region reaching past the grid skips the placements that land outside.
+func TestTileOffTheGrid(t *testing.T) {
+	layout := NewLayout(10, 6)
+	placed, skipped := layout.Tile(torch, Rect{X: 8, Y: 4, Width: 5, Height: 2}, Spacing{DX: 2, DY: 2})
+	if want := []Point{{X: 8, Y: 4}}; !reflect.DeepEqual(placed, want) {
+		t.Errorf("placed %v, want %v", placed, want)
+	}
+	if want := []Point{{X: 10, Y: 4}, {X: 12, Y: 4}}; !reflect.DeepEqual(skipped, want) {
+		t.Errorf("skipped %v, want %v", skipped, want)
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/csv"
+	"os"
+	"strconv"
+)
+
+// Trace records the light level of every cell after every evolve pass, as "step,x,y,source,level" CSV rows.
+// Each pass is flushed as it is written, so the file can be followed while the simulation runs. Recording stops
+// by itself at the first pass that changes nothing, which keeps the file bounded.
+type Trace struct {
+	// If set, only cells whose level changed in a pass are written (all of them for the first pass).
+	ChangedOnly bool
+
+	// Set once the levels have settled, or the trace was closed
+	Done bool
+
+	file   *os.File
+	writer *csv.Writer
+	step   int
+
+	// Levels after the previous pass, indexed y*width+x
+	previous []int32
+}
+
+// NewTrace creates the trace file at path, replacing any file there, and writes the header.
+func NewTrace(path string, changedOnly bool) (*Trace, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	trace := &Trace{ChangedOnly: changedOnly, file: file, writer: csv.NewWriter(file)}
+	if err := trace.writer.Write([]string{"step", "x", "y", "source", "level"}); err != nil {
+		file.Close()
+		return nil, err
+	}
+	trace.writer.Flush()
+	return trace, trace.writer.Error()
+}
+
+// Record writes the state of the layout after a pass that changed `changed` cells. It does nothing once Done.
+func (trace *Trace) Record(layout Layout, changed int) error {
+	if trace.Done {
+		return nil
+	}
+	trace.step++
+
+	cells := layout.Cells()
+	first := len(trace.previous) != len(cells)
+	if first {
+		trace.previous = make([]int32, len(cells))
+	}
+	step := strconv.Itoa(trace.step)
+	for i, cell := range cells {
+		if trace.ChangedOnly && !first && cell.Level == trace.previous[i] {
+			continue
+		}
+		trace.previous[i] = cell.Level
+		record := []string{
+			step,
+			strconv.Itoa(i % int(layout.Width)),
+			strconv.Itoa(i / int(layout.Width)),
+			strconv.Itoa(int(cell.Source)),
+			strconv.Itoa(int(cell.Level)),
+		}
+		if err := trace.writer.Write(record); err != nil {
+			return err
+		}
+	}
+	trace.writer.Flush()
+	if err := trace.writer.Error(); err != nil {
+		return err
+	}
+
+	if changed == 0 {
+		trace.Done = true
+		return trace.file.Close()
+	}
+	return nil
+}
+
+// Close stops recording and closes the file, unless the trace already did when the levels settled.
+func (trace *Trace) Close() error {
+	if trace.Done {
+		return nil
+	}
+	trace.Done = true
+	return trace.file.Close()
+}
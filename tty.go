@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+)
+
+// TTYFrameInterval is how long the terminal renderer waits between evolve passes, the same 10 FPS as the window.
+const TTYFrameInterval = 100 * time.Millisecond
+
+// ansi256 returns the entry of the xterm 256-color cube closest to c.
+func ansi256(c color.RGBA) int {
+	step := func(v uint8) int {
+		return (int(v)*5 + 127) / 255
+	}
+	return 16 + 36*step(c.R) + 6*step(c.G) + step(c.B)
+}
+
+// WriteTTYFrame draws a snapshot as text with ANSI colors, over whatever the previous frame left on the terminal.
+// Each cell is two characters wide, with its on-screen color as background: sources show their emission, blockers
+// are "##", and other cells are blank.
+func WriteTTYFrame(w io.Writer, snapshot *Snapshot) error {
+	var frame strings.Builder
+	frame.WriteString("\x1b[H")
+
+	width, height := snapshot.Size()
+	for y := int32(0); y < height; y++ {
+		for x := int32(0); x < width; x++ {
+			cell, _ := snapshot.At(Point{X: x, Y: y})
+			text := "  "
+			switch {
+			case cell.Source < 0:
+				text = "##"
+			case cell.Source > 0:
+				text = fmt.Sprintf("%2d", cell.Source)
+			}
+			fmt.Fprintf(&frame, "\x1b[48;5;%dm\x1b[38;5;16m%s", ansi256(CellColor(cell, liveBlending)), text)
+		}
+		frame.WriteString("\x1b[0m\n")
+	}
+
+	_, err := io.WriteString(w, frame.String())
+	return err
+}
+
+// runTTY animates the simulation in the terminal until interrupted, instead of opening a window.
+func runTTY(simulation *Simulation, w io.Writer) error {
+	interrupted := make(chan os.Signal, 1)
+	signal.Notify(interrupted, os.Interrupt)
+	defer signal.Stop(interrupted)
+
+	// Clear the screen and hide the cursor, and show it again on the way out.
+	if _, err := io.WriteString(w, "\x1b[2J\x1b[?25l"); err != nil {
+		return err
+	}
+	defer io.WriteString(w, "\x1b[0m\x1b[?25h")
+
+	ticker := time.NewTicker(TTYFrameInterval)
+	defer ticker.Stop()
+	for {
+		changed := simulation.Evolve()
+		if err := WriteTTYFrame(w, simulation.Snapshot()); err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "Number changed: %d (Ctrl+C to quit)\x1b[K\n", changed); err != nil {
+			return err
+		}
+
+		select {
+		case <-interrupted:
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Walkability classes, as used by the mob pathfinding experiments.
+const (
+	Unwalkable   = uint8(0)
+	WalkableDark = uint8(1)
+	WalkableLit  = uint8(2)
+)
+
+// WalkabilityMagic starts every packed walkability file.
+const WalkabilityMagic = "MCLW"
+
+// WalkabilityVersion is the version of the packed walkability format.
+const WalkabilityVersion = uint8(1)
+
+// Walkability returns, per cell, Unwalkable for blockers, WalkableDark if the light level is below threshold and
+// WalkableLit otherwise. If sourcesBlocked is set, light sources are Unwalkable too.
+// The result is indexed as matrix[y][x].
+func (layout Layout) Walkability(threshold int32, sourcesBlocked bool) [][]uint8 {
+	width, height := layout.Size()
+	matrix := make([][]uint8, height)
+	for y := int32(0); y < height; y++ {
+		matrix[y] = make([]uint8, width)
+		for x := int32(0); x < width; x++ {
+			cell := layout.Get(Point{X: x, Y: y})
+
+			switch {
+			case cell.Source < 0, sourcesBlocked && cell.Source > 0:
+				matrix[y][x] = Unwalkable
+			case cell.Level < threshold:
+				matrix[y][x] = WalkableDark
+			default:
+				matrix[y][x] = WalkableLit
+			}
+		}
+	}
+	return matrix
+}
+
+// WriteWalkabilityCSV writes the matrix as CSV, one row per grid row.
+func WriteWalkabilityCSV(w io.Writer, matrix [][]uint8) error {
+	writer := csv.NewWriter(w)
+	for _, row := range matrix {
+		record := make([]string, len(row))
+		for x, class := range row {
+			record[x] = strconv.Itoa(int(class))
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteWalkabilityPacked writes the matrix in the packed binary format:
+//
+//	4 bytes  magic "MCLW"
+//	1 byte   version
+//	2 bytes  width, little endian
+//	2 bytes  height, little endian
+//	then the cells row by row, 2 bits each, four to a byte starting from the least significant bits.
+//	The last byte is zero-padded.
+//
+// Matrices wider or taller than 65535 cells do not fit the header and give an ErrOutOfRange error.
+func WriteWalkabilityPacked(w io.Writer, matrix [][]uint8) error {
+	height := len(matrix)
+	width := 0
+	if height > 0 {
+		width = len(matrix[0])
+	}
+	if width > math.MaxUint16 || height > math.MaxUint16 {
+		return fmt.Errorf("%w: a %dx%d matrix does not fit the packed format, at most %d cells a side",
+			ErrOutOfRange, width, height, math.MaxUint16)
+	}
+
+	header := make([]byte, 0, 9)
+	header = append(header, WalkabilityMagic...)
+	header = append(header, WalkabilityVersion)
+	header = append(header, 0, 0, 0, 0)
+	binary.LittleEndian.PutUint16(header[5:], uint16(width))
+	binary.LittleEndian.PutUint16(header[7:], uint16(height))
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+
+	packed := make([]byte, (width*height+3)/4)
+	i := 0
+	for _, row := range matrix {
+		for _, class := range row {
+			packed[i/4] |= (class & 0b11) << (2 * (i % 4))
+			i++
+		}
+	}
+	_, err := w.Write(packed)
+	return err
+}
+
+// exportWalkability writes the walkability matrix as CSV to the file named by exportName for path, and packed to
+// the one for path with its extension replaced by .bin, and returns their names.
+func (layout Layout) exportWalkability(path string, threshold int32, sourcesBlocked bool) ([]string, error) {
+	matrix := layout.Walkability(threshold, sourcesBlocked)
+	params := ExportParams{"threshold": fmt.Sprint(threshold), "sources": "open"}
+	if sourcesBlocked {
+		params["sources"] = "blocked"
+	}
+
+	csvPath, err := writeExport(path, params, func(w io.Writer) error {
+		return WriteWalkabilityCSV(w, matrix)
+	})
+	if err != nil {
+		return nil, err
+	}
+	binPath, err := writeExport(strings.TrimSuffix(path, filepath.Ext(path))+".bin", params, func(w io.Writer) error {
+		return WriteWalkabilityPacked(w, matrix)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return []string{csvPath, binPath}, nil
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/csv"
+	"errors"
+	"math/rand"
+	"strconv"
+	"testing"
+)
+
+// unpackWalkability reads a matrix written by WriteWalkabilityPacked.
+func unpackWalkability(t *testing.T, data []byte) [][]uint8 {
+	t.Helper()
+	if len(data) < 9 || string(data[:4]) != WalkabilityMagic || data[4] != WalkabilityVersion {
+		t.Fatalf("bad packed header % x", data[:9])
+	}
+	width := int(binary.LittleEndian.Uint16(data[5:]))
+	height := int(binary.LittleEndian.Uint16(data[7:]))
+	packed := data[9:]
+	if want := (width*height + 3) / 4; len(packed) != want {
+		t.Fatalf("%dx%d matrix packed in %d bytes, want %d", width, height, len(packed), want)
+	}
+
+	matrix := make([][]uint8, height)
+	i := 0
+	for y := range matrix {
+		matrix[y] = make([]uint8, width)
+		for x := range matrix[y] {
+			matrix[y][x] = packed[i/4] >> (2 * (i % 4)) & 0b11
+			i++
+		}
+	}
+	return matrix
+}
+
+// readWalkabilityCSV reads a matrix written by WriteWalkabilityCSV.
+func readWalkabilityCSV(t *testing.T, data []byte) [][]uint8 {
+	t.Helper()
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	matrix := make([][]uint8, len(records))
+	for y, record := range records {
+		matrix[y] = make([]uint8, len(record))
+		for x, field := range record {
+			class, err := strconv.ParseUint(field, 10, 8)
+			if err != nil {
+				t.Fatal(err)
+			}
+			matrix[y][x] = uint8(class)
+		}
+	}
+	return matrix
+}
+
+func TestWalkabilityPackedMatchesCSV(t *testing.T) {
+	rng := rand.New(rand.NewSource(211))
+	for round := 0; round < 100; round++ {
+		layout := NewLayout(1+rng.Int31n(30), 1+rng.Int31n(30))
+		width, height := layout.Size()
+		for i := width * height / 3; i > 0; i-- {
+			layout.SetSource(Point{X: rng.Int31n(width), Y: rng.Int31n(height)}, rng.Int31n(17)-1)
+		}
+		layout.Converge(ConvergeMaxIterations)
+		matrix := layout.Walkability(rng.Int31n(16), rng.Intn(2) == 0)
+
+		var csvData, packedData bytes.Buffer
+		if err := WriteWalkabilityCSV(&csvData, matrix); err != nil {
+			t.Fatal(err)
+		}
+		if err := WriteWalkabilityPacked(&packedData, matrix); err != nil {
+			t.Fatal(err)
+		}
+
+		fromCSV, fromPacked := readWalkabilityCSV(t, csvData.Bytes()), unpackWalkability(t, packedData.Bytes())
+		if len(fromCSV) != int(height) || len(fromPacked) != int(height) {
+			t.Fatalf("round %d: %d CSV rows and %d packed rows, want %d", round, len(fromCSV), len(fromPacked), height)
+		}
+		for y := range matrix {
+			for x, class := range matrix[y] {
+				if fromCSV[y][x] != class || fromPacked[y][x] != class {
+					t.Fatalf("round %d: (%d, %d) is %d in CSV and %d packed, want %d",
+						round, x, y, fromCSV[y][x], fromPacked[y][x], class)
+				}
+			}
+		}
+	}
+}
+
+func TestWriteWalkabilityPackedTooLarge(t *testing.T) {
+	wide := [][]uint8{make([]uint8, 65536)}
+	if err := WriteWalkabilityPacked(&bytes.Buffer{}, wide); !errors.Is(err, ErrOutOfRange) {
+		t.Errorf("65536 cells wide gave %v, want ErrOutOfRange", err)
+	}
+	tall := make([][]uint8, 65536)
+	for y := range tall {
+		tall[y] = make([]uint8, 1)
+	}
+	if err := WriteWalkabilityPacked(&bytes.Buffer{}, tall); !errors.Is(err, ErrOutOfRange) {
+		t.Errorf("65536 cells tall gave %v, want ErrOutOfRange", err)
+	}
+
+	var output bytes.Buffer
+	if err := WriteWalkabilityPacked(&output, [][]uint8{make([]uint8, 65535)}); err != nil {
+		t.Errorf("65535 cells wide gave %v", err)
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Limits on a single what-if request.
+const (
+	WhatIfMaxEdits = 64 * 64
+	WhatIfTimeout  = 50 * time.Millisecond
+)
+
+// Edit sets the emission of the cell at Point.
+type Edit struct {
+	Point  Point
+	Source int32
+}
+
+// WhatIfResult is what a batch of edits would lead to once the light settles.
+type WhatIfResult struct {
+	// Cell count per band of the global bands, as in Layout.BandCounts.
+	BandCounts []int
+
+	// Number of cells whose block light would differ from the published state.
+	Changed int
+
+	// Levels indexed as Levels[y][x], only filled in if asked for.
+	Levels [][]int32
+}
+
+// whatIfLayouts recycles the scratch layouts of what-if requests.
+var whatIfLayouts = sync.Pool{}
+
+// WhatIf applies the edits to a copy of the latest published block light (not the levels shown, which afterglow and
+// the view change), relights it and reports the outcome.
+// The live layout is untouched and nothing is published, so it is safe to call from any goroutine, including
+// concurrently with Evolve and other what-ifs.
+func (simulation *Simulation) WhatIf(edits []Edit, withLevels bool) (WhatIfResult, error) {
+	if len(edits) > WhatIfMaxEdits {
+		return WhatIfResult{}, fmt.Errorf("%w: %d edits, at most %d allowed", ErrOutOfRange, len(edits), WhatIfMaxEdits)
+	}
+	live := simulation.blockSnapshot()
+	for _, edit := range edits {
+		if _, inGrid := live.At(edit.Point); !inGrid {
+			return WhatIfResult{}, fmt.Errorf("%w: edit at (%d, %d)", ErrOutOfBounds, edit.Point.X, edit.Point.Y)
+		}
+		if edit.Source < -1 || edit.Source > 15 {
+			return WhatIfResult{}, fmt.Errorf("%w: edit at (%d, %d) has emission %d (must be -1 to 15)", ErrOutOfRange,
+				edit.Point.X, edit.Point.Y, edit.Source)
+		}
+	}
+
+	deadline := time.Now().Add(WhatIfTimeout)
+	width, height := live.Size()
+	scratch, _ := whatIfLayouts.Get().(Layout)
+	if scratchWidth, scratchHeight := scratch.Size(); scratchWidth != width || scratchHeight != height {
+		scratch = NewLayout(width, height)
+	}
+	defer whatIfLayouts.Put(scratch)
+
+	scratch.Restore(live)
+	for _, edit := range edits {
+		cell, _ := scratch.At(edit.Point)
+		cell.Source = edit.Source
+	}
+	if !scratch.RelightAll(deadline) {
+		return WhatIfResult{}, fmt.Errorf("%w: what-if did not settle within %v", ErrNotConverged, WhatIfTimeout)
+	}
+
+	result := WhatIfResult{BandCounts: scratch.BandCounts(bands)}
+	if withLevels {
+		result.Levels = make([][]int32, height)
+	}
+	for y := int32(0); y < height; y++ {
+		if withLevels {
+			result.Levels[y] = make([]int32, width)
+		}
+		for x := int32(0); x < width; x++ {
+			level := scratch.Get(Point{X: x, Y: y}).Level
+			if before, _ := live.At(Point{X: x, Y: y}); level != before.Level {
+				result.Changed++
+			}
+			if withLevels {
+				result.Levels[y][x] = level
+			}
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"errors"
+	"math/rand"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// whatIfTestLayout returns a converged 64x64 layout with a few sources and a wall.
+func whatIfTestLayout() Layout {
+	layout := NewLayout(64, 64)
+	for y := int32(0); y < 40; y++ {
+		layout.SetSource(Point{X: 30, Y: y}, -1)
+	}
+	layout.SetSource(Point{X: 10, Y: 10}, 15)
+	layout.SetSource(Point{X: 50, Y: 20}, 12)
+	layout.SetSource(Point{X: 20, Y: 55}, 9)
+	layout.Converge(ConvergeMaxIterations)
+	return layout
+}
+
+// whatIfWant returns the levels the edits lead to, by converging an edited clone of the layout.
+func whatIfWant(layout Layout, edits []Edit) [][]int32 {
+	edited := Layout{layout.Clone()}
+	for _, edit := range edits {
+		edited.SetSource(edit.Point, edit.Source)
+	}
+	edited.Converge(ConvergeMaxIterations)
+	width, height := edited.Size()
+	levels := make([][]int32, height)
+	for y := range levels {
+		levels[y] = make([]int32, width)
+		for x := range levels[y] {
+			levels[y][x] = edited.Get(Point{X: int32(x), Y: int32(y)}).Level
+		}
+	}
+	return levels
+}
+
+// assertWhatIfLevels fails the test at the first cell whose level differs between got and want.
+func assertWhatIfLevels(t *testing.T, got [][]int32, want [][]int32) {
+	t.Helper()
+	for y := range want {
+		for x := range want[y] {
+			if got[y][x] != want[y][x] {
+				t.Fatalf("what-if level at (%d, %d) is %d, want %d", x, y, got[y][x], want[y][x])
+			}
+		}
+	}
+}
+
+// randomEdits returns n edits at random cells of a 64x64 grid.
+func randomEdits(rng *rand.Rand, n int) []Edit {
+	edits := make([]Edit, n)
+	for i := range edits {
+		edits[i] = Edit{Point: Point{X: rng.Int31n(64), Y: rng.Int31n(64)}, Source: rng.Int31n(17) - 1}
+	}
+	return edits
+}
+
+func TestWhatIf(t *testing.T) {
+	layout := whatIfTestLayout()
+	simulation := NewSimulation(layout)
+	before := simulation.Layout.Snapshot().Cells()
+	published := simulation.Snapshot()
+
+	edits := []Edit{{Point: Point{X: 30, Y: 5}, Source: 0}, {Point: Point{X: 40, Y: 60}, Source: 14}}
+	result, err := simulation.WhatIf(edits, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := whatIfWant(layout, edits)
+	assertWhatIfLevels(t, result.Levels, want)
+
+	changed := 0
+	for y := range want {
+		for x := range want[y] {
+			if want[y][x] != layout.Get(Point{X: int32(x), Y: int32(y)}).Level {
+				changed++
+			}
+		}
+	}
+	if result.Changed != changed {
+		t.Errorf("%d cells changed, want %d", result.Changed, changed)
+	}
+
+	// The live state is untouched and nothing is published.
+	for i, cell := range simulation.Layout.Snapshot().Cells() {
+		if cell != before[i] {
+			t.Fatalf("what-if changed live cell %d from %+v to %+v", i, before[i], cell)
+		}
+	}
+	if simulation.Snapshot() != published {
+		t.Error("what-if published a snapshot")
+	}
+
+	if result, err := simulation.WhatIf(edits, false); err != nil || result.Levels != nil {
+		t.Errorf("what-if without levels gave levels %v and %v", result.Levels, err)
+	}
+}
+
+// What-ifs start from block light, not from the levels shown, which afterglow and the time of day change.
+func TestWhatIfFromBlockLight(t *testing.T) {
+	layout := whatIfTestLayout()
+	simulation := NewSimulation(layout)
+	simulation.DecayPerTick = 1
+	simulation.View = ViewCombined
+	simulation.Time = Midnight
+	simulation.Evolve()
+	simulation.Layout.SetSource(Point{X: 10, Y: 10}, 0)
+	for i := 0; i < ConvergeMaxIterations && simulation.Evolve() > 0; i++ {
+	}
+	// The removed source still glows.
+	if shown, _ := simulation.Snapshot().At(Point{X: 10, Y: 10}); shown.Level == 0 {
+		t.Fatal("the test needs the removed source to still glow")
+	}
+
+	result, err := simulation.WhatIf(nil, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Changed != 0 {
+		t.Errorf("no edits changed %d cells", result.Changed)
+	}
+	assertWhatIfLevels(t, result.Levels, whatIfWant(simulation.Layout, nil))
+}
+
+func TestWhatIfInvalid(t *testing.T) {
+	simulation := NewSimulation(NewLayout(8, 8))
+	offGrid := []Edit{{Point: Point{X: 8, Y: 0}, Source: 1}}
+	if _, err := simulation.WhatIf(offGrid, false); !errors.Is(err, ErrOutOfBounds) {
+		t.Errorf("an edit off the grid gave %v, want ErrOutOfBounds", err)
+	}
+	tooBright := []Edit{{Point: Point{X: 0, Y: 0}, Source: 16}}
+	if _, err := simulation.WhatIf(tooBright, false); !errors.Is(err, ErrOutOfRange) {
+		t.Errorf("emission 16 gave %v, want ErrOutOfRange", err)
+	}
+	if _, err := simulation.WhatIf(make([]Edit, WhatIfMaxEdits+1), false); !errors.Is(err, ErrOutOfRange) {
+		t.Errorf("too many edits gave %v, want ErrOutOfRange", err)
+	}
+}
+
+// Concurrent what-ifs, while the simulation evolves, each give what they would alone. Run with -race.
+func TestWhatIfConcurrent(t *testing.T) {
+	layout := whatIfTestLayout()
+	simulation := NewSimulation(layout)
+	rng := rand.New(rand.NewSource(239))
+	batches := make([][]Edit, 32)
+	wants := make([][][]int32, len(batches))
+	for i := range batches {
+		batches[i] = randomEdits(rng, 1+rng.Intn(8))
+		wants[i] = whatIfWant(layout, batches[i])
+	}
+
+	// As many at a time as there are processors, so that none runs out of time waiting for one.
+	var requests sync.WaitGroup
+	results := make([]WhatIfResult, len(batches))
+	errs := make([]error, len(batches))
+	next := make(chan int, len(batches))
+	for i := range batches {
+		next <- i
+	}
+	close(next)
+	for r := 0; r < runtime.GOMAXPROCS(0); r++ {
+		requests.Add(1)
+		go func() {
+			defer requests.Done()
+			for i := range next {
+				results[i], errs[i] = simulation.WhatIf(batches[i], true)
+			}
+		}()
+	}
+	// The layout is converged, so evolving changes nothing the what-ifs start from.
+	for i := 0; i < 10; i++ {
+		simulation.Evolve()
+	}
+	requests.Wait()
+
+	for i := range batches {
+		if errs[i] != nil {
+			t.Fatalf("batch %d: %v", i, errs[i])
+		}
+		assertWhatIfLevels(t, results[i].Levels, wants[i])
+	}
+}
+
+// 100 concurrent what-ifs of 16 edits each on a 64x64 grid. Requests that run out of time are reported as
+// timeouts/op: that is the limit protecting the server at work, and how many there are depends on the processors.
+func BenchmarkWhatIf100Concurrent64(b *testing.B) {
+	simulation := NewSimulation(whatIfTestLayout())
+	rng := rand.New(rand.NewSource(239))
+	batches := make([][]Edit, 100)
+	for i := range batches {
+		batches[i] = randomEdits(rng, 16)
+	}
+	var timeouts int64
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		var requests sync.WaitGroup
+		for i := range batches {
+			requests.Add(1)
+			go func(edits []Edit) {
+				defer requests.Done()
+				_, err := simulation.WhatIf(edits, false)
+				if errors.Is(err, ErrNotConverged) {
+					atomic.AddInt64(&timeouts, 1)
+				} else if err != nil {
+					b.Error(err)
+				}
+			}(batches[i])
+		}
+		requests.Wait()
+	}
+	b.ReportMetric(float64(timeouts)/float64(b.N), "timeouts/op")
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// WorldCoordinateLimit bounds world coordinates, as the world border does in game. It leaves room to add grid
+// offsets to an origin without overflowing int32.
+const WorldCoordinateLimit = 30000000
+
+// WorldAxis is a signed Minecraft world axis: Index is 0, 1 or 2 for x, y or z and Sign is +1 or -1.
+type WorldAxis struct {
+	Index int
+	Sign  int32
+}
+
+// WorldTransform maps grid cells to Minecraft world block coordinates. Grid (0, 0) is at Origin, and one step
+// along grid x or y is one block along XAxis or YAxis. The third world axis stays at its Origin value (the slice).
+type WorldTransform struct {
+	Origin [3]int32
+	XAxis  WorldAxis
+	YAxis  WorldAxis
+}
+
+// World returns the world coordinates (x, y, z) of the cell at p.
+func (transform WorldTransform) World(p Point) [3]int32 {
+	world := transform.Origin
+	world[transform.XAxis.Index] += transform.XAxis.Sign * p.X
+	world[transform.YAxis.Index] += transform.YAxis.Sign * p.Y
+	return world
+}
+
+// Grid returns the cell at the world coordinates, or false if they are not on the transform's slice or are beyond
+// the world border.
+func (transform WorldTransform) Grid(world [3]int32) (Point, bool) {
+	for axis := 0; axis < 3; axis++ {
+		if axis != transform.XAxis.Index && axis != transform.YAxis.Index && world[axis] != transform.Origin[axis] {
+			return Point{}, false
+		}
+	}
+	for _, axis := range []WorldAxis{transform.XAxis, transform.YAxis} {
+		if world[axis.Index] < -WorldCoordinateLimit || world[axis.Index] > WorldCoordinateLimit {
+			return Point{}, false
+		}
+	}
+	return Point{
+		X: transform.XAxis.Sign * (world[transform.XAxis.Index] - transform.Origin[transform.XAxis.Index]),
+		Y: transform.YAxis.Sign * (world[transform.YAxis.Index] - transform.Origin[transform.YAxis.Index]),
+	}, true
+}
+
+// ParseWorldTransform reads an origin "x,y,z" and an axis mapping such as "+x+z" (grid x along world +x, grid y
+// along world +z) or "-z+y".
+func ParseWorldTransform(origin string, axes string) (WorldTransform, error) {
+	transform := WorldTransform{}
+
+	parts := strings.Split(origin, ",")
+	if len(parts) != 3 {
+		return WorldTransform{}, fmt.Errorf("%w: origin %q: expected \"x,y,z\"", ErrMalformedInput, origin)
+	}
+	for i, part := range parts {
+		value, err := strconv.ParseInt(strings.TrimSpace(part), 10, 32)
+		if err != nil {
+			return WorldTransform{}, fmt.Errorf("%w: origin %q: bad coordinate %q", ErrMalformedInput, origin, part)
+		}
+		if value < -WorldCoordinateLimit || value > WorldCoordinateLimit {
+			return WorldTransform{}, fmt.Errorf("%w: origin %q: %d is beyond the world border (%d)",
+				ErrOutOfRange, origin, value, WorldCoordinateLimit)
+		}
+		transform.Origin[i] = int32(value)
+	}
+
+	if len(axes) != 4 {
+		return WorldTransform{}, fmt.Errorf("%w: axes %q: expected two signed axes such as \"+x+z\"", ErrMalformedInput, axes)
+	}
+	parsed := [2]WorldAxis{}
+	for i := range parsed {
+		switch axes[2*i] {
+		case '+':
+			parsed[i].Sign = 1
+		case '-':
+			parsed[i].Sign = -1
+		default:
+			return WorldTransform{}, fmt.Errorf("%w: axes %q: %q is not a sign", ErrMalformedInput, axes, axes[2*i])
+		}
+		index := strings.IndexByte("xyz", axes[2*i+1])
+		if index < 0 {
+			return WorldTransform{}, fmt.Errorf("%w: axes %q: %q is not an axis", ErrMalformedInput, axes, axes[2*i+1])
+		}
+		parsed[i].Index = index
+	}
+	if parsed[0].Index == parsed[1].Index {
+		return WorldTransform{}, fmt.Errorf("%w: axes %q: grid x and y map to the same world axis", ErrMalformedInput, axes)
+	}
+	transform.XAxis = parsed[0]
+	transform.YAxis = parsed[1]
+	return transform, nil
+}
+
+// worldStatus describes the world position of the cell at p, for the status line.
+func (transform WorldTransform) worldStatus(p Point) string {
+	world := transform.World(p)
+	return fmt.Sprintf("(%d, %d) = world %d %d %d", p.X, p.Y, world[0], world[1], world[2])
+}
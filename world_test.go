@@ -0,0 +1,115 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestParseWorldTransform(t *testing.T) {
+	tests := []struct {
+		origin string
+		axes   string
+		want   WorldTransform
+	}{
+		{"0,64,0", "+x+z", WorldTransform{Origin: [3]int32{0, 64, 0}, XAxis: WorldAxis{0, 1}, YAxis: WorldAxis{2, 1}}},
+		{"-120, 12 ,7", "-z+y", WorldTransform{Origin: [3]int32{-120, 12, 7}, XAxis: WorldAxis{2, -1},
+			YAxis: WorldAxis{1, 1}}},
+		{"30000000,-30000000,0", "+y-x", WorldTransform{Origin: [3]int32{30000000, -30000000, 0},
+			XAxis: WorldAxis{1, 1}, YAxis: WorldAxis{0, -1}}},
+	}
+	for _, test := range tests {
+		got, err := ParseWorldTransform(test.origin, test.axes)
+		if err != nil || got != test.want {
+			t.Errorf("ParseWorldTransform(%q, %q) = %+v, %v; want %+v", test.origin, test.axes, got, err, test.want)
+		}
+	}
+
+	bad := []struct {
+		origin   string
+		axes     string
+		category error
+	}{
+		{"0,64", "+x+z", ErrMalformedInput},
+		{"0,64,0,1", "+x+z", ErrMalformedInput},
+		{"0,high,0", "+x+z", ErrMalformedInput},
+		{"0,1.5,0", "+x+z", ErrMalformedInput},
+		{"", "+x+z", ErrMalformedInput},
+		{"0,64,0", "+x", ErrMalformedInput},
+		{"0,64,0", "+x+z+y", ErrMalformedInput},
+		{"0,64,0", "x+z+", ErrMalformedInput},
+		{"0,64,0", "*x+z", ErrMalformedInput},
+		{"0,64,0", "+w+z", ErrMalformedInput},
+		{"0,64,0", "+X+Z", ErrMalformedInput},
+		{"0,64,0", "+x-x", ErrMalformedInput},
+		{"30000001,0,0", "+x+z", ErrOutOfRange},
+		{"0,0,-30000001", "+x+z", ErrOutOfRange},
+	}
+	for _, test := range bad {
+		if _, err := ParseWorldTransform(test.origin, test.axes); !errors.Is(err, test.category) {
+			t.Errorf("ParseWorldTransform(%q, %q) gave %v, want %v", test.origin, test.axes, err, test.category)
+		}
+	}
+}
+
+// Every mapping of the grid axes to two different signed world axes goes to the world and back, and steps along
+// the axes it names.
+func TestWorldTransformRoundTrip(t *testing.T) {
+	origin := [3]int32{-37, 70, 1200}
+	points := []Point{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 0, Y: 1}, {X: 15, Y: 9}, {X: 1023, Y: 511}}
+	mappings := 0
+	for _, x := range []string{"+x", "-x", "+y", "-y", "+z", "-z"} {
+		for _, y := range []string{"+x", "-x", "+y", "-y", "+z", "-z"} {
+			if x[1] == y[1] {
+				continue
+			}
+			mappings++
+			transform, err := ParseWorldTransform(fmt.Sprintf("%d,%d,%d", origin[0], origin[1], origin[2]), x+y)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, p := range points {
+				world := transform.World(p)
+				want := origin
+				want[transform.XAxis.Index] += transform.XAxis.Sign * p.X
+				want[transform.YAxis.Index] += transform.YAxis.Sign * p.Y
+				if world != want {
+					t.Errorf("%s: %v is world %v, want %v", x+y, p, world, want)
+				}
+				if back, ok := transform.Grid(world); !ok || back != p {
+					t.Errorf("%s: world %v is cell %v, %v; want %v", x+y, world, back, ok, p)
+				}
+			}
+
+			// Off the slice, nothing is on the grid.
+			off := transform.World(Point{X: 2, Y: 3})
+			off[3-transform.XAxis.Index-transform.YAxis.Index]++
+			if p, ok := transform.Grid(off); ok {
+				t.Errorf("%s: world %v, off the slice, is cell %v", x+y, off, p)
+			}
+		}
+	}
+	if mappings != 24 {
+		t.Errorf("tried %d mappings, want 24", mappings)
+	}
+}
+
+func TestWorldTransformGrid(t *testing.T) {
+	transform, err := ParseWorldTransform("100,64,-20", "+x-z")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if world := transform.World(Point{X: 3, Y: 5}); world != [3]int32{103, 64, -25} {
+		t.Errorf("(3, 5) is world %v, want [103 64 -25]", world)
+	}
+	// Cells before the origin have negative grid coordinates.
+	if p, ok := transform.Grid([3]int32{98, 64, -18}); !ok || p != (Point{X: -2, Y: -2}) {
+		t.Errorf("world 98 64 -18 is cell %v, %v; want (-2, -2)", p, ok)
+	}
+	if p, ok := transform.Grid([3]int32{WorldCoordinateLimit + 1, 64, -20}); ok {
+		t.Errorf("beyond the world border is cell %v", p)
+	}
+	if status := transform.worldStatus(Point{X: 3, Y: 5}); status != "(3, 5) = world 103 64 -25" {
+		t.Errorf("status is %q", status)
+	}
+}